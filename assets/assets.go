@@ -0,0 +1,11 @@
+package assets
+
+import (
+	"embed"
+)
+
+// Migrations are the sql scripts of the database schema, applied in the order of
+// their names (NNN_name.sql) by the admin cli migrate command
+//
+//go:embed database/*.sql
+var Migrations embed.FS
@@ -0,0 +1,9 @@
+-- person, as created by the first version of the service
+CREATE TABLE IF NOT EXISTS public.person (
+	id			serial		PRIMARY KEY,
+	person_id	varchar(100) NOT NULL UNIQUE,
+	name		varchar(200) NOT NULL,
+	created_at	timestamptz	NOT NULL,
+	updated_at	timestamptz	NULL,
+	tenant_id	varchar(100) NULL
+);
@@ -0,0 +1,40 @@
+-- contact info, addresses and identity documents of a person
+ALTER TABLE public.person
+	ADD COLUMN IF NOT EXISTS birth_date		date		NULL,
+	ADD COLUMN IF NOT EXISTS email			varchar(254) NULL,
+	ADD COLUMN IF NOT EXISTS phone			varchar(16)	NULL,
+	ADD COLUMN IF NOT EXISTS nationality	char(2)		NULL,
+	ADD COLUMN IF NOT EXISTS tax_id			varchar(50)	NULL;
+
+CREATE TABLE IF NOT EXISTS public.person_address (
+	id				serial		PRIMARY KEY,
+	fk_person_id	integer		NOT NULL REFERENCES public.person(id) ON DELETE CASCADE,
+	type			varchar(20)	NOT NULL,
+	street			varchar(200) NOT NULL,
+	number			varchar(20)	NULL,
+	complement		varchar(100) NULL,
+	district		varchar(100) NULL,
+	city			varchar(100) NOT NULL,
+	state			varchar(50)	NULL,
+	postal_code		varchar(20)	NOT NULL,
+	country			char(2)		NOT NULL,
+	is_primary		boolean		NOT NULL DEFAULT false,
+	created_at		timestamptz	NOT NULL,
+	updated_at		timestamptz	NULL
+);
+CREATE INDEX IF NOT EXISTS person_address_fk_person_id_idx ON public.person_address (fk_person_id);
+
+CREATE TABLE IF NOT EXISTS public.person_document (
+	id					serial		PRIMARY KEY,
+	fk_person_id		integer		NOT NULL REFERENCES public.person(id) ON DELETE CASCADE,
+	type				varchar(20)	NOT NULL,
+	number				varchar(50)	NOT NULL,
+	issuing_country		char(2)		NOT NULL,
+	issuing_authority	varchar(100) NULL,
+	issue_date			date		NULL,
+	expiry_date			date		NULL,
+	created_at			timestamptz	NOT NULL,
+	updated_at			timestamptz	NULL,
+	UNIQUE (fk_person_id, type, number, issuing_country)
+);
+CREATE INDEX IF NOT EXISTS person_document_fk_person_id_idx ON public.person_document (fk_person_id);
@@ -0,0 +1,13 @@
+-- natural or legal person and a tax id onboarded once per tenant
+ALTER TABLE public.person
+	ADD COLUMN IF NOT EXISTS person_type	varchar(10)	NOT NULL DEFAULT 'natural';
+
+-- the tax ids are stored without formatting (123.456.789-09 as 12345678909),
+-- the duplicated ones must be solved before the index is created
+UPDATE public.person
+	SET tax_id = upper(regexp_replace(tax_id, '[^0-9A-Za-z]', '', 'g'))
+	WHERE tax_id IS NOT NULL AND tax_id !~ '^[0-9A-Z]*$';
+
+CREATE UNIQUE INDEX IF NOT EXISTS person_tenant_tax_id_uq
+	ON public.person (COALESCE(tenant_id, ''), tax_id)
+	WHERE tax_id IS NOT NULL;
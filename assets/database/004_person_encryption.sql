@@ -0,0 +1,32 @@
+-- field-level encryption of the person PII (name, email, phone, tax_id)
+
+-- data and blind index keys, wrapped by the master key of the key provider
+CREATE TABLE IF NOT EXISTS public.encryption_key (
+	purpose			varchar(10)	NOT NULL,
+	version			integer		NOT NULL,
+	master_key_id	varchar(200) NOT NULL,
+	wrapped_key		bytea		NOT NULL,
+	created_at		timestamptz	NOT NULL,
+	updated_at		timestamptz	NULL,
+	PRIMARY KEY (purpose, version)
+);
+
+-- the ciphertexts are longer than the plaintexts
+ALTER TABLE public.person
+	ALTER COLUMN name		TYPE text,
+	ALTER COLUMN email		TYPE text,
+	ALTER COLUMN phone		TYPE text,
+	ALTER COLUMN tax_id		TYPE text;
+
+-- blind indexes (keyed hashes) for the exact match lookups of the encrypted fields,
+-- filled by the writes and by the re-encryption of the existing rows
+ALTER TABLE public.person
+	ADD COLUMN IF NOT EXISTS email_bidx		char(32)	NULL,
+	ADD COLUMN IF NOT EXISTS tax_id_bidx	char(32)	NULL;
+
+CREATE INDEX IF NOT EXISTS person_email_bidx_idx ON public.person (email_bidx);
+
+-- the encrypted tax ids are all different, the uniqueness moves to the blind index
+CREATE UNIQUE INDEX IF NOT EXISTS person_tenant_tax_id_bidx_uq
+	ON public.person (COALESCE(tenant_id, ''), tax_id_bidx)
+	WHERE tax_id_bidx IS NOT NULL;
@@ -0,0 +1,29 @@
+-- data subject requests (gdpr/lgpd): audit trail, erasure and tombstones
+
+-- history of the changes and exports of a person, kept after the erasure
+-- (the person_id is not a foreign key and no PII is stored)
+CREATE TABLE IF NOT EXISTS public.person_audit (
+	id			bigserial	PRIMARY KEY,
+	person_id	varchar(100) NOT NULL,
+	tenant_id	varchar(100) NULL,
+	action		varchar(50)	NOT NULL,
+	actor		varchar(200) NULL,
+	trace_id	varchar(100) NULL,
+	created_at	timestamptz	NOT NULL
+);
+CREATE INDEX IF NOT EXISTS person_audit_person_id_idx ON public.person_audit (person_id, created_at);
+
+-- the erased persons keep their row anonymized, hidden from the reads
+ALTER TABLE public.person
+	ADD COLUMN IF NOT EXISTS erased_at	timestamptz	NULL;
+
+-- proof of the erasure, one per person
+CREATE TABLE IF NOT EXISTS public.person_tombstone (
+	person_id			varchar(100) PRIMARY KEY,
+	tenant_id			varchar(100) NULL,
+	actor				varchar(200) NULL,
+	trace_id			varchar(100) NULL,
+	erased_at			timestamptz	NOT NULL,
+	objects_deleted		integer		NOT NULL DEFAULT 0,
+	objects_deleted_at	timestamptz	NULL
+);
@@ -0,0 +1,24 @@
+-- accent insensitive full-text and fuzzy search of the person name
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent is only stable (its dictionary may change), the indexes need a immutable function
+CREATE OR REPLACE FUNCTION public.f_unaccent(text) RETURNS text
+	LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+	AS $$ SELECT public.unaccent('public.unaccent', $1) $$;
+
+-- the plaintext names (encryption disabled or rows not encrypted yet)
+ALTER TABLE public.person
+	ADD COLUMN IF NOT EXISTS name_search tsvector
+		GENERATED ALWAYS AS (to_tsvector('simple', public.f_unaccent(lower(name)))) STORED;
+
+CREATE INDEX IF NOT EXISTS person_name_search_idx ON public.person USING gin (name_search);
+CREATE INDEX IF NOT EXISTS person_name_trgm_idx ON public.person USING gin (public.f_unaccent(lower(name)) gin_trgm_ops);
+
+-- the encrypted names: blind indexes (keyed hashes) of their trigrams, filled by the
+-- writes and by the re-encryption. The hashes show how many trigrams two names share,
+-- not the names.
+ALTER TABLE public.person
+	ADD COLUMN IF NOT EXISTS name_trgm_bidx text[] NULL;
+
+CREATE INDEX IF NOT EXISTS person_name_trgm_bidx_idx ON public.person USING gin (name_trgm_bidx);
@@ -0,0 +1,35 @@
+-- deduplication of the persons onboarded and merge of the duplicates
+
+-- the possible duplicates flagged by the onboarding, waiting for a review
+CREATE TABLE IF NOT EXISTS public.person_duplicate (
+	id				bigserial	PRIMARY KEY,
+	person_id		varchar(100) NOT NULL,
+	candidate_id	varchar(100) NOT NULL,
+	tenant_id		varchar(100) NULL,
+	score			float8		NOT NULL,
+	reasons			text[]		NOT NULL,
+	status			varchar(20)	NOT NULL DEFAULT 'pending',	-- pending, merged or dismissed
+	created_at		timestamptz	NOT NULL,
+	resolved_at		timestamptz	NULL,
+	UNIQUE (person_id, candidate_id)
+);
+CREATE INDEX IF NOT EXISTS person_duplicate_tenant_status_idx ON public.person_duplicate (COALESCE(tenant_id, ''), status, created_at);
+CREATE INDEX IF NOT EXISTS person_duplicate_candidate_id_idx ON public.person_duplicate (candidate_id);
+
+-- the merges, the merged person keeps its row anonymized and hidden as a erased one,
+-- its data and child collections moved to the survivor. Its audit history is kept
+-- under its person_id and listed with the one of the survivor.
+CREATE TABLE IF NOT EXISTS public.person_merge (
+	merged_person_id	varchar(100) PRIMARY KEY,
+	survivor_person_id	varchar(100) NOT NULL,
+	tenant_id			varchar(100) NULL,
+	actor				varchar(200) NULL,
+	trace_id			varchar(100) NULL,
+	merged_at			timestamptz	NOT NULL,
+	objects_moved		integer		NOT NULL DEFAULT 0,
+	objects_moved_at	timestamptz	NULL
+);
+CREATE INDEX IF NOT EXISTS person_merge_survivor_person_id_idx ON public.person_merge (survivor_person_id);
+
+-- the candidates found by a identity document
+CREATE INDEX IF NOT EXISTS person_document_number_idx ON public.person_document (type, upper(regexp_replace(number, '[^[:alnum:]]', '', 'g')));
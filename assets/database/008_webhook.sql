@@ -0,0 +1,51 @@
+-- webhook subscriptions of the tenants and the deliveries of the onboarding events
+
+-- the endpoints of a tenant and the events they are notified of ('*' for all),
+-- the secret signing the deliveries is encrypted when the field encryption is enabled
+CREATE TABLE IF NOT EXISTS public.webhook_subscription (
+	id			bigserial	PRIMARY KEY,
+	tenant_id	varchar(100) NULL,
+	url			varchar(2000) NOT NULL,
+	events		text[]		NOT NULL,
+	secret		text		NOT NULL,
+	description	varchar(200) NULL,
+	is_active	boolean		NOT NULL DEFAULT true,
+	created_at	timestamptz	NOT NULL,
+	updated_at	timestamptz	NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_subscription_tenant_idx ON public.webhook_subscription (COALESCE(tenant_id, ''));
+
+-- a event to be delivered to a subscription, written in the transaction of the change
+-- (outbox). The dispatcher claims the pending ones due, a failed delivery is tried
+-- again later with a exponential backoff and is dead once the attempts are over.
+CREATE TABLE IF NOT EXISTS public.webhook_delivery (
+	id					bigserial	PRIMARY KEY,
+	subscription_id		bigint		NOT NULL REFERENCES public.webhook_subscription(id) ON DELETE CASCADE,
+	tenant_id			varchar(100) NULL,
+	event_id			varchar(100) NOT NULL,
+	event				varchar(50)	NOT NULL,
+	person_id			varchar(100) NOT NULL,
+	document_id			integer		NULL,
+	occurred_at			timestamptz	NOT NULL,
+	status				varchar(20)	NOT NULL DEFAULT 'pending',	-- pending, delivered or dead
+	attempts			integer		NOT NULL DEFAULT 0,
+	next_attempt_at		timestamptz	NOT NULL,
+	last_status_code	integer		NULL,
+	last_error			text		NULL,
+	created_at			timestamptz	NOT NULL,
+	delivered_at		timestamptz	NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_delivery_pending_idx ON public.webhook_delivery (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_delivery_subscription_idx ON public.webhook_delivery (subscription_id, created_at);
+
+-- the log of the attempts of a delivery
+CREATE TABLE IF NOT EXISTS public.webhook_attempt (
+	id				bigserial	PRIMARY KEY,
+	delivery_id		bigint		NOT NULL REFERENCES public.webhook_delivery(id) ON DELETE CASCADE,
+	attempt			integer		NOT NULL,
+	status_code		integer		NULL,
+	error			text		NULL,
+	duration_ms		integer		NOT NULL,
+	attempted_at	timestamptz	NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_attempt_delivery_idx ON public.webhook_attempt (delivery_id, attempted_at);
@@ -0,0 +1,31 @@
+-- asynchronous jobs (imports, exports, re-encryption) run by the worker pool of the replicas
+
+-- a queued job due (run_at) is claimed by a replica with FOR UPDATE SKIP LOCKED and
+-- held while the replica renews the lease (locked_until). A running job whose lease
+-- expired (replica gone) is claimed again, a failed one is queued again with a
+-- exponential backoff and is failed once the attempts are over.
+CREATE TABLE IF NOT EXISTS public.job (
+	id					bigserial	PRIMARY KEY,
+	kind				varchar(50)	NOT NULL,
+	tenant_id			varchar(100) NULL,
+	payload				jsonb		NOT NULL DEFAULT '{}',
+	status				varchar(20)	NOT NULL DEFAULT 'queued',	-- queued, running, succeeded, failed or canceled
+	attempts			integer		NOT NULL DEFAULT 0,
+	max_attempts		integer		NOT NULL,
+	progress_done		integer		NOT NULL DEFAULT 0,
+	progress_total		integer		NOT NULL DEFAULT 0,
+	result				jsonb		NULL,
+	error				text		NULL,
+	cancel_requested	boolean		NOT NULL DEFAULT false,
+	run_at				timestamptz	NOT NULL,
+	locked_by			varchar(200) NULL,
+	locked_until		timestamptz	NULL,
+	actor				varchar(200) NULL,
+	trace_id			varchar(100) NULL,
+	created_at			timestamptz	NOT NULL,
+	started_at			timestamptz	NULL,
+	finished_at			timestamptz	NULL
+);
+CREATE INDEX IF NOT EXISTS job_queued_idx ON public.job (run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS job_running_idx ON public.job (locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS job_tenant_idx ON public.job (COALESCE(tenant_id, ''), created_at);
@@ -0,0 +1,21 @@
+-- field-level encryption of the address street and of the document number
+
+-- the ciphertexts are longer than the plaintexts
+ALTER TABLE public.person_address
+	ALTER COLUMN street		TYPE text;
+
+ALTER TABLE public.person_document
+	ALTER COLUMN number		TYPE text;
+
+-- blind index of the normalized number, for the matches of the deduplication and the merge
+ALTER TABLE public.person_document
+	ADD COLUMN IF NOT EXISTS number_bidx	char(32)	NULL;
+
+-- the encrypted numbers are all different, the uniqueness moves to the blind index
+ALTER TABLE public.person_document
+	DROP CONSTRAINT IF EXISTS person_document_fk_person_id_type_number_issuing_country_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS person_document_number_uq
+	ON public.person_document (fk_person_id, type, issuing_country, COALESCE(number_bidx, number));
+
+CREATE INDEX IF NOT EXISTS person_document_number_bidx_idx ON public.person_document (number_bidx);
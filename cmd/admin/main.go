@@ -0,0 +1,452 @@
+package main
+
+import(
+	"io"
+	"os"
+	"fmt"
+	"flag"
+	"time"
+	"errors"
+	"context"
+	"strings"
+	"os/signal"
+	"syscall"
+	"crypto/x509"
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/assets"
+	"github.com/go-onboarding/internal/infra/configuration"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/dedup"
+	"github.com/go-onboarding/internal/core/service"
+	"github.com/go-onboarding/internal/infra/server"
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/notify"
+	"github.com/go-onboarding/internal/infra/credential"
+	"github.com/go-onboarding/internal/infra/encryption"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/retry"
+
+	go_core_aws_config "github.com/eliezerraj/go-core/aws/aws_config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package", "admin").Logger()
+
+// the cli output is json on stdout, the logs go to stderr
+const usage = `go-onboarding-admin, maintenance of the onboarding service from a pod shell
+
+usage: go-onboarding-admin [config flags] <command> [command flags]
+
+The configuration is the one of the service (defaults, -config file, .env, env vars
+and the config flags), the logs are at warn unless LOG_LEVEL is set.
+
+commands:
+  migrate [-status]                       apply the pending sql scripts of the schema, or list them
+  person get -id <person_id>              get a person with its addresses and documents
+  person list [-from <person_id>]         list the persons from a person_id, in order
+  person create [-file <json>]            create a person from a json (stdin when no file)
+  person update [-file <json>]            update a person from a json (stdin when no file)
+  import -file <csv> [-tenant <id>]       add the persons of a csv, the ones already added are skipped
+  export [-tenant <id>] [-out <file>]     write the persons of a tenant as json lines (stdout when no file)
+  verify-tls [-cert <file>] [-key <file>] check the base64 cert and key files as the server loads them
+  config                                  print the effective configuration, the secrets redacted
+`
+
+// a command, it runs with the configuration loaded
+type command struct {
+	name	string
+	needDB	bool
+	run		func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{name: "migrate", needDB: true, run: runMigrate},
+	{name: "person", needDB: true, run: runPerson},
+	{name: "import", needDB: true, run: runImport},
+	{name: "export", needDB: true, run: runExport},
+	{name: "verify-tls", run: runVerifyTLS},
+	{name: "config", run: runConfig},
+}
+
+// what the commands use
+type env struct {
+	appServer		model.AppServer
+	values			*configuration.Values
+	databaseServer	*database.DatabasePGServer
+	workerService	*service.WorkerService
+	out				io.Writer
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// About split the config flags from the command and its flags, load the
+// configuration, open what the command needs and run it
+func run(args []string) (err error) {
+	split := -1
+	for i, arg := range args {
+		if findCommand(arg) != nil {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("command missing")
+	}
+	cmd := findCommand(args[split])
+
+	// quiet until the configuration tells otherwise
+	logging.SetLevel("warn")
+	values, errValues := configuration.LoadValues(args[:split])
+	if values == nil {
+		return errValues
+	}
+	if values.Origin("LOG_LEVEL") != configuration.OriginDefault {
+		logging.SetLevel(values.String("LOG_LEVEL"))
+	}
+
+	e := &env{values: values, out: os.Stdout}
+	if cmd.name == "config" {
+		// printed even when invalid, to find what is wrong
+		if err = runConfig(context.Background(), e, args[split+1:]); err != nil {
+			return err
+		}
+		if errValues != nil {
+			return fmt.Errorf("invalid configuration:\n%w", errValues)
+		}
+		return nil
+	}
+	if e.appServer, err = configuration.Load(args[:split]); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if cmd.needDB {
+		if err = e.open(ctx); err != nil {
+			return err
+		}
+		defer e.databaseServer.CloseConnection()
+	}
+
+	childLogger.Info().Str("command", cmd.name).Send()
+	return cmd.run(ctx, e, args[split+1:])
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// About open the database and wire the service as the server does, the persons
+// written here are encrypted, checked for duplicates and published as the ones of the api
+func (e *env) open(ctx context.Context) (err error) {
+	appServer := e.appServer
+
+	// the aws config is only loaded when a aws service is used
+	var awsConfig *aws.Config
+	if appServer.DatabaseAuth.Mode == "iam" || (appServer.Encryption.IsEnabled && appServer.Encryption.KeyProvider == "kms") {
+		var goCoreAwsConfig go_core_aws_config.AwsConfig
+		if awsConfig, err = goCoreAwsConfig.NewAWSConfig(ctx, appServer.AwsService.AwsRegion); err != nil {
+			return err
+		}
+	}
+
+	var credentialProvider credential.Provider
+	if appServer.DatabaseAuth.Mode == "iam" {
+		credentialProvider = credential.NewIAMProvider(	appServer.DatabaseConfig.Host + ":" + appServer.DatabaseConfig.Port,
+														appServer.AwsService.AwsRegion,
+														appServer.DatabaseAuth.IAMUser,
+														awsConfig.Credentials)
+	} else {
+		if credentialProvider, err = credential.NewFileProvider(appServer.DatabaseAuth.UserFile, appServer.DatabaseAuth.PasswordFile); err != nil {
+			return err
+		}
+	}
+
+	e.databaseServer, err = database.NewDatabasePGServer(ctx, *appServer.DatabaseConfig, credentialProvider)
+	if err != nil {
+		return err
+	}
+
+	workerRepository := database.NewWorkerRepository(e.databaseServer)
+	if appServer.Encryption.IsEnabled {
+		keyProvider, err := encryption.NewKeyProvider(encryption.ProviderConfig{	Provider: appServer.Encryption.KeyProvider,
+																				KeyFile: appServer.Encryption.KeyFile,
+																				KMSKeyID: appServer.Encryption.KMSKeyID,
+																			}, awsConfig)
+		if err != nil {
+			return err
+		}
+		keyring, err := encryption.NewKeyring(ctx, keyProvider, database.NewKeyRepository(e.databaseServer))
+		if err != nil {
+			return err
+		}
+		workerRepository.SetKeyring(keyring)
+	}
+
+	e.workerService = service.NewWorkerService(workerRepository, nil, appServer.AwsService)
+	e.workerService.SetRetryPolicy(retry.Policy{	MaxAttempts: appServer.DatabaseRetry.Attempts,
+													BaseDelay: time.Duration(appServer.DatabaseRetry.BaseDelay) * time.Millisecond,
+													MaxDelay: time.Duration(appServer.DatabaseRetry.MaxDelay) * time.Millisecond,
+	})
+	if appServer.Dedup.IsEnabled {
+		e.workerService.SetDedupPolicy(dedup.Policy{	MatchThreshold: float64(appServer.Dedup.MatchPercent) / 100,
+														ReviewThreshold: float64(appServer.Dedup.ReviewPercent) / 100,
+		})
+	}
+	// the events are queued for the subscriptions, the server delivers them
+	if appServer.Webhook.IsEnabled {
+		e.workerService.SetWebhook(notify.NewNotifier(time.Duration(appServer.Webhook.Timeout) * time.Second, appServer.Webhook.AllowHTTP), retry.Policy{	MaxAttempts: appServer.Webhook.MaxAttempts,
+																														BaseDelay: time.Duration(appServer.Webhook.BaseDelay) * time.Second,
+																														MaxDelay: time.Duration(appServer.Webhook.MaxDelay) * time.Second,
+		}, appServer.Webhook.AllowHTTP)
+	}
+
+	return nil
+}
+
+// About write a value as indented json
+func (e *env) print(value any) error {
+	encoder := json.NewEncoder(e.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// About parse the flags of a command, the positional args are refused
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// About open a input file, stdin for "" or "-"
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	status := fs.Bool("status", false, "list the scripts with when they were applied, nothing is applied")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	migrationRepository := database.NewMigrationRepository(e.databaseServer, assets.Migrations, "database")
+	if *status {
+		migrations, err := migrationRepository.ListMigration(ctx)
+		if err != nil {
+			return err
+		}
+		return e.print(migrations)
+	}
+
+	applied, err := migrationRepository.Migrate(ctx)
+	if err != nil {
+		// the ones applied before the failure are reported too
+		e.print(applied)
+		return err
+	}
+	return e.print(applied)
+}
+
+func runPerson(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("person: get, list, create or update missing")
+	}
+
+	fs := flag.NewFlagSet("person " + args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "get":
+		personID := fs.String("id", "", "person_id")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		if *personID == "" {
+			return errors.New("person get: -id missing")
+		}
+		res, err := e.workerService.GetPerson(database.UsePrimary(ctx), &model.Onboarding{Person: &model.Person{PersonID: *personID}})
+		if err != nil {
+			return err
+		}
+		return e.print(res)
+	case "list":
+		from := fs.String("from", "", "first person_id")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		res, err := e.workerService.ListPerson(database.UsePrimary(ctx), &model.Onboarding{Person: &model.Person{PersonID: *from}})
+		if err != nil {
+			return err
+		}
+		return e.print(res)
+	case "create", "update":
+		file := fs.String("file", "", "json of the person, stdin when empty")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		in, err := openInput(*file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		person := model.Person{}
+		decoder := json.NewDecoder(in)
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&person); err != nil {
+			return fmt.Errorf("person %s: %w", args[0], err)
+		}
+
+		var res *model.Onboarding
+		if args[0] == "create" {
+			res, err = e.workerService.AddPerson(ctx, &model.Onboarding{Person: &person})
+		} else {
+			res, err = e.workerService.UpdatePerson(ctx, &model.Onboarding{Person: &person})
+		}
+		if err != nil {
+			return err
+		}
+		return e.print(res)
+	default:
+		return fmt.Errorf("person: unknown command %s", args[0])
+	}
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "csv file, stdin when -")
+	tenantID := fs.String("tenant", "", "tenant of all the rows, the tenant_id column otherwise")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("import: -file missing")
+	}
+	in, err := openInput(*file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	res, err := e.workerService.ImportPersons(ctx, *tenantID, in, nil)
+	if err != nil {
+		return err
+	}
+	return e.print(res)
+}
+
+func runExport(ctx context.Context, e *env, args []string) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	tenantID := fs.String("tenant", "", "tenant, the persons without one when empty")
+	file := fs.String("out", "", "json lines file, stdout when empty")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	out := e.out
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if errClose := f.Close(); err == nil {
+				err = errClose
+			}
+		}()
+		out = f
+	}
+
+	persons, err := e.workerService.ExportTenant(ctx, *tenantID, out, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d persons exported\n", persons)
+	return nil
+}
+
+func runVerifyTLS(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("verify-tls", flag.ContinueOnError)
+	certFile := fs.String("cert", "", "base64 encoded cert (full chain) file, TLS_CERT_FILE when empty")
+	keyFile := fs.String("key", "", "base64 encoded private key file, TLS_KEY_FILE when empty")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *certFile == "" {
+		*certFile = e.values.String("TLS_CERT_FILE")
+	}
+	if *keyFile == "" {
+		*keyFile = e.values.String("TLS_KEY_FILE")
+	}
+
+	cert, err := configuration.ReadCert(*certFile, *keyFile)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := server.SetTLSOn(cert.CertPEM, cert.CertPrivKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	chain := tlsConfig.Certificates[0].Certificate
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return fmt.Errorf("cert not valid now, valid from %s to %s", leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	return e.print(map[string]any{	"cert_file": *certFile,
+									"key_file": *keyFile,
+									"subject": leaf.Subject.String(),
+									"issuer": leaf.Issuer.String(),
+									"dns_names": leaf.DNSNames,
+									"not_before": leaf.NotBefore,
+									"not_after": leaf.NotAfter,
+									"expires_in_days": int(leaf.NotAfter.Sub(now).Hours() / 24),
+									"chain_length": len(chain),
+	})
+}
+
+func runConfig(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	type entry struct {
+		Key		string	`json:"key"`
+		Value	string	`json:"value"`
+		Origin	string	`json:"origin"`
+	}
+	entries := []entry{}
+	for _, key := range e.values.Keys() {
+		entries = append(entries, entry{Key: key, Value: e.values.Redacted(key), Origin: e.values.Origin(key)})
+	}
+	return e.print(entries)
+}
@@ -1,30 +1,44 @@
 package main
 
 import(
+	"os"
 	"time"
 	"context"
 	
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-onboarding/internal/infra/configuration"
 	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/dedup"
 	"github.com/go-onboarding/internal/core/service"
 	"github.com/go-onboarding/internal/infra/server"
 	"github.com/go-onboarding/internal/adapter/api"
+	"github.com/go-onboarding/internal/adapter/rpc"
+	"github.com/go-onboarding/internal/adapter/bucket"
 	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/notify"
+	"github.com/go-onboarding/internal/infra/cache"
+	"github.com/go-onboarding/internal/infra/credential"
+	"github.com/go-onboarding/internal/infra/encryption"
+	"github.com/go-onboarding/internal/infra/health"
+	"github.com/go-onboarding/internal/infra/jobs"
+	"github.com/go-onboarding/internal/infra/loadshed"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/openapi"
+	"github.com/go-onboarding/internal/infra/ratelimit"
+	"github.com/go-onboarding/internal/infra/retry"
 
-	go_core_pg "github.com/eliezerraj/go-core/database/pg"
 	go_core_aws_config "github.com/eliezerraj/go-core/aws/aws_config"
 	go_core_s3_bucket "github.com/eliezerraj/go-core/aws/bucket_s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
 )
 
 var(
-	logLevel = 	zerolog.InfoLevel // zerolog.InfoLevel zerolog.DebugLevel
 	appServer	model.AppServer
-	databaseConfig 		go_core_pg.DatabaseConfig
-	databasePGServer 	go_core_pg.DatabasePGServer
+	databasePGServer 	*database.DatabasePGServer
 	goCoreAwsConfig 	go_core_aws_config.AwsConfig
 	goCoreAwsBucketS3	go_core_s3_bucket.AwsBucketS3
 
@@ -34,21 +48,20 @@ var(
 // Above init
 func init(){
 	childLogger.Info().Str("func","init").Send()
-	zerolog.SetGlobalLevel(logLevel)
 
-	infoPod, server := configuration.GetInfoPod()
-	configOTEL 		:= configuration.GetOtelEnv()
-	databaseConfig 	:= configuration.GetDatabaseEnv()
-	certsTls 		:= configuration.GetCertEnv() 
-
-	awsService 		:= configuration.GetAwsServiceEnv() 
+	var err error
+	appServer, err = configuration.Load(os.Args[1:])
+	if err != nil {
+		log.Error().Err(err).Msg("fatal error load configuration aborting")
+		os.Exit(1)
+	}
 
-	appServer.InfoPod = &infoPod
-	appServer.Server = &server
-	appServer.ConfigOTEL = &configOTEL
-	appServer.AwsService = &awsService
-	appServer.Cert = &certsTls
-	appServer.DatabaseConfig = &databaseConfig
+	logging.Setup(appServer.Log.Level, appServer.Log.Format)
+	logging.SetMaskFields(appServer.Log.MaskFields)
+	if err := logging.SetTrustedProxies(appServer.Server.TrustedProxies); err != nil {
+		log.Error().Err(err).Msg("fatal error load trusted proxies aborting")
+		os.Exit(1)
+	}
 }
 
 // Above main
@@ -58,29 +71,98 @@ func main (){
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Open Database
-	count := 1
-	var err error
-	for {
-		databasePGServer, err = databasePGServer.NewDatabasePGServer(ctx, *appServer.DatabaseConfig)
+	// the background loops are stopped by its shutdown, before the resources are closed
+	httpServer := server.NewHttpAppServer(appServer.Server)
+
+	// Prepare aws services
+	awsConfig, err := goCoreAwsConfig.NewAWSConfig(ctx, appServer.AwsService.AwsRegion)
+	if err != nil {
+		log.Error().Err(err).Msg("fatal error create new aws session aborting")
+		os.Exit(1)
+	}
+
+	// Database credentials
+	var credentialProvider credential.Provider
+	if appServer.DatabaseAuth.Mode == "iam" {
+		credentialProvider = credential.NewIAMProvider(	appServer.DatabaseConfig.Host + ":" + appServer.DatabaseConfig.Port,
+														appServer.AwsService.AwsRegion,
+														appServer.DatabaseAuth.IAMUser,
+														awsConfig.Credentials)
+	} else {
+		fileProvider, err := credential.NewFileProvider(appServer.DatabaseAuth.UserFile, appServer.DatabaseAuth.PasswordFile)
 		if err != nil {
-			if count < 3 {
-				log.Error().Err(err).Msg("error open database... trying again !!")
-			} else {
-				log.Error().Err(err).Msg("fatal error open Database aborting")
-				panic(err)
-			}
-			time.Sleep(3 * time.Second) //backoff
-			count = count + 1
-			continue
+			log.Error().Err(err).Msg("fatal error read database credentials aborting")
+			os.Exit(1)
 		}
-		break
+		httpServer.Go(ctx, "credential_watch", func(ctx context.Context) {
+			fileProvider.Watch(ctx, time.Duration(appServer.DatabaseAuth.RefreshInterval) * time.Second)
+		})
+		credentialProvider = fileProvider
 	}
 
-	// Prepare aws services
-	awsConfig, err := goCoreAwsConfig.NewAWSConfig(ctx, appServer.AwsService.AwsRegion)
+	// Open Database
+	connectPolicy := retry.Policy{	MaxAttempts: appServer.DatabaseRetry.ConnectAttempts,
+									BaseDelay: time.Duration(appServer.DatabaseRetry.ConnectBaseDelay) * time.Millisecond,
+									MaxDelay: time.Duration(appServer.DatabaseRetry.ConnectMaxDelay) * time.Millisecond,
+	}
+	err = retry.Do(ctx, connectPolicy, "database_connect", database.IsRetryable, func(ctx context.Context) error {
+		var err error
+		databasePGServer, err = database.NewDatabasePGServer(ctx, *appServer.DatabaseConfig, credentialProvider)
+		return err
+	})
 	if err != nil {
-		panic("error create new aws session " + err.Error())
+		log.Error().Err(err).Msg("fatal error open Database aborting")
+		os.Exit(1)
+	}
+
+	// Open the reader, the reads stay in the primary when it is not reachable
+	var readRouter *database.ReadRouter
+	var readerPGServer *database.DatabasePGServer
+	if appServer.DatabaseReader.IsEnabled {
+		readerConfig := *appServer.DatabaseConfig
+		readerConfig.Host = appServer.DatabaseReader.Host
+		readerConfig.Port = appServer.DatabaseReader.Port
+		readerConfig.DbMax_Connection = appServer.DatabaseReader.MaxConnection
+
+		readerCredentialProvider := credentialProvider
+		if appServer.DatabaseAuth.Mode == "iam" {
+			// the iam token is bound to the endpoint
+			readerCredentialProvider = credential.NewIAMProvider(	readerConfig.Host + ":" + readerConfig.Port,
+																	appServer.AwsService.AwsRegion,
+																	appServer.DatabaseAuth.IAMUser,
+																	awsConfig.Credentials)
+		}
+
+		readerPGServer, err = database.NewDatabasePGServer(ctx, readerConfig, readerCredentialProvider)
+		if err != nil {
+			log.Error().Err(err).Msg("error open database reader, reads will use the primary !!")
+		} else {
+			readRouter = database.NewReadRouter(readerPGServer, time.Duration(appServer.DatabaseReader.ReadYourWrites) * time.Second)
+			httpServer.Go(ctx, "reader_watch", func(ctx context.Context) {
+				readRouter.Watch(ctx, time.Duration(appServer.DatabaseReader.CheckInterval) * time.Second)
+			})
+		}
+	}
+
+	// Field encryption of the person PII, the data keys are shared by the replicas in the database
+	var keyring *encryption.Keyring
+	if appServer.Encryption.IsEnabled {
+		keyProvider, err := encryption.NewKeyProvider(encryption.ProviderConfig{	Provider: appServer.Encryption.KeyProvider,
+																				KeyFile: appServer.Encryption.KeyFile,
+																				KMSKeyID: appServer.Encryption.KMSKeyID,
+																			}, awsConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("fatal error load encryption master keys aborting")
+			os.Exit(1)
+		}
+		keyring, err = encryption.NewKeyring(ctx, keyProvider, database.NewKeyRepository(databasePGServer))
+		if err != nil {
+			log.Error().Err(err).Msg("fatal error load encryption data keys aborting")
+			os.Exit(1)
+		}
+		httpServer.Go(ctx, "keyring_watch", func(ctx context.Context) {
+			keyring.Watch(ctx, time.Duration(appServer.Encryption.ReloadInterval) * time.Second)
+		})
 	}
 
 	// Otel over aws services
@@ -89,12 +171,238 @@ func main (){
 	// Create a S3 worker
 	s3BucketWorker := goCoreAwsBucketS3.NewAwsS3Bucket(awsConfig)
 
+	// the queue of the asynchronous jobs, shared by the replicas
+	var jobQueue *database.JobRepository
+	if appServer.Job.IsEnabled {
+		jobQueue = database.NewJobRepository(databasePGServer)
+		if err := jobQueue.Check(ctx); err != nil {
+			log.Error().Err(err).Msg("fatal error check job queue aborting")
+			os.Exit(1)
+		}
+	}
+
 	// wire	
-	database := database.NewWorkerRepository(&databasePGServer)
+	database := database.NewWorkerRepository(databasePGServer)
+	database.SetReadRouter(readRouter)
+	database.SetKeyring(keyring)
 	workerService := service.NewWorkerService(database, s3BucketWorker, appServer.AwsService)
-	httpRouters := api.NewHttpRouters(workerService, time.Duration(appServer.Server.CtxTimeout))
+	workerService.SetRetryPolicy(retry.Policy{	MaxAttempts: appServer.DatabaseRetry.Attempts,
+												BaseDelay: time.Duration(appServer.DatabaseRetry.BaseDelay) * time.Millisecond,
+												MaxDelay: time.Duration(appServer.DatabaseRetry.MaxDelay) * time.Millisecond,
+	})
 
-	// start server
-	httpServer := server.NewHttpAppServer(appServer.Server)
-	httpServer.StartHttpAppServer(ctx, &httpRouters, &appServer)
+	// the persons matching a person of the tenant are refused, the possible duplicates flagged
+	if appServer.Dedup.IsEnabled {
+		workerService.SetDedupPolicy(dedup.Policy{	MatchThreshold: float64(appServer.Dedup.MatchPercent) / 100,
+													ReviewThreshold: float64(appServer.Dedup.ReviewPercent) / 100,
+		})
+	}
+
+	// the files uploaded for a person, exported and erased with it
+	if appServer.AwsService.BucketName != "" {
+		workerService.SetPersonBucket(bucket.NewPersonBucket(s3.NewFromConfig(*awsConfig), appServer.AwsService.BucketName, appServer.AwsService.FilePath))
+	}
+
+	// the events are delivered to the webhook subscriptions in background
+	if appServer.Webhook.IsEnabled {
+		webhookTimeout := time.Duration(appServer.Webhook.Timeout) * time.Second
+		workerService.SetWebhook(notify.NewNotifier(webhookTimeout, appServer.Webhook.AllowHTTP), retry.Policy{	MaxAttempts: appServer.Webhook.MaxAttempts,
+																					BaseDelay: time.Duration(appServer.Webhook.BaseDelay) * time.Second,
+																					MaxDelay: time.Duration(appServer.Webhook.MaxDelay) * time.Second,
+		}, appServer.Webhook.AllowHTTP)
+		httpServer.Go(ctx, "webhook_dispatch", func(ctx context.Context) {
+			workerService.DispatchWebhook(ctx, time.Duration(appServer.Webhook.Interval) * time.Second, appServer.Webhook.Batch, appServer.Webhook.Concurrency, webhookTimeout)
+		})
+	}
+
+	// the persons of older data keys (or in plaintext) are encrypted again in background
+	if keyring != nil && appServer.Encryption.ReencryptInterval > 0 {
+		httpServer.Go(ctx, "reencrypt", func(ctx context.Context) {
+			workerService.Reencrypt(ctx, time.Duration(appServer.Encryption.ReencryptInterval) * time.Second, appServer.Encryption.ReencryptBatch)
+		})
+	}
+
+	// the asynchronous jobs (imports, exports, re-encryption) run by a pool of workers
+	var jobPool *jobs.Pool
+	if jobQueue != nil {
+		workerService.SetJobQueue(jobQueue, appServer.Job.MaxAttempts)
+		jobPool = jobs.NewPool(jobQueue, jobs.Config{	Concurrency: appServer.Job.Concurrency,
+														PollInterval: time.Duration(appServer.Job.PollInterval) * time.Second,
+														Lease: time.Duration(appServer.Job.Lease) * time.Second,
+														Retry: retry.Policy{	BaseDelay: time.Duration(appServer.Job.BaseDelay) * time.Second,
+																				MaxDelay: time.Duration(appServer.Job.MaxDelay) * time.Second,
+														},
+		})
+		workerService.RegisterJobs(jobPool)
+		jobPool.Start(ctx)
+	}
+
+	// the tenants labelled in the business metrics
+	metrics.SetTenants(appServer.Metrics.Tenants)
+
+	// pool gauges
+	metrics.RegisterPool("primary", databasePGServer.Stat)
+	if readerPGServer != nil {
+		metrics.RegisterPool("reader", readerPGServer.Stat)
+	}
+
+	// health checks
+	healthCheck := health.NewHealth()
+	checkTimeout := time.Duration(appServer.HealthCheck.Timeout) * time.Second
+	checkCacheTTL := time.Duration(appServer.HealthCheck.CacheTTL) * time.Second
+	healthCheck.Register(health.Check{	Name: "database",
+										Probes: []health.Probe{health.ProbeReady, health.ProbeStartup},
+										Critical: true,
+										Timeout: checkTimeout,
+										CacheTTL: checkCacheTTL,
+										Check: health.PingCheck(databasePGServer.Ping),
+	})
+	healthCheck.Register(health.Check{	Name: "database_pool",
+										Probes: []health.Probe{health.ProbeReady},
+										Timeout: checkTimeout,
+										Check: health.PoolSaturationCheck(databasePGServer.Stat, appServer.HealthCheck.PoolSaturationPercent),
+	})
+	if readerPGServer != nil {
+		// not critical, the reads fall back to the primary
+		healthCheck.Register(health.Check{	Name: "database_reader",
+											Probes: []health.Probe{health.ProbeReady},
+											Timeout: checkTimeout,
+											CacheTTL: checkCacheTTL,
+											Check: health.PingCheck(readerPGServer.Ping),
+		})
+	}
+	if appServer.AwsService.BucketName != "" {
+		healthCheck.Register(health.Check{	Name: "bucket_s3",
+											Probes: []health.Probe{health.ProbeReady, health.ProbeStartup},
+											Timeout: checkTimeout,
+											CacheTTL: checkCacheTTL,
+											Check: health.HeadBucketCheck(s3.NewFromConfig(*awsConfig), appServer.AwsService.BucketName),
+		})
+	}
+	healthCheck.Register(health.Check{	Name: "disk_upload",
+										Probes: []health.Probe{health.ProbeReady},
+										Timeout: checkTimeout,
+										CacheTTL: checkCacheTTL,
+										Check: health.DiskFreeCheck(appServer.HealthCheck.DiskPath, uint64(appServer.HealthCheck.DiskMinFreeMB) << 20),
+	})
+
+	httpRouters := api.NewHttpRouters(workerService, time.Duration(appServer.Server.CtxTimeout), healthCheck)
+	httpRouters.SetExportTimeout(time.Duration(appServer.Server.ExportTimeout))
+
+	// openapi document served and validated
+	openAPI, err := openapi.NewValidator(appServer.OpenAPI.ValidateRequest, appServer.OpenAPI.ValidateResponse)
+	if err != nil {
+		log.Error().Err(err).Msg("fatal error load openapi document aborting")
+		os.Exit(1)
+	}
+	httpServer.SetOpenAPI(openAPI)
+
+	// bearer tokens of the /admin routes
+	if err := httpServer.LoadAdminTokens(); err != nil {
+		log.Error().Err(err).Msg("fatal error load admin tokens aborting")
+		os.Exit(1)
+	}
+
+	// grpc server for the internal services, drained before the resources are closed
+	var grpcServer *server.GrpcServer
+	grpcRouters := rpc.NewGrpcRouters(workerService, time.Duration(appServer.Server.CtxTimeout))
+	if appServer.Server.GrpcPort > 0 {
+		grpcServer, err = server.NewGrpcAppServer(appServer.Server)
+		if err != nil {
+			log.Error().Err(err).Msg("fatal error create grpc server aborting")
+			os.Exit(1)
+		}
+		httpServer.AddDrainer("grpc", grpcServer.Shutdown)
+	}
+	// the running jobs are waited (or queued again) before the database is closed
+	if jobPool != nil {
+		httpServer.AddDrainer("jobs", jobPool.Shutdown)
+	}
+	httpServer.AddCloser("database", func(ctx context.Context) error {
+		databasePGServer.CloseConnection()
+		return nil
+	})
+	if readerPGServer != nil {
+		httpServer.AddCloser("database_reader", func(ctx context.Context) error {
+			readerPGServer.CloseConnection()
+			return nil
+		})
+	}
+
+	// person cache
+	if appServer.Cache.IsEnabled {
+		var shared cache.Backend
+		if appServer.Cache.SharedBackend == "redis" {
+			redisBackend, err := cache.NewRedisBackend(appServer.Cache.RedisURL, "go-onboarding:cache:")
+			if err != nil {
+				log.Error().Err(err).Msg("fatal error create cache backend aborting")
+				os.Exit(1)
+			}
+			httpServer.AddCloser("cache_backend", func(ctx context.Context) error {
+				return redisBackend.Close()
+			})
+			shared = redisBackend
+		}
+		workerService.SetPersonCache(cache.New("person", cache.Config{
+			TTL: time.Duration(appServer.Cache.TTL) * time.Second,
+			NegativeTTL: time.Duration(appServer.Cache.NegativeTTL) * time.Second,
+			NotFound: erro.ErrNotFound,
+			LoadTimeout: time.Duration(appServer.Server.CtxTimeout) * time.Second,
+		}, cache.NewLRU(appServer.Cache.Size), shared))
+	}
+
+	// rate limit
+	if appServer.RateLimit.IsEnabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if appServer.RateLimit.Store == "redis" {
+			redisStore, err := ratelimit.NewRedisStore(appServer.RateLimit.RedisURL)
+			if err != nil {
+				log.Error().Err(err).Msg("fatal error create rate limit store aborting")
+				os.Exit(1)
+			}
+			httpServer.AddCloser("rate_limit_store", func(ctx context.Context) error {
+				return redisStore.Close()
+			})
+			store = redisStore
+		}
+		rateLimiter := ratelimit.NewLimiter(store, map[ratelimit.Class]ratelimit.Limit{
+			ratelimit.ClassRead: {Rate: float64(appServer.RateLimit.ReadRate), Burst: appServer.RateLimit.ReadBurst},
+			ratelimit.ClassWrite: {Rate: float64(appServer.RateLimit.WriteRate), Burst: appServer.RateLimit.WriteBurst},
+			ratelimit.ClassUpload: {Rate: float64(appServer.RateLimit.UploadRate), Burst: appServer.RateLimit.UploadBurst},
+		})
+		httpServer.SetRateLimiter(rateLimiter)
+		if grpcServer != nil {
+			grpcServer.SetRateLimiter(rateLimiter)
+		}
+	}
+
+	// load shedding
+	if appServer.LoadShed.IsEnabled {
+		loadShedder := loadshed.NewLimiter(loadshed.Config{
+			InitialLimit: appServer.LoadShed.InitialLimit,
+			MinLimit: appServer.LoadShed.MinLimit,
+			MaxLimit: appServer.LoadShed.MaxLimit,
+			LatencyTolerance: float64(appServer.LoadShed.LatencyTolerancePercent) / 100,
+			WriteShare: float64(appServer.LoadShed.WriteSharePercent) / 100,
+			Window: time.Duration(appServer.LoadShed.Window) * time.Millisecond,
+		}, databasePGServer.Stat)
+		httpServer.SetLoadShedder(loadShedder)
+		if grpcServer != nil {
+			grpcServer.SetLoadShedder(loadShedder)
+		}
+	}
+
+	if grpcServer != nil {
+		if err := grpcServer.StartGrpcAppServer(ctx, &grpcRouters, healthCheck, &appServer); err != nil {
+			log.Error().Err(err).Msg("fatal error start grpc server aborting")
+			os.Exit(1)
+		}
+	}
+
+	err = httpServer.StartHttpAppServer(ctx, &httpRouters, &appServer)
+	cancel()
+	if err != nil {
+		log.Error().Err(err).Msg("shutdown with errors")
+		os.Exit(1)
+	}
 }
\ No newline at end of file
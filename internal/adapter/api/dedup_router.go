@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"time"
+	"context"
+	"net/http"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// About list the possible duplicates flagged by the onboarding: the tenant_id and status query params
+func (h *HttpRouters) ListDuplicate(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListDuplicate").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ListDuplicate")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	query := req.URL.Query()
+	logging.SetTenant(ctx, query.Get("tenant_id"))
+
+	res, err := h.workerService.ListDuplicate(ctx, query.Get("tenant_id"), query.Get("status"))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About dismiss (POST) a possible duplicate, the persons are not the same
+func (h *HttpRouters) DismissDuplicate(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","DismissDuplicate").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.DismissDuplicate")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	res, err := h.workerService.DismissDuplicate(ctx, int64(id))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About merge (POST) a duplicate person into the survivor, the body has both person_id
+func (h *HttpRouters) MergePerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","MergePerson").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.MergePerson")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	merge := model.PersonMerge{}
+	err = json.NewDecoder(req.Body).Decode(&merge)
+	if err != nil {
+		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+	}
+	defer req.Body.Close()
+
+	res, err := h.workerService.MergePerson(ctx, &merge)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"time"
+	"context"
+	"strconv"
+	"net/http"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// About queue (POST) a asynchronous job, the job is returned with its id to follow it
+func (h *HttpRouters) EnqueueJob(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","EnqueueJob").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.EnqueueJob")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	job := model.AsyncJob{}
+	err = json.NewDecoder(req.Body).Decode(&job)
+	if err != nil {
+		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+	}
+	defer req.Body.Close()
+
+	res, err := h.workerService.EnqueueJob(ctx, &job)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About list the jobs of the tenant_id query param: the kind, status, limit and offset query params
+func (h *HttpRouters) ListJob(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListJob").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ListJob")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	query := req.URL.Query()
+	jobList := model.AsyncJobList{	TenantID: query.Get("tenant_id"),
+									Kind: query.Get("kind"),
+									Status: query.Get("status"),
+	}
+	logging.SetTenant(ctx, jobList.TenantID)
+	if value := query.Get("limit"); value != "" {
+		if jobList.Limit, err = strconv.Atoi(value); err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if jobList.Offset, err = strconv.Atoi(value); err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+	}
+
+	res, err := h.workerService.ListJob(ctx, &jobList)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About get a job with its status and progress
+func (h *HttpRouters) GetJob(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","GetJob").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.GetJob")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	res, err := h.workerService.GetJob(ctx, int64(id))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About cancel (POST) a job, a finished one is a conflict
+func (h *HttpRouters) CancelJob(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","CancelJob").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.CancelJob")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	res, err := h.workerService.CancelJob(ctx, int64(id))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
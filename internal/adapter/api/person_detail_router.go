@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"time"
+	"context"
+	"strconv"
+	"net/http"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/gorilla/mux"
+)
+
+// About get a integer path variable
+func intVar(req *http.Request, name string) (int, error) {
+	value, err := strconv.Atoi(mux.Vars(req)[name])
+	if err != nil {
+		return 0, erro.ErrBadRequest
+	}
+	return value, nil
+}
+
+// About list the addresses of a person
+func (h *HttpRouters) ListAddress(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListAddress").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ListAddress")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	res, err := h.workerService.ListAddress(ctx, mux.Vars(req)["id"])
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About add a address (POST) or update it (PUT /{address_id})
+func (h *HttpRouters) SaveAddress(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","SaveAddress").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.SaveAddress")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	address := model.Address{}
+	err = json.NewDecoder(req.Body).Decode(&address)
+	if err != nil {
+		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+	}
+	defer req.Body.Close()
+
+	var res *model.Address
+	if req.Method == http.MethodPut {
+		address.ID, err = intVar(req, "address_id")
+		if err != nil {
+			return h.ErrorHandler(trace_id, err)
+		}
+		res, err = h.workerService.UpdateAddress(ctx, mux.Vars(req)["id"], &address)
+	} else {
+		res, err = h.workerService.AddAddress(ctx, mux.Vars(req)["id"], &address)
+	}
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About delete a address
+func (h *HttpRouters) DeleteAddress(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","DeleteAddress").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.DeleteAddress")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	addressID, err := intVar(req, "address_id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	err = h.workerService.DeleteAddress(ctx, mux.Vars(req)["id"], addressID)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, model.MessageRouter{Message: "true"})
+}
+
+// About list the identity documents of a person
+func (h *HttpRouters) ListDocument(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListDocument").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ListDocument")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	res, err := h.workerService.ListDocument(ctx, mux.Vars(req)["id"])
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About add a identity document (POST) or update it (PUT /{document_id})
+func (h *HttpRouters) SaveDocument(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","SaveDocument").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.SaveDocument")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	document := model.Document{}
+	err = json.NewDecoder(req.Body).Decode(&document)
+	if err != nil {
+		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+	}
+	defer req.Body.Close()
+
+	var res *model.Document
+	if req.Method == http.MethodPut {
+		document.ID, err = intVar(req, "document_id")
+		if err != nil {
+			return h.ErrorHandler(trace_id, err)
+		}
+		res, err = h.workerService.UpdateDocument(ctx, mux.Vars(req)["id"], &document)
+	} else {
+		res, err = h.workerService.AddDocument(ctx, mux.Vars(req)["id"], &document)
+	}
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About delete a identity document
+func (h *HttpRouters) DeleteDocument(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","DeleteDocument").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.DeleteDocument")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	documentID, err := intVar(req, "document_id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	err = h.workerService.DeleteDocument(ctx, mux.Vars(req)["id"], documentID)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, model.MessageRouter{Message: "true"})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"errors"
 	"time"
 	"context"
 	"encoding/json"
@@ -9,65 +10,117 @@ import (
 	"reflect"
 	"io/ioutil"
 	"strings"
+	"strconv"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-onboarding/internal/core/service"
 	"github.com/go-onboarding/internal/core/model"
 	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/health"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
 
 	"github.com/eliezerraj/go-core/coreJson"
 	"github.com/gorilla/mux"
-
-	go_core_observ "github.com/eliezerraj/go-core/observability"
 )
 
 var childLogger = log.With().Str("component", "go-onboarding").Str("package", "internal.adapter.api").Logger()
 
 var core_json coreJson.CoreJson
 var core_apiError coreJson.APIError
-var tracerProvider go_core_observ.TracerProvider
+
+// The conflict of a onboarding refused as a duplicate, with the persons matched
+type duplicateError struct {
+	coreJson.APIError
+	Candidates	[]model.PersonDuplicate	`json:"candidates"`
+}
 
 type HttpRouters struct {
 	workerService 	*service.WorkerService
 	ctxTimeout		time.Duration
+	exportTimeout	time.Duration
+	health			*health.Health
 }
 
 // Above create routers
 func NewHttpRouters(workerService *service.WorkerService,
-					ctxTimeout	time.Duration) HttpRouters {
+					ctxTimeout	time.Duration,
+					health		*health.Health) HttpRouters {
 	childLogger.Info().Str("func","NewHttpRouters").Send()
 
 	return HttpRouters{
 		workerService: workerService,
 		ctxTimeout: ctxTimeout,
+		exportTimeout: ctxTimeout,
+		health: health,
+	}
+}
+
+// About set the seconds to write a export archive, the ctx timeout until set
+func (h *HttpRouters) SetExportTimeout(exportTimeout time.Duration) {
+	h.exportTimeout = exportTimeout
+}
+
+// About set the readiness, it is turned off when the shutdown begins
+func (h *HttpRouters) SetReady(ready bool) {
+	h.health.SetReady(ready)
+}
+
+// About write a probe report, the checks details only when ?verbose=true
+func (h *HttpRouters) writeProbe(rw http.ResponseWriter, req *http.Request, probe health.Probe) {
+	report := h.health.Run(req.Context(), probe)
+
+	rw.Header().Set("Content-Type", "application/json")
+	if report.Status != health.StatusUp {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if verbose, _ := strconv.ParseBool(req.URL.Query().Get("verbose")); verbose {
+		json.NewEncoder(rw).Encode(report)
+		return
 	}
+	json.NewEncoder(rw).Encode(model.MessageRouter{Message: strconv.FormatBool(report.Status == health.StatusUp)})
 }
 
-// About return a health
+// About return a health, kept as an alias of ready
 func (h *HttpRouters) Health(rw http.ResponseWriter, req *http.Request) {
-	childLogger.Info().Str("func","Health").Send()
+	childLogger.Debug().Str("func","Health").Send()
 
-	json.NewEncoder(rw).Encode(model.MessageRouter{Message: "true"})
+	h.writeProbe(rw, req, health.ProbeReady)
 }
 
 // About return a live
 func (h *HttpRouters) Live(rw http.ResponseWriter, req *http.Request) {
-	childLogger.Info().Str("func","Live").Send()
+	childLogger.Debug().Str("func","Live").Send()
 
-	json.NewEncoder(rw).Encode(model.MessageRouter{Message: "true"})
+	h.writeProbe(rw, req, health.ProbeLive)
+}
+
+// About return a ready
+func (h *HttpRouters) Ready(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Debug().Str("func","Ready").Send()
+
+	h.writeProbe(rw, req, health.ProbeReady)
+}
+
+// About return a startup
+func (h *HttpRouters) Startup(rw http.ResponseWriter, req *http.Request) {
+	childLogger.Debug().Str("func","Startup").Send()
+
+	h.writeProbe(rw, req, health.ProbeStartup)
 }
 
 // About show all header received
 func (h *HttpRouters) Header(rw http.ResponseWriter, req *http.Request) {
-	childLogger.Info().Str("func","Header").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","Header").Send()
 	
 	json.NewEncoder(rw).Encode(req.Header)
 }
 
 // About show all context values
 func (h *HttpRouters) Context(rw http.ResponseWriter, req *http.Request) {
-	childLogger.Info().Str("func","Context").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","Context").Send()
 	
 	contextValues := reflect.ValueOf(req.Context()).Elem()
 	json.NewEncoder(rw).Encode(fmt.Sprintf("%v",contextValues))
@@ -75,25 +128,96 @@ func (h *HttpRouters) Context(rw http.ResponseWriter, req *http.Request) {
 
 // About show pgx stats
 func (h *HttpRouters) Stat(rw http.ResponseWriter, req *http.Request) {
-	childLogger.Info().Str("func","Stat").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","Stat").Send()
 	
 	res := h.workerService.Stat(req.Context())
 
 	json.NewEncoder(rw).Encode(res)
 }
 
+// About get (GET) or change (PUT) the log level and format at runtime
+func (h *HttpRouters) LogConfig(rw http.ResponseWriter, req *http.Request) error {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","LogConfig").Send()
+
+	trace_id := fmt.Sprintf("%v", req.Context().Value("trace-request-id"))
+
+	if req.Method == http.MethodPut {
+		logConfig := model.Log{}
+		err := json.NewDecoder(req.Body).Decode(&logConfig)
+		if err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+		defer req.Body.Close()
+
+		if logConfig.Format != "" {
+			if err := logging.SetFormat(logConfig.Format); err != nil {
+				return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+			}
+		}
+		if logConfig.Level != "" {
+			if err := logging.SetLevel(logConfig.Level); err != nil {
+				return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+			}
+		}
+		if logConfig.MaskFields != nil {
+			logging.SetMaskFields(logConfig.MaskFields)
+		}
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, model.Log{Level: logging.Level(), Format: logging.Format(), MaskFields: logging.MaskFields()})
+}
+
+// About get (GET) the encryption keys status, create a data key version (POST rotate)
+// or wrap the data keys with the current master key (POST rewrap)
+func (h *HttpRouters) EncryptionKeys(rw http.ResponseWriter, req *http.Request) error {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","EncryptionKeys").Send()
+
+	trace_id := fmt.Sprintf("%v", req.Context().Value("trace-request-id"))
+
+	var res *model.EncryptionStatus
+	var err error
+	switch mux.Vars(req)["action"] {
+	case "":
+		res, err = h.workerService.EncryptionStatus(req.Context())
+	case "rotate":
+		res, err = h.workerService.RotateEncryptionKey(req.Context())
+	case "rewrap":
+		res, err = h.workerService.RewrapEncryptionKeys(req.Context())
+	default:
+		err = erro.ErrNotFound
+	}
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
 // About handle error
 func (h *HttpRouters) ErrorHandler(trace_id string, err error) *coreJson.APIError {
 	if strings.Contains(err.Error(), "context deadline exceeded") {
     	err = erro.ErrTimeout
 	} 
+	// the validation errors keep the invalid fields in the message
+	if errors.Is(err, erro.ErrInvalid) {
+		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusBadRequest)
+		return &core_apiError
+	}
 	switch err {
 	case erro.ErrBadRequest:
 		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusBadRequest)
 	case erro.ErrNotFound:
 		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusNotFound)
+	case erro.ErrUnauthorized:
+		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusUnauthorized)
+	case erro.ErrHTTPForbiden:
+		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusForbidden)
 	case erro.ErrTimeout:
 		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusGatewayTimeout)
+	case erro.ErrDuplicateTaxID, erro.ErrDuplicatePerson, erro.ErrJobFinished:
+		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusConflict)
+	case erro.ErrEncryptionDisabled, erro.ErrWebhookDisabled, erro.ErrJobDisabled:
+		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusNotImplemented)
 	default:
 		core_apiError = core_apiError.NewAPIError(err, trace_id, http.StatusInternalServerError)
 	}
@@ -101,25 +225,32 @@ func (h *HttpRouters) ErrorHandler(trace_id string, err error) *coreJson.APIErro
 }
 
 // About add person
-func (h *HttpRouters) AddPerson(rw http.ResponseWriter, req *http.Request) error {
-	childLogger.Info().Str("func","AddPerson").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+func (h *HttpRouters) AddPerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","AddPerson").Send()
 
 	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
     defer cancel()
 
-	span := tracerProvider.Span(ctx, "adapter.api.AddPerson")
-	defer span.End()
+	ctx, span := tracing.Start(ctx, "adapter.api.AddPerson")
+	defer func() { tracing.End(span, err) }()
 
 	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
 
 	onBoarding := model.Onboarding{}
-	err := json.NewDecoder(req.Body).Decode(&onBoarding)
+	err = json.NewDecoder(req.Body).Decode(&onBoarding)
     if err != nil {
 		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
     }
 	defer req.Body.Close()
 
 	res, err := h.workerService.AddPerson(ctx, &onBoarding)
+	var duplicate *model.DuplicateError
+	if errors.As(err, &duplicate) {
+		// the candidates are returned with the conflict, to be reviewed or merged
+		return core_json.WriteJSON(rw, http.StatusConflict, duplicateError{	APIError: *h.ErrorHandler(trace_id, erro.ErrDuplicatePerson),
+																			Candidates: duplicate.Candidates,
+		})
+	}
 	if err != nil {
 		return h.ErrorHandler(trace_id, err)
 	}
@@ -128,14 +259,14 @@ func (h *HttpRouters) AddPerson(rw http.ResponseWriter, req *http.Request) error
 }
 
 // About get person
-func (h *HttpRouters) GetPerson(rw http.ResponseWriter, req *http.Request) error {
-	childLogger.Info().Str("func","GetPerson").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+func (h *HttpRouters) GetPerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","GetPerson").Send()
 
 	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
     defer cancel()
 
-	span := tracerProvider.Span(ctx, "adapter.api.GetPerson")
-	defer span.End()
+	ctx, span := tracing.Start(ctx, "adapter.api.GetPerson")
+	defer func() { tracing.End(span, err) }()
 
 	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
 
@@ -155,20 +286,143 @@ func (h *HttpRouters) GetPerson(rw http.ResponseWriter, req *http.Request) error
 	return core_json.WriteJSON(rw, http.StatusOK, res)
 }
 
+// About find the persons of a tenant by the exact tax_id or email query param
+func (h *HttpRouters) LookupPerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","LookupPerson").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+    defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.LookupPerson")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	query := req.URL.Query()
+	onBoarding := model.Onboarding{Person: &model.Person{	TenantID: query.Get("tenant_id"),
+															TaxID: query.Get("tax_id"),
+															Email: query.Get("email"),
+	}}
+	logging.SetTenant(ctx, onBoarding.Person.TenantID)
+
+	res, err := h.workerService.LookupPerson(ctx, &onBoarding)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About search the persons of a tenant by the name: the q, tenant_id, limit and offset query params
+func (h *HttpRouters) SearchPerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","SearchPerson").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+    defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.SearchPerson")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	query := req.URL.Query()
+	personSearch := model.PersonSearch{	Query: query.Get("q"),
+										TenantID: query.Get("tenant_id"),
+	}
+	if value := query.Get("limit"); value != "" {
+		if personSearch.Limit, err = strconv.Atoi(value); err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if personSearch.Offset, err = strconv.Atoi(value); err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+	}
+	logging.SetTenant(ctx, personSearch.TenantID)
+
+	res, err := h.workerService.SearchPerson(ctx, &personSearch)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About export (GET) all the data held about a person as a zip
+func (h *HttpRouters) ExportPerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ExportPerson").Send()
+
+	// the archive is written after the status, with the files of the person it may take
+	// longer than a request: it has its own deadline, the reads keep the ctx timeout
+	ctx, cancel := context.WithTimeout(req.Context(), h.exportTimeout * time.Second)
+    defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ExportPerson")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	personID := mux.Vars(req)["id"]
+
+	readCtx, readCancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer readCancel()
+
+	export, err := h.workerService.ExportPerson(readCtx, personID)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	// the write timeout of the server would cut the archive first
+	if err := http.NewResponseController(rw).SetWriteDeadline(time.Now().Add(h.exportTimeout * time.Second)); err != nil {
+		logging.Ctx(ctx, childLogger).Warn().Err(err).Msg("export archive kept the write timeout of the server")
+	}
+
+	rw.Header().Set("Content-Type", "application/zip")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "person-export-" + personID + ".zip"))
+	rw.WriteHeader(http.StatusOK)
+
+	// the status is sent already, a failure only cuts the archive
+	if err = h.workerService.WriteExportArchive(ctx, export, rw); err != nil {
+		logging.Ctx(ctx, childLogger).Error().Err(err).Msg("error write export archive")
+	}
+	return nil
+}
+
+// About erase (DELETE) a person, it may be called again until it succeeds
+func (h *HttpRouters) ErasePerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ErasePerson").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+    defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ErasePerson")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	res, err := h.workerService.ErasePerson(ctx, mux.Vars(req)["id"])
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
 // About update person
-func (h *HttpRouters) UpdatePerson(rw http.ResponseWriter, req *http.Request) error {
-	childLogger.Info().Str("func","UpdatePerson").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+func (h *HttpRouters) UpdatePerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","UpdatePerson").Send()
 
 	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
     defer cancel()
 
-	span := tracerProvider.Span(ctx, "adapter.api.UpdatePerson")
-	defer span.End()
+	ctx, span := tracing.Start(ctx, "adapter.api.UpdatePerson")
+	defer func() { tracing.End(span, err) }()
 
 	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
 
 	onBoarding := model.Onboarding{}
-	err := json.NewDecoder(req.Body).Decode(&onBoarding)
+	err = json.NewDecoder(req.Body).Decode(&onBoarding)
     if err != nil {
 		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
     }
@@ -183,14 +437,14 @@ func (h *HttpRouters) UpdatePerson(rw http.ResponseWriter, req *http.Request) er
 }
 
 // About list person
-func (h *HttpRouters) ListPerson(rw http.ResponseWriter, req *http.Request) error {
-	childLogger.Info().Str("func","ListPerson").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+func (h *HttpRouters) ListPerson(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListPerson").Send()
 	
 	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
     defer cancel()
 
-	span := tracerProvider.Span(ctx, "adapter.api.ListPerson")
-	defer span.End()
+	ctx, span := tracing.Start(ctx, "adapter.api.ListPerson")
+	defer func() { tracing.End(span, err) }()
 
 	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
 
@@ -211,20 +465,20 @@ func (h *HttpRouters) ListPerson(rw http.ResponseWriter, req *http.Request) erro
 }
 
 // About list person
-func (h *HttpRouters) UploadFile(rw http.ResponseWriter, req *http.Request) error {
-	childLogger.Info().Str("func","UploadFile").Interface("trace-resquest-id", req.Context().Value("trace-request-id")).Send()
+func (h *HttpRouters) UploadFile(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","UploadFile").Send()
 
 	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
     defer cancel()
 
 	// Trace
-	span := tracerProvider.Span(ctx, "adapter.api.UploadFile")
-	defer span.End()
+	ctx, span := tracing.Start(ctx, "adapter.api.UploadFile")
+	defer func() { tracing.End(span, err) }()
 
 	trace_id := fmt.Sprintf("%v",ctx.Value("trace-request-id"))
 
 	// Check the size
-	err := req.ParseMultipartForm(20 << 20) //20Mb
+	err = req.ParseMultipartForm(20 << 20) //20Mb
 	if err != nil {
 		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
 	}
@@ -238,6 +492,7 @@ func (h *HttpRouters) UploadFile(rw http.ResponseWriter, req *http.Request) erro
 	defer file.Close()
 
 	onboardingFile := model.OnboardingFile{}
+	onboardingFile.PersonID = req.FormValue("person_id")
 	onboardingFile.FileName = handler.Filename
 	onboardingFile.File, err = ioutil.ReadAll(file)
 	if err != nil {
@@ -245,7 +500,7 @@ func (h *HttpRouters) UploadFile(rw http.ResponseWriter, req *http.Request) erro
 		return &core_apiError
 	}
 
-	childLogger.Info().Str("func","UploadFile").
+	logging.Ctx(ctx, childLogger).Info().Str("func","UploadFile").
 						Interface("file_data", fmt.Sprintf("%v %v %v",handler.Header ,handler.Filename, handler.Size)).
 						Send()
 
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"fmt"
+	"time"
+	"context"
+	"strconv"
+	"net/http"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// About add a webhook subscription (POST) or update it (PUT /{id}), a update
+// changes the fields sent only. The tenant is the one of the caller (X-Tenant-Id),
+// not the one of the body. The secret is returned by the add only.
+func (h *HttpRouters) SaveWebhook(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","SaveWebhook").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.SaveWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	subscription := &model.WebhookSubscription{}
+	if req.Method == http.MethodPut {
+		id, err := intVar(req, "id")
+		if err != nil {
+			return h.ErrorHandler(trace_id, err)
+		}
+		subscription, err = h.workerService.GetWebhookSubscription(ctx, int64(id))
+		if err != nil {
+			return h.ErrorHandler(trace_id, err)
+		}
+	}
+
+	err = json.NewDecoder(req.Body).Decode(subscription)
+	if err != nil {
+		return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+	}
+	defer req.Body.Close()
+
+	var res *model.WebhookSubscription
+	if req.Method == http.MethodPut {
+		res, err = h.workerService.UpdateWebhookSubscription(ctx, subscription)
+	} else {
+		res, err = h.workerService.AddWebhookSubscription(ctx, subscription)
+	}
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About list the webhook subscriptions of the tenant of the caller (X-Tenant-Id)
+func (h *HttpRouters) ListWebhook(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListWebhook").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ListWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	res, err := h.workerService.ListWebhookSubscription(ctx)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About get a webhook subscription
+func (h *HttpRouters) GetWebhook(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","GetWebhook").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.GetWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	res, err := h.workerService.GetWebhookSubscription(ctx, int64(id))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About delete a webhook subscription with its deliveries
+func (h *HttpRouters) DeleteWebhook(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","DeleteWebhook").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.DeleteWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	err = h.workerService.DeleteWebhookSubscription(ctx, int64(id))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, model.MessageRouter{Message: "true"})
+}
+
+// About list the deliveries of a webhook subscription: the status, limit and offset query params
+func (h *HttpRouters) ListWebhookDelivery(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","ListWebhookDelivery").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.ListWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	query := req.URL.Query()
+	deliveryList := model.WebhookDeliveryList{	SubscriptionID: int64(id),
+												Status: query.Get("status"),
+	}
+	if value := query.Get("limit"); value != "" {
+		if deliveryList.Limit, err = strconv.Atoi(value); err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if deliveryList.Offset, err = strconv.Atoi(value); err != nil {
+			return h.ErrorHandler(trace_id, erro.ErrBadRequest)
+		}
+	}
+
+	res, err := h.workerService.ListWebhookDelivery(ctx, &deliveryList)
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About get a delivery of a webhook subscription with the log of its attempts
+func (h *HttpRouters) GetWebhookDelivery(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","GetWebhookDelivery").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.GetWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+	deliveryID, err := intVar(req, "delivery_id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	res, err := h.workerService.GetWebhookDelivery(ctx, int64(id), int64(deliveryID))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
+
+// About send (POST) a delivery again, a dead one is taken out of the dead letter
+func (h *HttpRouters) RetryWebhookDelivery(rw http.ResponseWriter, req *http.Request) (err error) {
+	logging.Ctx(req.Context(), childLogger).Info().Str("func","RetryWebhookDelivery").Send()
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.api.RetryWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	trace_id := fmt.Sprintf("%v", ctx.Value("trace-request-id"))
+
+	id, err := intVar(req, "id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+	deliveryID, err := intVar(req, "delivery_id")
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	res, err := h.workerService.RetryWebhookDelivery(ctx, int64(id), int64(deliveryID))
+	if err != nil {
+		return h.ErrorHandler(trace_id, err)
+	}
+
+	return core_json.WriteJSON(rw, http.StatusOK, res)
+}
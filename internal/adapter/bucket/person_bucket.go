@@ -0,0 +1,244 @@
+package bucket
+
+import (
+	"io"
+	"fmt"
+	"bytes"
+	"errors"
+	"context"
+	"strings"
+	"net/url"
+
+	"github.com/rs/zerolog/log"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.adapter.bucket").Logger()
+
+// the parts of a upload, the smallest size s3 takes but for the last one
+const uploadPartSize = 5 * 1024 * 1024
+
+// PersonBucket gives the files uploaded for a person, they are kept
+// under <file path>person/<escaped person_id>/
+type PersonBucket struct {
+	client		*s3.Client
+	bucketName	string
+	filePath	string
+}
+
+// About create the person bucket over the upload bucket and path
+func NewPersonBucket(client *s3.Client, bucketName string, filePath string) *PersonBucket {
+	childLogger.Info().Str("func","NewPersonBucket").Str("bucket", bucketName).Send()
+
+	return &PersonBucket{
+		client: client,
+		bucketName: strings.TrimSuffix(bucketName, "/"),
+		filePath: filePath,
+	}
+}
+
+// About the path of the files of a person, the person_id is escaped so a
+// person_id with a "/" never falls under the path of another person
+func (b *PersonBucket) Prefix(personID string) string {
+	return b.filePath + "person/" + url.PathEscape(personID) + "/"
+}
+
+// About list the keys of the files of a person, a nil bucket has none
+func (b *PersonBucket) List(ctx context.Context, personID string) (_ []string, err error) {
+	if b == nil {
+		return nil, nil
+	}
+	logging.Ctx(ctx, childLogger).Info().Str("func","List").Send()
+
+	ctx, span := tracing.Start(ctx, "bucket.List", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	keys := []string{}
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucketName),
+		Prefix: aws.String(b.Prefix(personID)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return keys, nil
+}
+
+// About open a file, the caller closes it
+func (b *PersonBucket) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucketName),
+		Key: aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// About delete all the files of a person, every version of them when the bucket is
+// versioned (a plain delete would only add a delete marker). Returns how many versions
+// were deleted, running it again after a failure deletes the ones left.
+func (b *PersonBucket) DeleteAll(ctx context.Context, personID string) (_ int, err error) {
+	if b == nil {
+		return 0, nil
+	}
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteAll").Send()
+
+	ctx, span := tracing.Start(ctx, "bucket.DeleteAll", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	deleted := 0
+	paginator := s3.NewListObjectVersionsPaginator(b.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(b.bucketName),
+		Prefix: aws.String(b.Prefix(personID)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return deleted, err
+		}
+
+		// a page has up to 1000 entries, the limit of a DeleteObjects
+		objects := []types.ObjectIdentifier{}
+		for _, version := range page.Versions {
+			objects = append(objects, types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) == 0 {
+			continue
+		}
+
+		out, err := b.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(b.bucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if len(out.Errors) > 0 {
+			first := out.Errors[0]
+			return deleted + len(objects) - len(out.Errors), fmt.Errorf("delete %d objects failed, first %s: %s", len(out.Errors), aws.ToString(first.Code), aws.ToString(first.Message))
+		}
+		deleted += len(objects)
+	}
+	return deleted, nil
+}
+
+// About move the files of a person to another one, as when they are merged. The files
+// are copied then all the versions of the source are deleted, running it again after
+// a failure moves the ones left. Returns how many files were copied.
+func (b *PersonBucket) MoveAll(ctx context.Context, fromPersonID string, toPersonID string) (_ int, err error) {
+	if b == nil {
+		return 0, nil
+	}
+	logging.Ctx(ctx, childLogger).Info().Str("func","MoveAll").Send()
+
+	ctx, span := tracing.Start(ctx, "bucket.MoveAll", tracing.PersonAttributes("", toPersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	keys, err := b.List(ctx, fromPersonID)
+	if err != nil {
+		return 0, err
+	}
+
+	from, to := b.Prefix(fromPersonID), b.Prefix(toPersonID)
+	moved := 0
+	for _, key := range keys {
+		_, err := b.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket: aws.String(b.bucketName),
+			CopySource: aws.String(b.bucketName + "/" + url.PathEscape(key)),
+			Key: aws.String(to + strings.TrimPrefix(key, from)),
+		})
+		if err != nil {
+			return moved, err
+		}
+		moved++
+	}
+
+	if _, err := b.DeleteAll(ctx, fromPersonID); err != nil {
+		return moved, err
+	}
+	return moved, nil
+}
+
+// About upload a file of any size read from a stream, in parts so only one part is
+// held in memory. A upload failed (the stream too) is aborted, nothing is left.
+func (b *PersonBucket) Upload(ctx context.Context, key string, body io.Reader) (err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","Upload").Send()
+
+	ctx, span := tracing.Start(ctx, "bucket.Upload")
+	defer func() { tracing.End(span, err) }()
+
+	upload, err := b.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(b.bucketName),
+		Key: aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// detached, the upload is aborted when the context is done too
+			_, errAbort := b.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+				Bucket: aws.String(b.bucketName),
+				Key: aws.String(key),
+				UploadId: upload.UploadId,
+			})
+			if errAbort != nil {
+				logging.Ctx(ctx, childLogger).Error().Err(errAbort).Msg("error abort upload")
+			}
+		}
+	}()
+
+	parts := []types.CompletedPart{}
+	buffer := make([]byte, uploadPartSize)
+	for number := int32(1); ; number++ {
+		size, errRead := io.ReadFull(body, buffer)
+		last := errors.Is(errRead, io.EOF) || errors.Is(errRead, io.ErrUnexpectedEOF)
+		if errRead != nil && !last {
+			return errRead
+		}
+		// the stream ended with the part before, a empty stream is a single empty part
+		if size == 0 && number > 1 {
+			break
+		}
+
+		part, err := b.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket: aws.String(b.bucketName),
+			Key: aws.String(key),
+			UploadId: upload.UploadId,
+			PartNumber: aws.Int32(number),
+			Body: bytes.NewReader(buffer[:size]),
+		})
+		if err != nil {
+			return err
+		}
+		parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(number)})
+
+		if last {
+			break
+		}
+	}
+
+	_, err = b.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket: aws.String(b.bucketName),
+		Key: aws.String(key),
+		UploadId: upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
@@ -0,0 +1,347 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/core/dedup"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The status of a possible duplicate
+const (
+	DuplicatePending	= "pending"
+	DuplicateMerged		= "merged"
+	DuplicateDismissed	= "dismissed"
+)
+
+// the advisory lock class serializing the onboardings of a tenant, the tenant is the key
+const dedupLock = 8_210_002
+
+// About serialize the onboardings of a tenant until the end of the transaction, so
+// the duplicates are searched with the persons onboarded concurrently committed
+func (w WorkerRepository) LockTenantOnboarding(ctx context.Context, tx pgx.Tx, tenantID string) (err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","LockTenantOnboarding").Send()
+
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, dedupLock, tenantID)
+	return err
+}
+
+// About find the persons of a tenant sharing a identifier with a person: the tax id,
+// the email or a identity document. The persons with a similar name are found by the search.
+// It runs in the transaction of the onboarding, the one holding the lock of the tenant.
+func (w WorkerRepository) FindDuplicateCandidates(ctx context.Context, tx pgx.Tx, person *model.Person, limit int) (_ []string, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","FindDuplicateCandidates").Send()
+
+	ctx, span := tracing.Start(ctx, "database.FindDuplicateCandidates", tracing.PersonAttributes(person.TenantID, person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	// a empty value has no index, it would match the blind index of ""
+	var taxIDIndex, emailIndex string
+	if person.TaxID != "" {
+		taxIDIndex = w.Keyring.BlindIndex(fieldTaxID, person.TaxID)
+	}
+	if person.Email != "" {
+		emailIndex = w.Keyring.BlindIndex(fieldEmail, person.Email)
+	}
+	types, countries, numbers, numberIndexes := []string{}, []string{}, []string{}, []string{}
+	for _, document := range person.Documents {
+		types = append(types, document.Type)
+		countries = append(countries, document.IssuingCountry)
+		numbers = append(numbers, dedup.DocumentNumber(document.Number))
+		numberIndexes = append(numberIndexes, w.Keyring.BlindIndex(fieldDocumentNumber, dedup.DocumentNumber(document.Number)))
+	}
+
+	query := `SELECT p.person_id
+				FROM public.person p
+				WHERE COALESCE(p.tenant_id, '') = $1
+					AND p.erased_at IS NULL
+					AND p.person_id <> $2
+					AND (p.tax_id_bidx = NULLIF($3,'') OR p.tax_id = NULLIF($4,'')
+						OR p.email_bidx = NULLIF($5,'') OR p.email = NULLIF($6,'')
+						OR EXISTS (SELECT 1
+									FROM public.person_document d,
+										unnest($7::text[], $8::text[], $9::text[], $11::text[]) AS q(type, issuing_country, number, number_bidx)
+									WHERE d.fk_person_id = p.id
+										AND d.type = q.type
+										AND d.issuing_country = q.issuing_country
+										AND (d.number_bidx = NULLIF(q.number_bidx, '')
+											OR upper(regexp_replace(d.number, '[^[:alnum:]]', '', 'g')) = q.number)))
+				ORDER BY p.person_id asc
+				LIMIT $10`
+
+	rows, err := tx.Query(ctx, query,	person.TenantID,
+										person.PersonID,
+										taxIDIndex,
+										person.TaxID,
+										emailIndex,
+										person.Email,
+										types,
+										countries,
+										numbers,
+										limit,
+										numberIndexes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	personIDs := []string{}
+	for rows.Next() {
+		var personID string
+		if err := rows.Scan(&personID); err != nil {
+			return nil, err
+		}
+		personIDs = append(personIDs, personID)
+	}
+	return personIDs, rows.Err()
+}
+
+// About flag a possible duplicate for review in the transaction of the onboarding
+func (w WorkerRepository) AddDuplicate(ctx context.Context, tx pgx.Tx, duplicate *model.PersonDuplicate) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddDuplicate").Float64("score", duplicate.Score).Send()
+
+	createdAt := time.Now()
+	duplicate.CreatedAt = &createdAt
+	duplicate.Status = DuplicatePending
+
+	query := `INSERT INTO public.person_duplicate (	person_id,
+													candidate_id,
+													tenant_id,
+													score,
+													reasons,
+													status,
+													created_at)
+				VALUES($1, $2, NULLIF($3,''), $4, $5, $6, $7)
+				ON CONFLICT (person_id, candidate_id) DO UPDATE
+					SET score = EXCLUDED.score,
+						reasons = EXCLUDED.reasons
+				RETURNING id`
+
+	return tx.QueryRow(ctx, query,	duplicate.PersonID,
+									duplicate.Candidate.PersonID,
+									duplicate.TenantID,
+									duplicate.Score,
+									duplicate.Reasons,
+									duplicate.Status,
+									createdAt).Scan(&duplicate.ID)
+}
+
+// About list the possible duplicates of a tenant by status, oldest first
+func (w WorkerRepository) ListDuplicate(ctx context.Context, tenantID string, status string) (_ []model.PersonDuplicate, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListDuplicate").Str("status", status).Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListDuplicate", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT id,
+					person_id,
+					candidate_id,
+					COALESCE(tenant_id, ''),
+					score,
+					reasons,
+					status,
+					created_at,
+					resolved_at
+				FROM public.person_duplicate
+				WHERE COALESCE(tenant_id, '') = $1
+					AND status = $2
+				ORDER BY created_at asc, id asc`
+
+	rows, err := conn.Query(ctx, query, tenantID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_duplicate_list := []model.PersonDuplicate{}
+	for rows.Next() {
+		res_candidate := model.Person{}
+		res_duplicate := model.PersonDuplicate{Candidate: &res_candidate}
+		err := rows.Scan(	&res_duplicate.ID,
+							&res_duplicate.PersonID,
+							&res_candidate.PersonID,
+							&res_duplicate.TenantID,
+							&res_duplicate.Score,
+							&res_duplicate.Reasons,
+							&res_duplicate.Status,
+							&res_duplicate.CreatedAt,
+							&res_duplicate.ResolvedAt,
+						)
+		if err != nil {
+			return nil, err
+		}
+		res_duplicate_list = append(res_duplicate_list, res_duplicate)
+	}
+	return res_duplicate_list, rows.Err()
+}
+
+// About dismiss a pending possible duplicate, they are not the same person
+func (w WorkerRepository) DismissDuplicate(ctx context.Context, tx pgx.Tx, id int64) (_ *model.PersonDuplicate, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DismissDuplicate").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.DismissDuplicate")
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE public.person_duplicate
+				SET status = $2,
+					resolved_at = $3
+				WHERE id = $1
+					AND status = $4
+				RETURNING person_id, candidate_id, COALESCE(tenant_id, ''), score, reasons, status, created_at, resolved_at`
+
+	res_candidate := model.Person{}
+	res_duplicate := model.PersonDuplicate{ID: id, Candidate: &res_candidate}
+	err = tx.QueryRow(ctx, query, id, DuplicateDismissed, time.Now(), DuplicatePending).Scan(	&res_duplicate.PersonID,
+																								&res_candidate.PersonID,
+																								&res_duplicate.TenantID,
+																								&res_duplicate.Score,
+																								&res_duplicate.Reasons,
+																								&res_duplicate.Status,
+																								&res_duplicate.CreatedAt,
+																								&res_duplicate.ResolvedAt)
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res_duplicate, nil
+}
+
+// About merge a person into the survivor, both locked by the transaction: the merged
+// person is anonymized and hidden, its addresses and documents move to the survivor
+// (a document the survivor has already is dropped), the merge is recorded and the
+// pending possible duplicates of the merged person are resolved.
+// The survivor data is updated by the caller.
+func (w WorkerRepository) MergePerson(ctx context.Context, tx pgx.Tx, mergedID int, survivorID int, merge *model.PersonMerge) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","MergePerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.MergePerson", tracing.PersonAttributes(merge.TenantID, merge.SurvivorPersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	merge.MergedAt = time.Now()
+
+	if _, err := anonymizePerson(ctx, tx, mergedID, merge.MergedAt); err != nil {
+		return err
+	}
+
+	// a single primary address, the one of the survivor when it has one
+	query := `UPDATE public.person_address
+				SET fk_person_id = $2,
+					is_primary = is_primary AND NOT EXISTS (SELECT 1 FROM public.person_address WHERE fk_person_id = $2 AND is_primary),
+					updated_at = $3
+				WHERE fk_person_id = $1`
+	if _, err := tx.Exec(ctx, query, mergedID, survivorID, merge.MergedAt); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM public.person_document d
+				WHERE d.fk_person_id = $1
+					AND EXISTS (SELECT 1
+								FROM public.person_document s
+								WHERE s.fk_person_id = $2
+									AND s.type = d.type
+									AND COALESCE(s.number_bidx, s.number) = COALESCE(d.number_bidx, d.number)
+									AND s.issuing_country = d.issuing_country)`
+	if _, err := tx.Exec(ctx, query, mergedID, survivorID); err != nil {
+		return err
+	}
+	query = `UPDATE public.person_document SET fk_person_id = $2, updated_at = $3 WHERE fk_person_id = $1`
+	if _, err := tx.Exec(ctx, query, mergedID, survivorID, merge.MergedAt); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO public.person_merge (	merged_person_id,
+												survivor_person_id,
+												tenant_id,
+												actor,
+												trace_id,
+												merged_at)
+				VALUES($1, $2, NULLIF($3,''), NULLIF($4,''), NULLIF($5,''), $6)`
+	_, err = tx.Exec(ctx, query,	merge.MergedPersonID,
+									merge.SurvivorPersonID,
+									merge.TenantID,
+									merge.Actor,
+									merge.TraceID,
+									merge.MergedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE public.person_duplicate
+				SET status = $2,
+					resolved_at = $3
+				WHERE (person_id = $1 OR candidate_id = $1)
+					AND status = $4`
+	_, err = tx.Exec(ctx, query, merge.MergedPersonID, DuplicateMerged, merge.MergedAt, DuplicatePending)
+	return err
+}
+
+// About get the merge of a merged person, always from the primary
+func (w WorkerRepository) GetMerge(ctx context.Context, mergedPersonID string) (_ *model.PersonMerge, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetMerge").Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetMerge", tracing.PersonAttributes("", mergedPersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT merged_person_id,
+					survivor_person_id,
+					COALESCE(tenant_id, ''),
+					COALESCE(actor, ''),
+					COALESCE(trace_id, ''),
+					merged_at,
+					objects_moved,
+					objects_moved_at
+				FROM public.person_merge
+				WHERE merged_person_id = $1`
+
+	merge := model.PersonMerge{}
+	err = w.DatabasePGServer.GetConnection().QueryRow(ctx, query, mergedPersonID).Scan(	&merge.MergedPersonID,
+																						&merge.SurvivorPersonID,
+																						&merge.TenantID,
+																						&merge.Actor,
+																						&merge.TraceID,
+																						&merge.MergedAt,
+																						&merge.ObjectsMoved,
+																						&merge.ObjectsMovedAt)
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// About add the bucket objects moved to the survivor by a merge
+func (w WorkerRepository) AddMergeObjects(ctx context.Context, merge *model.PersonMerge, moved int) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddMergeObjects").Int("moved", moved).Send()
+
+	movedAt := time.Now()
+
+	query := `UPDATE public.person_merge
+				SET objects_moved = objects_moved + $2,
+					objects_moved_at = $3
+				WHERE merged_person_id = $1
+				RETURNING objects_moved`
+
+	err = w.DatabasePGServer.GetConnection().QueryRow(ctx, query, merge.MergedPersonID, moved, movedAt).Scan(&merge.ObjectsMoved)
+	if err != nil {
+		return err
+	}
+	merge.ObjectsMovedAt = &movedAt
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"io"
+	"net"
+	"errors"
+	"context"
+	"strings"
+	"syscall"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/go-onboarding/internal/core/erro"
+)
+
+// unique indexes of the tax id inside a tenant, in plaintext (003_person_tax_id.sql)
+// and encrypted (004_person_encryption.sql)
+const (
+	constraintTenantTaxID		= "person_tenant_tax_id_uq"
+	constraintTenantTaxIDIndex	= "person_tenant_tax_id_bidx_uq"
+)
+
+// About translate the unique violations the client can fix into their domain errors
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" &&
+		(pgErr.ConstraintName == constraintTenantTaxID || pgErr.ConstraintName == constraintTenantTaxIDIndex) {
+		return erro.ErrDuplicateTaxID
+	}
+	return err
+}
+
+// A commit that failed without an answer of the server may have been applied,
+// its outcome is unknown so the transaction is never retried
+type commitError struct {
+	err error
+}
+
+func (e *commitError) Error() string {
+	return "commit outcome unknown: " + e.err.Error()
+}
+
+func (e *commitError) Unwrap() error {
+	return e.err
+}
+
+// About commit a transaction
+func (d *DatabasePGServer) CommitTx(ctx context.Context, tx pgx.Tx) error {
+	err := tx.Commit(ctx)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) || errors.Is(err, pgx.ErrTxCommitRollback) {
+		return err
+	}
+	return &commitError{err: err}
+}
+
+// About tell the errors worth running the whole transaction (or connect) again:
+// serialization failures, deadlocks, the server going away (rds proxy failover)
+// and the broken connections
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var commitErr *commitError
+	if errors.As(err, &commitErr) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01",  // deadlock_detected
+			"53300",  // too_many_connections
+			"57P01",  // admin_shutdown
+			"57P02",  // crash_shutdown
+			"57P03":  // cannot_connect_now
+			return true
+		}
+		// connection_exception class
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"errors"
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/jobs"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// JobRepository is the asynchronous job queue, the jobs are shared by all the replicas
+type JobRepository struct {
+	DatabasePGServer	*DatabasePGServer
+}
+
+func NewJobRepository(databasePGServer *DatabasePGServer) *JobRepository{
+	childLogger.Info().Str("func","NewJobRepository").Send()
+
+	return &JobRepository{
+		DatabasePGServer: databasePGServer,
+	}
+}
+
+// About check the table of the jobs exists, the queue can not run before its
+// migration (009_job.sql) is applied
+func (j *JobRepository) Check(ctx context.Context) error {
+	childLogger.Info().Str("func","Check").Send()
+
+	var exists bool
+	if err := j.DatabasePGServer.GetConnection().QueryRow(ctx, `SELECT to_regclass('public.job') IS NOT NULL`).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("table public.job not found, apply the migrations (admin migrate)")
+	}
+	return nil
+}
+
+// the columns of a job
+const jobColumns = `id,
+					kind,
+					COALESCE(tenant_id, ''),
+					payload,
+					status,
+					attempts,
+					max_attempts,
+					progress_done,
+					progress_total,
+					result,
+					COALESCE(error, ''),
+					cancel_requested,
+					run_at,
+					COALESCE(actor, ''),
+					COALESCE(trace_id, ''),
+					created_at,
+					started_at,
+					finished_at`
+
+func scanJob(row pgx.Row) (*model.AsyncJob, error) {
+	job := model.AsyncJob{}
+	var payload, result []byte
+	err := row.Scan(&job.ID,
+					&job.Kind,
+					&job.TenantID,
+					&payload,
+					&job.Status,
+					&job.Attempts,
+					&job.MaxAttempts,
+					&job.Progress.Done,
+					&job.Progress.Total,
+					&result,
+					&job.Error,
+					&job.CancelRequested,
+					&job.RunAt,
+					&job.Actor,
+					&job.TraceID,
+					&job.CreatedAt,
+					&job.StartedAt,
+					&job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	job.Result = result
+	return &job, nil
+}
+
+// About queue a job, it runs from its run_at (now when not given)
+func (j *JobRepository) AddJob(ctx context.Context, job *model.AsyncJob) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddJob").Str("kind", job.Kind).Send()
+
+	ctx, span := tracing.Start(ctx, "database.AddJob", tracing.PersonAttributes(job.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	job.Status = jobs.StatusQueued
+	job.CreatedAt = time.Now()
+	if job.RunAt == nil {
+		job.RunAt = &job.CreatedAt
+	}
+	if len(job.Payload) == 0 {
+		job.Payload = []byte(`{}`)
+	}
+
+	query := `INSERT INTO public.job (	kind,
+										tenant_id,
+										payload,
+										status,
+										max_attempts,
+										run_at,
+										actor,
+										trace_id,
+										created_at)
+				VALUES($1, NULLIF($2,''), $3, $4, $5, $6, NULLIF($7,''), NULLIF($8,''), $9) RETURNING id`
+
+	return j.DatabasePGServer.GetConnection().QueryRow(ctx, query,	job.Kind,
+																	job.TenantID,
+																	[]byte(job.Payload),
+																	job.Status,
+																	job.MaxAttempts,
+																	job.RunAt,
+																	job.Actor,
+																	job.TraceID,
+																	job.CreatedAt).Scan(&job.ID)
+}
+
+// About get a job
+func (j *JobRepository) GetJob(ctx context.Context, id int64) (_ *model.AsyncJob, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetJob").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetJob")
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + jobColumns + `
+				FROM public.job
+				WHERE id = $1`
+
+	res, err := scanJob(j.DatabasePGServer.GetConnection().QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	return res, err
+}
+
+// About list the jobs of a tenant, newest first, of a kind and status when given.
+// One more than the limit is read to tell a next page.
+func (j *JobRepository) ListJob(ctx context.Context, tenantID string, kind string, status string, limit int, offset int) (_ []model.AsyncJob, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListJob").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListJob", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + jobColumns + `
+				FROM public.job
+				WHERE COALESCE(tenant_id, '') = $1
+					AND ($2 = '' OR kind = $2)
+					AND ($3 = '' OR status = $3)
+				ORDER BY created_at desc, id desc
+				LIMIT $4 OFFSET $5`
+
+	rows, err := j.DatabasePGServer.GetConnection().Query(ctx, query, tenantID, kind, status, limit + 1, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_job_list := []model.AsyncJob{}
+	for rows.Next() {
+		res_job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		res_job_list = append(res_job_list, *res_job)
+	}
+	return res_job_list, rows.Err()
+}
+
+// About cancel a job: a queued one (or a running one of a replica gone) is canceled
+// at once, a running one is asked to and its worker cancels it with the next heartbeat.
+// A finished job is not changed.
+func (j *JobRepository) CancelJob(ctx context.Context, id int64) (_ *model.AsyncJob, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","CancelJob").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.CancelJob")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+
+	query := `UPDATE public.job
+				SET cancel_requested = true,
+					status = CASE WHEN status = $2 AND locked_until >= $4 THEN status ELSE $3 END,
+					error = CASE WHEN status = $2 AND locked_until >= $4 THEN error ELSE 'job canceled' END,
+					finished_at = CASE WHEN status = $2 AND locked_until >= $4 THEN finished_at ELSE $4 END,
+					locked_by = CASE WHEN status = $2 AND locked_until >= $4 THEN locked_by ELSE NULL END,
+					locked_until = CASE WHEN status = $2 AND locked_until >= $4 THEN locked_until ELSE NULL END
+				WHERE id = $1
+					AND status IN ($2, $5)
+				RETURNING ` + jobColumns
+
+	res, err := scanJob(j.DatabasePGServer.GetConnection().QueryRow(ctx, query, id, jobs.StatusRunning, jobs.StatusCanceled, now, jobs.StatusQueued))
+	if err == pgx.ErrNoRows {
+		// not found or already finished
+		if _, err := j.GetJob(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, erro.ErrJobFinished
+	}
+	return res, err
+}
+
+// About claim the queued job due, oldest first, or a running one whose lease is over
+// (its replica is gone). It is leased: the other replicas skip it (SKIP LOCKED) and
+// it is claimed again when the lease is over without a heartbeat.
+func (j *JobRepository) Claim(ctx context.Context, kinds []string, worker string, lease time.Duration) (_ *model.AsyncJob, err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","Claim").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ClaimJob")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+
+	query := `UPDATE public.job j
+				SET status = $2,
+					attempts = j.attempts + 1,
+					locked_by = $3,
+					locked_until = $4,
+					started_at = COALESCE(j.started_at, $1)
+				WHERE j.id = (SELECT q.id
+								FROM public.job q
+								WHERE q.kind = ANY($5)
+									AND NOT q.cancel_requested
+									AND ((q.status = $6 AND q.run_at <= $1)
+										OR (q.status = $2 AND q.locked_until < $1))
+								ORDER BY q.run_at asc, q.id asc
+								LIMIT 1
+								FOR UPDATE SKIP LOCKED)
+				RETURNING ` + jobColumns
+
+	res, err := scanJob(j.DatabasePGServer.GetConnection().QueryRow(ctx, query, now, jobs.StatusRunning, worker, now.Add(lease), kinds, jobs.StatusQueued))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+// About renew the lease of a job held by the worker and save its progress
+func (j *JobRepository) Heartbeat(ctx context.Context, id int64, worker string, progress model.JobProgress, lease time.Duration) (_ bool, err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","Heartbeat").Int64("id", id).Send()
+
+	query := `UPDATE public.job
+				SET locked_until = $4,
+					progress_done = $5,
+					progress_total = $6
+				WHERE id = $1
+					AND locked_by = $2
+					AND status = $3
+				RETURNING cancel_requested`
+
+	var canceled bool
+	err = j.DatabasePGServer.GetConnection().QueryRow(ctx, query, id, worker, jobs.StatusRunning, time.Now().Add(lease), progress.Done, progress.Total).Scan(&canceled)
+	if err == pgx.ErrNoRows {
+		return false, jobs.ErrLost
+	}
+	return canceled, err
+}
+
+// About save the outcome of a job held by the worker and release its lease,
+// a finished job gets its finished_at
+func (j *JobRepository) Finish(ctx context.Context, job *model.AsyncJob, worker string) (err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","Finish").Int64("id", job.ID).Str("status", job.Status).Send()
+
+	ctx, span := tracing.Start(ctx, "database.FinishJob")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	job.FinishedAt = nil
+	if job.Status != jobs.StatusQueued {
+		job.FinishedAt = &now
+	}
+
+	query := `UPDATE public.job
+				SET status = $4,
+					attempts = $5,
+					progress_done = $6,
+					progress_total = $7,
+					result = $8,
+					error = NULLIF($9,''),
+					run_at = COALESCE($10, run_at),
+					finished_at = $11,
+					locked_by = NULL,
+					locked_until = NULL
+				WHERE id = $1
+					AND locked_by = $2
+					AND status = $3`
+
+	tag, err := j.DatabasePGServer.GetConnection().Exec(ctx, query,	job.ID,
+																	worker,
+																	jobs.StatusRunning,
+																	job.Status,
+																	job.Attempts,
+																	job.Progress.Done,
+																	job.Progress.Total,
+																	[]byte(job.Result),
+																	job.Error,
+																	job.RunAt,
+																	job.FinishedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return jobs.ErrLost
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/infra/encryption"
+)
+
+// KeyRepository is the encryption key store, the keys are shared by all the replicas
+type KeyRepository struct {
+	DatabasePGServer	*DatabasePGServer
+}
+
+func NewKeyRepository(databasePGServer *DatabasePGServer) *KeyRepository{
+	childLogger.Info().Str("func","NewKeyRepository").Send()
+
+	return &KeyRepository{
+		DatabasePGServer: databasePGServer,
+	}
+}
+
+// About list the wrapped keys
+func (k *KeyRepository) ListKeys(ctx context.Context) ([]encryption.StoredKey, error) {
+	query := `SELECT purpose, version, master_key_id, wrapped_key, created_at
+				FROM public.encryption_key
+				ORDER BY purpose, version`
+
+	rows, err := k.DatabasePGServer.GetConnection().Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []encryption.StoredKey{}
+	for rows.Next() {
+		key := encryption.StoredKey{}
+		if err := rows.Scan(&key.Purpose, &key.Version, &key.MasterKeyID, &key.WrappedKey, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// About add a wrapped key, it fails when the version exists (primary key)
+func (k *KeyRepository) AddKey(ctx context.Context, key encryption.StoredKey) error {
+	query := `INSERT INTO public.encryption_key (purpose, version, master_key_id, wrapped_key, created_at)
+				VALUES($1, $2, $3, $4, $5)`
+
+	_, err := k.DatabasePGServer.GetConnection().Exec(ctx, query, key.Purpose, key.Version, key.MasterKeyID, key.WrappedKey, key.CreatedAt)
+	return err
+}
+
+// About replace a wrapped key after it is rewrapped
+func (k *KeyRepository) UpdateKey(ctx context.Context, key encryption.StoredKey) error {
+	query := `UPDATE public.encryption_key
+				SET master_key_id = $3, wrapped_key = $4, updated_at = $5
+				WHERE purpose = $1 AND version = $2`
+
+	_, err := k.DatabasePGServer.GetConnection().Exec(ctx, query, key.Purpose, key.Version, key.MasterKeyID, key.WrappedKey, time.Now())
+	return err
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+	"context"
+	"strings"
+
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// the advisory lock serializing the migrations of concurrent runs
+const migrationLock = 8_210_001
+
+// Migration is a sql script of the schema, applied once
+type Migration struct {
+	Version		string		`json:"version"`
+	AppliedAt	*time.Time	`json:"applied_at,omitempty"`
+}
+
+// MigrationRepository applies the sql scripts of the schema, the ones applied are
+// recorded in public.schema_migration
+type MigrationRepository struct {
+	DatabasePGServer	*DatabasePGServer
+	scripts				fs.FS
+	dir					string
+}
+
+func NewMigrationRepository(databasePGServer *DatabasePGServer, scripts fs.FS, dir string) *MigrationRepository{
+	childLogger.Info().Str("func","NewMigrationRepository").Send()
+
+	return &MigrationRepository{
+		DatabasePGServer: databasePGServer,
+		scripts: scripts,
+		dir: dir,
+	}
+}
+
+// About create the table of the migrations applied
+func (m *MigrationRepository) init(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS public.schema_migration (
+				version		varchar(200) PRIMARY KEY,
+				applied_at	timestamptz	NOT NULL
+			)`
+
+	_, err := m.DatabasePGServer.GetConnection().Exec(ctx, query)
+	return err
+}
+
+// About list the scripts, by name, with when they were applied
+func (m *MigrationRepository) ListMigration(ctx context.Context) (_ []Migration, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListMigration").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListMigration")
+	defer func() { tracing.End(span, err) }()
+
+	if err = m.init(ctx); err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(m.scripts, m.dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Strings(versions)
+
+	rows, err := m.DatabasePGServer.GetConnection().Query(ctx, `SELECT version, applied_at FROM public.schema_migration`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]time.Time{}
+	for rows.Next() {
+		var version string
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	for _, version := range versions {
+		migration := Migration{Version: version}
+		if appliedAt, ok := applied[version]; ok {
+			migration.AppliedAt = &appliedAt
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// About apply the scripts not applied yet, in order, each in its own transaction.
+// The concurrent runs wait each other (advisory lock), the scripts applied are returned.
+func (m *MigrationRepository) Migrate(ctx context.Context) (_ []Migration, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","Migrate").Send()
+
+	ctx, span := tracing.Start(ctx, "database.Migrate")
+	defer func() { tracing.End(span, err) }()
+
+	migrations, err := m.ListMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res_migration_list := []Migration{}
+	for _, migration := range migrations {
+		if migration.AppliedAt != nil {
+			continue
+		}
+		script, err := fs.ReadFile(m.scripts, path.Join(m.dir, migration.Version))
+		if err != nil {
+			return res_migration_list, err
+		}
+
+		applied, err := m.apply(ctx, migration.Version, string(script))
+		if err != nil {
+			return res_migration_list, err
+		}
+		if applied != nil {
+			res_migration_list = append(res_migration_list, *applied)
+		}
+	}
+	return res_migration_list, nil
+}
+
+// About apply a script and record it, unless another run did it meanwhile
+func (m *MigrationRepository) apply(ctx context.Context, version string, script string) (_ *Migration, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","apply").Str("version", version).Send()
+
+	tx, conn, err := m.DatabasePGServer.StartTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer m.DatabasePGServer.ReleaseTx(conn)
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.schema_migration WHERE version = $1)`, version).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, tx.Commit(ctx)
+	}
+
+	// a script has many statements, run by the simple protocol (no arguments)
+	if _, err = tx.Exec(ctx, script); err != nil {
+		return nil, err
+	}
+	appliedAt := time.Now()
+	if _, err = tx.Exec(ctx, `INSERT INTO public.schema_migration (version, applied_at) VALUES($1, $2)`, version, appliedAt); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &Migration{Version: version, AppliedAt: &appliedAt}, nil
+}
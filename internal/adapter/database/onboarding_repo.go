@@ -1,203 +1,390 @@
-package database
-
-import (
-	"context"
-	"time"
-	"errors"
-	
-	"github.com/go-onboarding/internal/core/model"
-	"github.com/go-onboarding/internal/core/erro"
-
-	go_core_observ "github.com/eliezerraj/go-core/observability"
-	go_core_pg "github.com/eliezerraj/go-core/database/pg"
-
-	"github.com/jackc/pgx/v5"
-	"github.com/rs/zerolog/log"
-)
-
-var tracerProvider go_core_observ.TracerProvider
-var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.adapter.database").Logger()
-
-type WorkerRepository struct {
-	DatabasePGServer *go_core_pg.DatabasePGServer
-}
-
-func NewWorkerRepository(databasePGServer *go_core_pg.DatabasePGServer) *WorkerRepository{
-	childLogger.Info().Str("func","NewWorkerRepository").Send()
-
-	return &WorkerRepository{
-		DatabasePGServer: databasePGServer,
-	}
-}
-
-// Above get stats from database
-func (w WorkerRepository) Stat(ctx context.Context) (go_core_pg.PoolStats){
-	childLogger.Info().Str("func","Stat").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-	
-	stats := w.DatabasePGServer.Stat()
-
-	resPoolStats := go_core_pg.PoolStats{
-		AcquireCount:         stats.AcquireCount(),
-		AcquiredConns:        stats.AcquiredConns(),
-		CanceledAcquireCount: stats.CanceledAcquireCount(),
-		ConstructingConns:    stats.ConstructingConns(),
-		EmptyAcquireCount:    stats.EmptyAcquireCount(),
-		IdleConns:            stats.IdleConns(),
-		MaxConns:             stats.MaxConns(),
-		TotalConns:           stats.TotalConns(),
-	}
-
-	return resPoolStats
-}
-
-func (w WorkerRepository) AddPerson(ctx context.Context, tx pgx.Tx, onboarding *model.Onboarding) (*model.Onboarding, error){
-	childLogger.Info().Str("func","AddPerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-
-	span := tracerProvider.Span(ctx, "database.AddPerson")
-	defer span.End()
-
-	query := `INSERT INTO person (	person_id, 
-									name,
-									created_at,
-									tenant_id) 
-									VALUES($1, $2, $3, $4) RETURNING id`
-
-	onboarding.Person.CreatedAt = time.Now()
-
-	row := tx.QueryRow(ctx, query,  onboarding.Person.PersonID,  
-									onboarding.Person.Name,
-									onboarding.Person.CreatedAt,
-									onboarding.Person.TenantID)
-
-	var id int
-	
-	if err := row.Scan(&id); err != nil {
-		return nil, errors.New(err.Error())
-	}
-
-	onboarding.Person.ID = id
-
-	return onboarding, nil
-}
-
-func (w WorkerRepository) GetPerson(ctx context.Context, onboarding *model.Onboarding) (*model.Onboarding, error){
-	childLogger.Info().Str("func","GetPerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-
-	span := tracerProvider.Span(ctx, "database.GetPerson")
-	defer span.End()
-
-	conn, err := w.DatabasePGServer.Acquire(ctx)
-	if err != nil {
-		return nil, errors.New(err.Error())
-	}
-	defer w.DatabasePGServer.Release(conn)
-
-	res_person := model.Person{}
-	res_onboarding := model.Onboarding{Person: &res_person}
-
-	query := `SELECT id,
-					person_id,	 
-					name,
-					created_at,
-					updated_at 
-				FROM public.person 
-				WHERE person_id =$1`
-
-	rows, err := conn.Query(ctx, query, onboarding.Person.PersonID)
-	if err != nil {
-		return nil, errors.New(err.Error())
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		err := rows.Scan( 	&res_person.ID,
-							&res_person.PersonID, 
-							&res_person.Name, 
-							&res_person.CreatedAt,
-							&res_person.UpdatedAt,
-						)
-		if err != nil {
-			return nil, errors.New(err.Error())
-        }
-		return &res_onboarding, nil
-	}
-	
-	return nil, erro.ErrNotFound
-}
-
-func (w WorkerRepository) UpdatePerson(ctx context.Context, tx pgx.Tx, onboarding *model.Onboarding) (int64, error){
-	childLogger.Info().Str("func","UpdatePerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-
-	span := tracerProvider.Span(ctx, "database.UpdatePerson")
-	defer span.End()
-
-	t_updateAt := time.Now()
-	onboarding.Person.UpdatedAt = &t_updateAt
-
-	query := `Update public.person
-				set name = $2, 
-					updated_at = $3
-				where person_id = $1`
-
-	row, err := tx.Exec(ctx, query, onboarding.Person.PersonID,  
-									onboarding.Person.Name,
-									onboarding.Person.UpdatedAt)
-	if err != nil {
-		return 0, errors.New(err.Error())
-	}
-	if int(row.RowsAffected()) == 0 {
-		return 0, erro.ErrUpdateRows
-	}
-	childLogger.Debug().Int("rowsAffected : ",int(row.RowsAffected())).Msg("")
-	
-	return row.RowsAffected(), nil
-}
-
-func (w WorkerRepository) ListPerson(ctx context.Context, onboarding *model.Onboarding) (*[]model.Onboarding, error){
-	childLogger.Info().Str("func","ListPerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
-
-	span := tracerProvider.Span(ctx, "database.ListPerson")
-	defer span.End()
-
-	conn, err := w.DatabasePGServer.Acquire(ctx)
-	if err != nil {
-		childLogger.Error().Err(err).Msg("error acquire")
-		return nil, errors.New(err.Error())
-	}
-	defer w.DatabasePGServer.Release(conn)
-
-	res_onboarding_list := []model.Onboarding{}
-
-	query := `SELECT id,
-					person_id, 
-					name,
-					created_at,
-					updated_at 
-				FROM public.person
-				WHERE person_id >= $1 
-				ORDER BY person_id asc`
-
-	rows, err := conn.Query(ctx, query, onboarding.Person.PersonID)
-	if err != nil {
-		return nil, errors.New(err.Error())
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		res_person := model.Person{}
-		res_onboarding := model.Onboarding{Person: &res_person}
-
-		err := rows.Scan( 	&res_person.ID,
-							&res_person.PersonID, 
-							&res_person.Name, 
-							&res_person.CreatedAt,
-							&res_person.UpdatedAt,
-						)
-		if err != nil {
-			return nil, errors.New(err.Error())
-        }
-		res_onboarding_list = append(res_onboarding_list, res_onboarding)
-	}
-	
-	return &res_onboarding_list, nil
-}
\ No newline at end of file
+package database
+
+import (
+	"context"
+	"time"
+	
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/encryption"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.adapter.database").Logger()
+
+type WorkerRepository struct {
+	DatabasePGServer	*DatabasePGServer
+	ReadRouter			*ReadRouter
+	Keyring				*encryption.Keyring
+}
+
+func NewWorkerRepository(databasePGServer *DatabasePGServer) *WorkerRepository{
+	childLogger.Info().Str("func","NewWorkerRepository").Send()
+
+	return &WorkerRepository{
+		DatabasePGServer: databasePGServer,
+	}
+}
+
+// About route GetPerson and ListPerson to a reader pool, nil keeps them in the primary
+func (w *WorkerRepository) SetReadRouter(readRouter *ReadRouter) {
+	w.ReadRouter = readRouter
+}
+
+// About encrypt the person PII at rest with the keyring, nil keeps it in plaintext
+func (w *WorkerRepository) SetKeyring(keyring *encryption.Keyring) {
+	w.Keyring = keyring
+}
+
+// Above get stats from database
+func (w WorkerRepository) Stat(ctx context.Context) (go_core_pg.PoolStats){
+	logging.Ctx(ctx, childLogger).Info().Str("func","Stat").Send()
+	
+	stats := w.DatabasePGServer.Stat()
+
+	resPoolStats := go_core_pg.PoolStats{
+		AcquireCount:         stats.AcquireCount(),
+		AcquiredConns:        stats.AcquiredConns(),
+		CanceledAcquireCount: stats.CanceledAcquireCount(),
+		ConstructingConns:    stats.ConstructingConns(),
+		EmptyAcquireCount:    stats.EmptyAcquireCount(),
+		IdleConns:            stats.IdleConns(),
+		MaxConns:             stats.MaxConns(),
+		TotalConns:           stats.TotalConns(),
+	}
+
+	return resPoolStats
+}
+
+func (w WorkerRepository) AddPerson(ctx context.Context, tx pgx.Tx, onboarding *model.Onboarding) (_ *model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.AddPerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO person (	person_id, 
+									name,
+									person_type,
+									birth_date,
+									email,
+									phone,
+									nationality,
+									tax_id,
+									email_bidx,
+									tax_id_bidx,
+									name_trgm_bidx,
+									created_at,
+									tenant_id) 
+									VALUES($1, $2, $3, NULLIF($4,'')::date, NULLIF($5,''), NULLIF($6,''), NULLIF($7,''), NULLIF($8,''), NULLIF($9,''), NULLIF($10,''), $11, $12, $13) RETURNING id`
+
+	sealed, err := w.sealPerson(onboarding.Person)
+	if err != nil {
+		return nil, err
+	}
+	onboarding.Person.CreatedAt = time.Now()
+
+	row := tx.QueryRow(ctx, query,  onboarding.Person.PersonID,  
+									sealed.Name,
+									onboarding.Person.PersonType,
+									onboarding.Person.BirthDate,
+									sealed.Email,
+									sealed.Phone,
+									onboarding.Person.Nationality,
+									sealed.TaxID,
+									sealed.EmailIndex,
+									sealed.TaxIDIndex,
+									sealed.NameTrigrams,
+									onboarding.Person.CreatedAt,
+									onboarding.Person.TenantID)
+
+	var id int
+	
+	if err := row.Scan(&id); err != nil {
+		return nil, uniqueViolation(err)
+	}
+
+	onboarding.Person.ID = id
+
+	return onboarding, nil
+}
+
+func (w WorkerRepository) GetPerson(ctx context.Context, onboarding *model.Onboarding) (_ *model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetPerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	return w.getPerson(ctx, conn, onboarding)
+}
+
+// About get a person in a transaction, it sees the writes of the transaction and
+// takes no other connection of the pool
+func (w WorkerRepository) GetPersonTx(ctx context.Context, tx pgx.Tx, onboarding *model.Onboarding) (_ *model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetPersonTx").Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetPersonTx", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	return w.getPerson(ctx, tx, onboarding)
+}
+
+func (w WorkerRepository) getPerson(ctx context.Context, q querier, onboarding *model.Onboarding) (*model.Onboarding, error) {
+	res_person := model.Person{}
+	res_onboarding := model.Onboarding{Person: &res_person}
+
+	query := `SELECT id,
+					person_id,	 
+					name,
+					person_type,
+					COALESCE(to_char(birth_date, 'YYYY-MM-DD'), ''),
+					COALESCE(email, ''),
+					COALESCE(phone, ''),
+					COALESCE(nationality, ''),
+					COALESCE(tax_id, ''),
+					created_at,
+					updated_at,
+					COALESCE(tenant_id, '')
+				FROM public.person 
+				WHERE person_id =$1
+				AND ($2 = '' OR tenant_id = $2)
+				AND erased_at IS NULL`
+
+	// the tenant, when given, must be the one of the person: the one of the caller, none
+	// for a admin and for the reads of the service itself
+	rows, err := q.Query(ctx, query, onboarding.Person.PersonID, onboarding.Person.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, erro.ErrNotFound
+	}
+	err = rows.Scan( 	&res_person.ID,
+						&res_person.PersonID, 
+						&res_person.Name, 
+						&res_person.PersonType,
+						&res_person.BirthDate,
+						&res_person.Email,
+						&res_person.Phone,
+						&res_person.Nationality,
+						&res_person.TaxID,
+						&res_person.CreatedAt,
+						&res_person.UpdatedAt,
+						&res_person.TenantID,
+					)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err = w.openPerson(ctx, &res_person); err != nil {
+		return nil, err
+	}
+
+	// the child collections are read in the same connection (same replica)
+	res_person.Addresses, err = w.listAddress(ctx, q, res_person.ID)
+	if err != nil {
+		return nil, err
+	}
+	res_person.Documents, err = w.listDocument(ctx, q, res_person.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res_onboarding, nil
+}
+
+func (w WorkerRepository) UpdatePerson(ctx context.Context, tx pgx.Tx, onboarding *model.Onboarding) (_ int64, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdatePerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.UpdatePerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	t_updateAt := time.Now()
+	onboarding.Person.UpdatedAt = &t_updateAt
+
+	query := `Update public.person
+				set name = $2, 
+					person_type = $3,
+					birth_date = NULLIF($4,'')::date,
+					email = NULLIF($5,''),
+					phone = NULLIF($6,''),
+					nationality = NULLIF($7,''),
+					tax_id = NULLIF($8,''),
+					email_bidx = NULLIF($9,''),
+					tax_id_bidx = NULLIF($10,''),
+					name_trgm_bidx = $11,
+					updated_at = $12
+				where person_id = $1
+				and erased_at IS NULL`
+
+	sealed, err := w.sealPerson(onboarding.Person)
+	if err != nil {
+		return 0, err
+	}
+
+	row, err := tx.Exec(ctx, query, onboarding.Person.PersonID,  
+									sealed.Name,
+									onboarding.Person.PersonType,
+									onboarding.Person.BirthDate,
+									sealed.Email,
+									sealed.Phone,
+									onboarding.Person.Nationality,
+									sealed.TaxID,
+									sealed.EmailIndex,
+									sealed.TaxIDIndex,
+									sealed.NameTrigrams,
+									onboarding.Person.UpdatedAt)
+	if err != nil {
+		return 0, uniqueViolation(err)
+	}
+	if int(row.RowsAffected()) == 0 {
+		return 0, erro.ErrUpdateRows
+	}
+	logging.Ctx(ctx, childLogger).Debug().Int("rowsAffected : ",int(row.RowsAffected())).Msg("")
+	
+	return row.RowsAffected(), nil
+}
+
+func (w WorkerRepository) ListPerson(ctx context.Context, onboarding *model.Onboarding) (_ *[]model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListPerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		logging.Ctx(ctx, childLogger).Error().Err(err).Msg("error acquire")
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	res_onboarding_list := []model.Onboarding{}
+
+	query := `SELECT id,
+					person_id, 
+					name,
+					person_type,
+					COALESCE(to_char(birth_date, 'YYYY-MM-DD'), ''),
+					COALESCE(email, ''),
+					COALESCE(phone, ''),
+					COALESCE(nationality, ''),
+					COALESCE(tax_id, ''),
+					created_at,
+					updated_at,
+					COALESCE(tenant_id, '')
+				FROM public.person
+				WHERE person_id >= $1 
+				AND ($2 = '' OR tenant_id = $2)
+				AND erased_at IS NULL
+				ORDER BY person_id asc`
+
+	rows, err := conn.Query(ctx, query, onboarding.Person.PersonID, onboarding.Person.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		res_person := model.Person{}
+		res_onboarding := model.Onboarding{Person: &res_person}
+
+		err := rows.Scan( 	&res_person.ID,
+							&res_person.PersonID, 
+							&res_person.Name, 
+							&res_person.PersonType,
+							&res_person.BirthDate,
+							&res_person.Email,
+							&res_person.Phone,
+							&res_person.Nationality,
+							&res_person.TaxID,
+							&res_person.CreatedAt,
+							&res_person.UpdatedAt,
+							&res_person.TenantID,
+						)
+		if err != nil {
+			return nil, err
+        }
+		if err := w.openPerson(ctx, &res_person); err != nil {
+			return nil, err
+		}
+		res_onboarding_list = append(res_onboarding_list, res_onboarding)
+	}
+	
+	return &res_onboarding_list, nil
+}
+// About list the person_id of a tenant after the one given (keyset), in order
+func (w WorkerRepository) ListTenantPersonID(ctx context.Context, tenantID string, afterPersonID string, limit int) (_ []string, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListTenantPersonID").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListTenantPersonID", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT person_id
+				FROM public.person
+				WHERE COALESCE(tenant_id, '') = $1
+				AND person_id > $2
+				AND erased_at IS NULL
+				ORDER BY person_id asc
+				LIMIT $3`
+
+	rows, err := conn.Query(ctx, query, tenantID, afterPersonID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_person_id_list := []string{}
+	for rows.Next() {
+		var personID string
+		if err := rows.Scan(&personID); err != nil {
+			return nil, err
+		}
+		res_person_id_list = append(res_person_id_list, personID)
+	}
+	return res_person_id_list, rows.Err()
+}
+
+// About count the persons of a tenant
+func (w WorkerRepository) CountTenantPerson(ctx context.Context, tenantID string) (_ int, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","CountTenantPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.CountTenantPerson", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT count(*)
+				FROM public.person
+				WHERE COALESCE(tenant_id, '') = $1
+				AND erased_at IS NULL`
+
+	var count int
+	err = conn.QueryRow(ctx, query, tenantID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,386 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/dedup"
+	"github.com/go-onboarding/internal/core/search"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The person fields encrypted at rest, the name is bound to the ciphertext
+const (
+	fieldName	= "person.name"
+	fieldEmail	= "person.email"
+	fieldPhone	= "person.phone"
+	fieldTaxID	= "person.tax_id"
+	fieldNameTrigram	= "person.name.trigram"
+	fieldStreet			= "address.street"
+	fieldDocumentNumber	= "document.number"
+)
+
+// The person fields as stored
+type sealedPerson struct {
+	Name		string
+	Email		string
+	Phone		string
+	TaxID		string
+	EmailIndex	string
+	TaxIDIndex	string
+	NameTrigrams	[]string	// nil (NULL) in plaintext, the name_search column is used
+}
+
+// About encrypt the PII fields of a person and compute their blind indexes,
+// a nil keyring keeps them in plaintext
+func (w WorkerRepository) sealPerson(person *model.Person) (sealed sealedPerson, err error) {
+	if sealed.Name, err = w.Keyring.Encrypt(fieldName, person.Name); err != nil {
+		return sealed, err
+	}
+	if sealed.Email, err = w.Keyring.Encrypt(fieldEmail, person.Email); err != nil {
+		return sealed, err
+	}
+	if sealed.Phone, err = w.Keyring.Encrypt(fieldPhone, person.Phone); err != nil {
+		return sealed, err
+	}
+	if sealed.TaxID, err = w.Keyring.Encrypt(fieldTaxID, person.TaxID); err != nil {
+		return sealed, err
+	}
+	sealed.EmailIndex = w.Keyring.BlindIndex(fieldEmail, person.Email)
+	sealed.TaxIDIndex = w.Keyring.BlindIndex(fieldTaxID, person.TaxID)
+	sealed.NameTrigrams = w.nameTrigramIndexes(person.Name)
+
+	return sealed, nil
+}
+
+// About encrypt a document number and compute the blind index of its normalized
+// form, the one the duplicate detection compares. The index is empty without a keyring.
+func (w WorkerRepository) sealDocumentNumber(number string) (sealed string, index string, err error) {
+	if sealed, err = w.Keyring.Encrypt(fieldDocumentNumber, number); err != nil {
+		return "", "", err
+	}
+	if w.Keyring != nil {
+		index = w.Keyring.BlindIndex(fieldDocumentNumber, dedup.DocumentNumber(number))
+	}
+	return sealed, index, nil
+}
+
+// About the blind indexes of the trigrams of a name, for the fuzzy search of the
+// encrypted names. nil without a keyring.
+func (w WorkerRepository) nameTrigramIndexes(name string) []string {
+	if w.Keyring == nil {
+		return nil
+	}
+	trigrams := search.Trigrams(name)
+	indexes := make([]string, len(trigrams))
+	for i, trigram := range trigrams {
+		indexes[i] = w.Keyring.BlindIndex(fieldNameTrigram, trigram)
+	}
+	return indexes
+}
+
+// About decrypt the PII fields of a person read from the database
+func (w WorkerRepository) openPerson(ctx context.Context, person *model.Person) (err error) {
+	if person.Name, err = w.Keyring.Decrypt(ctx, fieldName, person.Name); err != nil {
+		return err
+	}
+	if person.Email, err = w.Keyring.Decrypt(ctx, fieldEmail, person.Email); err != nil {
+		return err
+	}
+	if person.Phone, err = w.Keyring.Decrypt(ctx, fieldPhone, person.Phone); err != nil {
+		return err
+	}
+	person.TaxID, err = w.Keyring.Decrypt(ctx, fieldTaxID, person.TaxID)
+	return err
+}
+
+// About encrypt with the active data key a batch of persons, addresses and documents
+// encrypted with a older key (or still in plaintext), returns how many rows were
+// encrypted again. The rows are locked and skipped by the other replicas running it.
+func (w WorkerRepository) ReencryptPerson(ctx context.Context, tx pgx.Tx, batch int) (_ int, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ReencryptPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ReencryptPerson")
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT id,
+					name,
+					COALESCE(email, ''),
+					COALESCE(phone, ''),
+					COALESCE(tax_id, '')
+				FROM public.person
+				WHERE erased_at IS NULL
+					AND (name NOT LIKE $1
+						OR name_trgm_bidx IS NULL
+						OR (email IS NOT NULL AND (email NOT LIKE $1 OR email_bidx IS NULL))
+						OR (phone IS NOT NULL AND phone NOT LIKE $1)
+						OR (tax_id IS NOT NULL AND (tax_id NOT LIKE $1 OR tax_id_bidx IS NULL)))
+				ORDER BY id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(ctx, query, w.Keyring.ActivePrefix() + "%", batch)
+	if err != nil {
+		return 0, err
+	}
+	persons := []model.Person{}
+	for rows.Next() {
+		person := model.Person{}
+		if err := rows.Scan(&person.ID, &person.Name, &person.Email, &person.Phone, &person.TaxID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		persons = append(persons, person)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	update := `UPDATE public.person
+				SET name = $2,
+					email = NULLIF($3,''),
+					phone = NULLIF($4,''),
+					tax_id = NULLIF($5,''),
+					email_bidx = NULLIF($6,''),
+					tax_id_bidx = NULLIF($7,''),
+					name_trgm_bidx = $8
+				WHERE id = $1`
+
+	for i := range persons {
+		if err := w.openPerson(ctx, &persons[i]); err != nil {
+			return 0, err
+		}
+		sealed, err := w.sealPerson(&persons[i])
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(ctx, update,	persons[i].ID,
+										sealed.Name,
+										sealed.Email,
+										sealed.Phone,
+										sealed.TaxID,
+										sealed.EmailIndex,
+										sealed.TaxIDIndex,
+										sealed.NameTrigrams)
+		if err != nil {
+			return 0, uniqueViolation(err)
+		}
+	}
+
+	addresses, err := w.reencryptAddress(ctx, tx, batch)
+	if err != nil {
+		return 0, err
+	}
+	documents, err := w.reencryptDocument(ctx, tx, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(persons) + addresses + documents, nil
+}
+
+// About encrypt again a batch of the streets not under the active key
+func (w WorkerRepository) reencryptAddress(ctx context.Context, tx pgx.Tx, batch int) (int, error) {
+	query := `SELECT id, street
+				FROM public.person_address
+				WHERE street <> '' AND street NOT LIKE $1
+				ORDER BY id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(ctx, query, w.Keyring.ActivePrefix() + "%", batch)
+	if err != nil {
+		return 0, err
+	}
+	addresses := []model.Address{}
+	for rows.Next() {
+		address := model.Address{}
+		if err := rows.Scan(&address.ID, &address.Street); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		addresses = append(addresses, address)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, address := range addresses {
+		street, err := w.Keyring.Decrypt(ctx, fieldStreet, address.Street)
+		if err != nil {
+			return 0, err
+		}
+		if street, err = w.Keyring.Encrypt(fieldStreet, street); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE public.person_address SET street = $2 WHERE id = $1`, address.ID, street); err != nil {
+			return 0, err
+		}
+	}
+	return len(addresses), nil
+}
+
+// About encrypt again a batch of the document numbers not under the active key
+// or without their blind index
+func (w WorkerRepository) reencryptDocument(ctx context.Context, tx pgx.Tx, batch int) (int, error) {
+	query := `SELECT id, number
+				FROM public.person_document
+				WHERE number <> '' AND (number NOT LIKE $1 OR number_bidx IS NULL)
+				ORDER BY id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(ctx, query, w.Keyring.ActivePrefix() + "%", batch)
+	if err != nil {
+		return 0, err
+	}
+	documents := []model.Document{}
+	for rows.Next() {
+		document := model.Document{}
+		if err := rows.Scan(&document.ID, &document.Number); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		documents = append(documents, document)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, document := range documents {
+		number, err := w.Keyring.Decrypt(ctx, fieldDocumentNumber, document.Number)
+		if err != nil {
+			return 0, err
+		}
+		sealed, index, err := w.sealDocumentNumber(number)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(ctx, `UPDATE public.person_document SET number = $2, number_bidx = NULLIF($3,'') WHERE id = $1`, document.ID, sealed, index)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(documents), nil
+}
+
+// About count the persons, addresses and documents waiting for the re-encryption
+func (w WorkerRepository) CountReencryptPending(ctx context.Context) (_ int, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","CountReencryptPending").Send()
+
+	ctx, span := tracing.Start(ctx, "database.CountReencryptPending")
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT count(*)
+				FROM public.person
+				WHERE erased_at IS NULL
+					AND (name NOT LIKE $1
+						OR name_trgm_bidx IS NULL
+						OR (email IS NOT NULL AND (email NOT LIKE $1 OR email_bidx IS NULL))
+						OR (phone IS NOT NULL AND phone NOT LIKE $1)
+						OR (tax_id IS NOT NULL AND (tax_id NOT LIKE $1 OR tax_id_bidx IS NULL)))
+				+ (SELECT count(*) FROM public.person_address WHERE street <> '' AND street NOT LIKE $1)
+				+ (SELECT count(*) FROM public.person_document WHERE number <> '' AND (number NOT LIKE $1 OR number_bidx IS NULL))`
+
+	var pending int
+	err = w.DatabasePGServer.GetConnection().QueryRow(ctx, query, w.Keyring.ActivePrefix() + "%").Scan(&pending)
+	return pending, err
+}
+
+// About find the persons of a tenant by the exact tax id or email, through the
+// blind index of the encrypted rows or the plaintext of the rows not encrypted yet.
+// The value must be normalized as it was when stored.
+func (w WorkerRepository) FindPerson(ctx context.Context, tenantID string, taxID string, email string) (_ *[]model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","FindPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.FindPerson", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	column, value, index := "tax_id", taxID, w.Keyring.BlindIndex(fieldTaxID, taxID)
+	if taxID == "" {
+		column, value, index = "email", email, w.Keyring.BlindIndex(fieldEmail, email)
+	}
+
+	query := `SELECT id,
+					person_id,
+					name,
+					person_type,
+					COALESCE(to_char(birth_date, 'YYYY-MM-DD'), ''),
+					COALESCE(email, ''),
+					COALESCE(phone, ''),
+					COALESCE(nationality, ''),
+					COALESCE(tax_id, ''),
+					created_at,
+					updated_at,
+					COALESCE(tenant_id, '')
+				FROM public.person
+				WHERE COALESCE(tenant_id, '') = $1
+					AND erased_at IS NULL
+					AND (` + column + `_bidx = $2 OR ` + column + ` = $3)
+				ORDER BY person_id asc`
+
+	rows, err := conn.Query(ctx, query, tenantID, index, value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_onboarding_list := []model.Onboarding{}
+	for rows.Next() {
+		res_person := model.Person{}
+
+		err := rows.Scan( 	&res_person.ID,
+							&res_person.PersonID,
+							&res_person.Name,
+							&res_person.PersonType,
+							&res_person.BirthDate,
+							&res_person.Email,
+							&res_person.Phone,
+							&res_person.Nationality,
+							&res_person.TaxID,
+							&res_person.CreatedAt,
+							&res_person.UpdatedAt,
+							&res_person.TenantID,
+						)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.openPerson(ctx, &res_person); err != nil {
+			return nil, err
+		}
+		res_onboarding_list = append(res_onboarding_list, model.Onboarding{Person: &res_person})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &res_onboarding_list, nil
+}
+
+// About the encryption keys and the persons waiting for the re-encryption
+func (w WorkerRepository) EncryptionStatus(ctx context.Context) (*model.EncryptionStatus, error) {
+	status := model.EncryptionStatus{	ActiveVersion: w.Keyring.ActiveVersion(),
+										Versions: w.Keyring.Versions(),
+										CheckedAt: time.Now(),
+	}
+	pending, err := w.CountReencryptPending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Pending = pending
+
+	return &status, nil
+}
@@ -0,0 +1,408 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// A pool connection or a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// About get the internal id and the tenant of a person, locking it until the transaction ends
+// so the child collections are not changed while the person is being deleted
+func (w WorkerRepository) LockPerson(ctx context.Context, tx pgx.Tx, personID string) (_ int, _ string, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","LockPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.LockPerson", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT id, COALESCE(tenant_id, '') FROM public.person WHERE person_id = $1 AND erased_at IS NULL FOR UPDATE`
+
+	var id int
+	var tenantID string
+	err = tx.QueryRow(ctx, query, personID).Scan(&id, &tenantID)
+	if err == pgx.ErrNoRows {
+		return 0, "", erro.ErrNotFound
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return id, tenantID, nil
+}
+
+// About list the addresses of a person, of the tenant when one is given
+func (w WorkerRepository) ListAddress(ctx context.Context, tenantID string, personID string) (_ []model.Address, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListAddress").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListAddress", tracing.PersonAttributes(tenantID, personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	id, err := personInternalID(ctx, conn, tenantID, personID)
+	if err != nil {
+		return nil, err
+	}
+	return w.listAddress(ctx, conn, id)
+}
+
+// About list the identity documents of a person, of the tenant when one is given
+func (w WorkerRepository) ListDocument(ctx context.Context, tenantID string, personID string) (_ []model.Document, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListDocument").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListDocument", tracing.PersonAttributes(tenantID, personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	id, err := personInternalID(ctx, conn, tenantID, personID)
+	if err != nil {
+		return nil, err
+	}
+	return w.listDocument(ctx, conn, id)
+}
+
+// About get the internal id of a person, the persons of the other tenants are not found
+func personInternalID(ctx context.Context, q querier, tenantID string, personID string) (int, error) {
+	rows, err := q.Query(ctx, `SELECT id FROM public.person WHERE person_id = $1 AND ($2 = '' OR tenant_id = $2) AND erased_at IS NULL`, personID, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int])
+	if err == pgx.ErrNoRows {
+		return 0, erro.ErrNotFound
+	}
+	return id, err
+}
+
+// About the addresses of a person, the street decrypted
+func (w WorkerRepository) listAddress(ctx context.Context, q querier, id int) ([]model.Address, error) {
+	query := `SELECT id,
+					type,
+					street,
+					COALESCE(number, ''),
+					COALESCE(complement, ''),
+					COALESCE(district, ''),
+					city,
+					COALESCE(state, ''),
+					postal_code,
+					country,
+					is_primary,
+					created_at,
+					updated_at
+				FROM public.person_address
+				WHERE fk_person_id = $1
+				ORDER BY is_primary desc, id asc`
+
+	rows, err := q.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_address_list := []model.Address{}
+	for rows.Next() {
+		res_address := model.Address{}
+		err := rows.Scan(	&res_address.ID,
+							&res_address.Type,
+							&res_address.Street,
+							&res_address.Number,
+							&res_address.Complement,
+							&res_address.District,
+							&res_address.City,
+							&res_address.State,
+							&res_address.PostalCode,
+							&res_address.Country,
+							&res_address.IsPrimary,
+							&res_address.CreatedAt,
+							&res_address.UpdatedAt,
+						)
+		if err != nil {
+			return nil, err
+		}
+		if res_address.Street, err = w.Keyring.Decrypt(ctx, fieldStreet, res_address.Street); err != nil {
+			return nil, err
+		}
+		res_address_list = append(res_address_list, res_address)
+	}
+	return res_address_list, rows.Err()
+}
+
+// About the identity documents of a person, the number decrypted
+func (w WorkerRepository) listDocument(ctx context.Context, q querier, id int) ([]model.Document, error) {
+	query := `SELECT id,
+					type,
+					number,
+					issuing_country,
+					COALESCE(issuing_authority, ''),
+					COALESCE(to_char(issue_date, 'YYYY-MM-DD'), ''),
+					COALESCE(to_char(expiry_date, 'YYYY-MM-DD'), ''),
+					created_at,
+					updated_at
+				FROM public.person_document
+				WHERE fk_person_id = $1
+				ORDER BY id asc`
+
+	rows, err := q.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_document_list := []model.Document{}
+	for rows.Next() {
+		res_document := model.Document{}
+		err := rows.Scan(	&res_document.ID,
+							&res_document.Type,
+							&res_document.Number,
+							&res_document.IssuingCountry,
+							&res_document.IssuingAuthority,
+							&res_document.IssueDate,
+							&res_document.ExpiryDate,
+							&res_document.CreatedAt,
+							&res_document.UpdatedAt,
+						)
+		if err != nil {
+			return nil, err
+		}
+		if res_document.Number, err = w.Keyring.Decrypt(ctx, fieldDocumentNumber, res_document.Number); err != nil {
+			return nil, err
+		}
+		res_document_list = append(res_document_list, res_document)
+	}
+	return res_document_list, rows.Err()
+}
+
+// About add a address, a new primary address demotes the previous one
+func (w WorkerRepository) AddAddress(ctx context.Context, tx pgx.Tx, id int, address *model.Address) (_ *model.Address, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddAddress").Send()
+
+	ctx, span := tracing.Start(ctx, "database.AddAddress")
+	defer func() { tracing.End(span, err) }()
+
+	if address.IsPrimary {
+		if err = clearPrimaryAddress(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `INSERT INTO public.person_address (	fk_person_id,
+													type,
+													street,
+													number,
+													complement,
+													district,
+													city,
+													state,
+													postal_code,
+													country,
+													is_primary,
+													created_at)
+				VALUES($1, $2, $3, NULLIF($4,''), NULLIF($5,''), NULLIF($6,''), $7, NULLIF($8,''), $9, $10, $11, $12) RETURNING id`
+
+	street, err := w.Keyring.Encrypt(fieldStreet, address.Street)
+	if err != nil {
+		return nil, err
+	}
+	address.CreatedAt = time.Now()
+
+	err = tx.QueryRow(ctx, query,	id,
+									address.Type,
+									street,
+									address.Number,
+									address.Complement,
+									address.District,
+									address.City,
+									address.State,
+									address.PostalCode,
+									address.Country,
+									address.IsPrimary,
+									address.CreatedAt).Scan(&address.ID)
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// About update a address of the person
+func (w WorkerRepository) UpdateAddress(ctx context.Context, tx pgx.Tx, id int, address *model.Address) (_ int64, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateAddress").Send()
+
+	ctx, span := tracing.Start(ctx, "database.UpdateAddress")
+	defer func() { tracing.End(span, err) }()
+
+	if address.IsPrimary {
+		if err = clearPrimaryAddress(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	street, err := w.Keyring.Encrypt(fieldStreet, address.Street)
+	if err != nil {
+		return 0, err
+	}
+	t_updateAt := time.Now()
+	address.UpdatedAt = &t_updateAt
+
+	query := `UPDATE public.person_address
+				SET type = $3,
+					street = $4,
+					number = NULLIF($5,''),
+					complement = NULLIF($6,''),
+					district = NULLIF($7,''),
+					city = $8,
+					state = NULLIF($9,''),
+					postal_code = $10,
+					country = $11,
+					is_primary = $12,
+					updated_at = $13
+				WHERE id = $1 AND fk_person_id = $2`
+
+	row, err := tx.Exec(ctx, query,	address.ID,
+									id,
+									address.Type,
+									street,
+									address.Number,
+									address.Complement,
+									address.District,
+									address.City,
+									address.State,
+									address.PostalCode,
+									address.Country,
+									address.IsPrimary,
+									address.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return row.RowsAffected(), nil
+}
+
+// About delete a address of the person
+func (w WorkerRepository) DeleteAddress(ctx context.Context, tx pgx.Tx, id int, addressID int) (_ int64, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteAddress").Send()
+
+	ctx, span := tracing.Start(ctx, "database.DeleteAddress")
+	defer func() { tracing.End(span, err) }()
+
+	row, err := tx.Exec(ctx, `DELETE FROM public.person_address WHERE id = $1 AND fk_person_id = $2`, addressID, id)
+	if err != nil {
+		return 0, err
+	}
+	return row.RowsAffected(), nil
+}
+
+func clearPrimaryAddress(ctx context.Context, tx pgx.Tx, id int) error {
+	_, err := tx.Exec(ctx, `UPDATE public.person_address SET is_primary = false WHERE fk_person_id = $1 AND is_primary`, id)
+	return err
+}
+
+// About add a identity document
+func (w WorkerRepository) AddDocument(ctx context.Context, tx pgx.Tx, id int, document *model.Document) (_ *model.Document, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddDocument").Send()
+
+	ctx, span := tracing.Start(ctx, "database.AddDocument")
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO public.person_document (	fk_person_id,
+													type,
+													number,
+													issuing_country,
+													issuing_authority,
+													issue_date,
+													expiry_date,
+													created_at,
+													number_bidx)
+				VALUES($1, $2, $3, $4, NULLIF($5,''), NULLIF($6,'')::date, NULLIF($7,'')::date, $8, NULLIF($9,'')) RETURNING id`
+
+	number, numberIndex, err := w.sealDocumentNumber(document.Number)
+	if err != nil {
+		return nil, err
+	}
+	document.CreatedAt = time.Now()
+
+	err = tx.QueryRow(ctx, query,	id,
+									document.Type,
+									number,
+									document.IssuingCountry,
+									document.IssuingAuthority,
+									document.IssueDate,
+									document.ExpiryDate,
+									document.CreatedAt,
+									numberIndex).Scan(&document.ID)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// About update a identity document of the person
+func (w WorkerRepository) UpdateDocument(ctx context.Context, tx pgx.Tx, id int, document *model.Document) (_ int64, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateDocument").Send()
+
+	ctx, span := tracing.Start(ctx, "database.UpdateDocument")
+	defer func() { tracing.End(span, err) }()
+
+	number, numberIndex, err := w.sealDocumentNumber(document.Number)
+	if err != nil {
+		return 0, err
+	}
+	t_updateAt := time.Now()
+	document.UpdatedAt = &t_updateAt
+
+	query := `UPDATE public.person_document
+				SET type = $3,
+					number = $4,
+					issuing_country = $5,
+					issuing_authority = NULLIF($6,''),
+					issue_date = NULLIF($7,'')::date,
+					expiry_date = NULLIF($8,'')::date,
+					updated_at = $9,
+					number_bidx = NULLIF($10,'')
+				WHERE id = $1 AND fk_person_id = $2`
+
+	row, err := tx.Exec(ctx, query,	document.ID,
+									id,
+									document.Type,
+									number,
+									document.IssuingCountry,
+									document.IssuingAuthority,
+									document.IssueDate,
+									document.ExpiryDate,
+									document.UpdatedAt,
+									numberIndex)
+	if err != nil {
+		return 0, err
+	}
+	return row.RowsAffected(), nil
+}
+
+// About delete a identity document of the person
+func (w WorkerRepository) DeleteDocument(ctx context.Context, tx pgx.Tx, id int, documentID int) (_ int64, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteDocument").Send()
+
+	ctx, span := tracing.Start(ctx, "database.DeleteDocument")
+	defer func() { tracing.End(span, err) }()
+
+	row, err := tx.Exec(ctx, `DELETE FROM public.person_document WHERE id = $1 AND fk_person_id = $2`, documentID, id)
+	if err != nil {
+		return 0, err
+	}
+	return row.RowsAffected(), nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"context"
+
+	"github.com/go-onboarding/internal/infra/credential"
+
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DatabasePGServer is a pgx pool whose connections take the credentials
+// from a credential.Provider, so rotated secrets are used without a restart
+type DatabasePGServer struct {
+	connPool *pgxpool.Pool
+}
+
+// About create the pool and check the connection
+func NewDatabasePGServer(	ctx context.Context,
+							databaseConfig go_core_pg.DatabaseConfig,
+							credentialProvider credential.Provider) (*DatabasePGServer, error) {
+	childLogger.Info().Str("func","NewDatabasePGServer").Send()
+
+	connStr := fmt.Sprintf("postgres://%s:%s/%s", 	databaseConfig.Host,
+													databaseConfig.Port,
+													databaseConfig.DatabaseName)
+
+	config, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxConns = int32(databaseConfig.DbMax_Connection)
+	config.MinConns = 1
+	config.MaxConnIdleTime = 5 * time.Minute
+	config.ConnConfig.Tracer = pgTracer{databaseName: databaseConfig.DatabaseName}
+
+	// every new connection asks for the current credentials
+	config.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+		credentials, err := credentialProvider.Credentials(ctx)
+		if err != nil {
+			return err
+		}
+		connConfig.User = credentials.User
+		connConfig.Password = credentials.Password
+		return nil
+	}
+
+	connPool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxPing, cancel := context.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	if err = connPool.Ping(ctxPing); err != nil {
+		connPool.Close()
+		return nil, err
+	}
+
+	return &DatabasePGServer{connPool: connPool}, nil
+}
+
+// About get the pool
+func (d *DatabasePGServer) GetConnection() *pgxpool.Pool {
+	return d.connPool
+}
+
+// About acquire a connection
+func (d *DatabasePGServer) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return d.connPool.Acquire(ctx)
+}
+
+// About release a connection
+func (d *DatabasePGServer) Release(conn *pgxpool.Conn) {
+	conn.Release()
+}
+
+// About start a transaction in a dedicated connection
+func (d *DatabasePGServer) StartTx(ctx context.Context) (pgx.Tx, *pgxpool.Conn, error) {
+	conn, err := d.connPool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		conn.Release()
+		return nil, nil, err
+	}
+	return tx, conn, nil
+}
+
+// About release the connection used by a transaction
+func (d *DatabasePGServer) ReleaseTx(conn *pgxpool.Conn) {
+	conn.Release()
+}
+
+// About ping the database
+func (d *DatabasePGServer) Ping(ctx context.Context) error {
+	return d.connPool.Ping(ctx)
+}
+
+// About get the pool stats
+func (d *DatabasePGServer) Stat() *pgxpool.Stat {
+	return d.connPool.Stat()
+}
+
+// About close the pool
+func (d *DatabasePGServer) CloseConnection() {
+	d.connPool.Close()
+}
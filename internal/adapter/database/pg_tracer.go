@@ -0,0 +1,47 @@
+package database
+
+import(
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// pgx query tracer, every statement becomes a span under the caller span
+type pgTracer struct {
+	databaseName string
+}
+
+// About start the statement span
+func (t pgTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := sqlOperation(data.SQL)
+
+	ctx, _ = tracing.Start(ctx, "db." + strings.ToLower(operation),
+							attribute.String("db.system", "postgresql"),
+							attribute.String("db.name", t.databaseName),
+							attribute.String("db.statement", data.SQL),
+							attribute.String("db.operation", operation))
+	return ctx
+}
+
+// About end the statement span
+func (t pgTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	tracing.End(span, data.Err)
+}
+
+// About the first keyword of a statement (SELECT, INSERT...)
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(fields[0])
+}
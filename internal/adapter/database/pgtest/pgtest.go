@@ -0,0 +1,348 @@
+package pgtest
+
+import (
+	"net"
+	"fmt"
+	"sync"
+	"regexp"
+	"strings"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Column of a result, the OID is the postgres type (pgtype.Int8OID, pgtype.TextOID...)
+type Column struct {
+	Name	string
+	OID		uint32
+}
+
+// Result of a statement, the rows hold go values encoded as the column types
+type Result struct {
+	Columns	[]Column
+	Rows	[][]any
+	Tag		string	// command tag, "SELECT <rows>" when empty
+}
+
+// Query received by the server, the args are in the text format (nil is NULL)
+type Query struct {
+	SQL		string
+	Args	[]*string
+}
+
+// About answer a statement. It is called with nil args when the statement is described,
+// the columns must then be the ones of the execution. A *pgconn.PgError is sent as is.
+type Handler func(sql string, args []*string) (Result, error)
+
+// Server is a fake postgres answering the statements with a handler, for the tests
+// going through pgx down to the wire. No auth, no tls, a statement at a time.
+type Server struct {
+	listener	net.Listener
+	handler		Handler
+	mu			sync.Mutex
+	queries		[]Query
+	wg			sync.WaitGroup
+}
+
+// About start a server on a local port
+func NewServer(handler Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener, handler: handler}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// About the host and port of the server
+func (s *Server) HostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host, port
+}
+
+// About the statements executed so far (the ones only described are not)
+func (s *Server) Queries() []Query {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Query(nil), s.queries...)
+}
+
+// About stop accepting connections, the open ones end with their client
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			s.serve(conn)
+		}()
+	}
+}
+
+// a prepared statement or a bound portal
+type statement struct {
+	sql				string
+	args			[]*string
+	resultFormats	[]int16
+}
+
+var paramRegexp = regexp.MustCompile(`\$(\d+)`)
+
+// About the number of params of a statement, its highest $n
+func paramCount(sql string) int {
+	count := 0
+	for _, match := range paramRegexp.FindAllStringSubmatch(sql, -1) {
+		if n, _ := strconv.Atoi(match[1]); n > count {
+			count = n
+		}
+	}
+	return count
+}
+
+func (s *Server) serve(conn net.Conn) {
+	backend := pgproto3.NewBackend(conn, conn)
+
+	for {
+		startup, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return
+		}
+		if _, ok := startup.(*pgproto3.StartupMessage); ok {
+			break
+		}
+		// ssl and gss are refused, the client goes on in plain text
+		if _, err := conn.Write([]byte("N")); err != nil {
+			return
+		}
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "server_version", Value: "16.0"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	typeMap := pgtype.NewMap()
+	statements := map[string]*statement{}
+	portals := map[string]*statement{}
+	txStatus := byte('I')
+	failed := false
+
+	sendError := func(err error) {
+		pgError, ok := err.(*pgconn.PgError)
+		if !ok {
+			pgError = &pgconn.PgError{Severity: "ERROR", Code: "XX000", Message: err.Error()}
+		}
+		backend.Send(&pgproto3.ErrorResponse{Severity: pgError.Severity, Code: pgError.Code, Message: pgError.Message, ConstraintName: pgError.ConstraintName})
+		if txStatus == 'T' {
+			txStatus = 'E'
+		}
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		// after a error the extended messages are skipped until the sync
+		if failed {
+			if _, ok := msg.(*pgproto3.Sync); !ok {
+				continue
+			}
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			sql := strings.TrimSpace(msg.String)
+			if tag, ok := txCommand(sql, &txStatus); ok {
+				backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(tag)})
+			} else if sql == "" || strings.HasPrefix(sql, "--") || sql == ";" {
+				backend.Send(&pgproto3.EmptyQueryResponse{})
+			} else if err := s.execute(backend, typeMap, &statement{sql: sql}, true); err != nil {
+				sendError(err)
+			}
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+		case *pgproto3.Parse:
+			statements[msg.Name] = &statement{sql: msg.Query}
+			backend.Send(&pgproto3.ParseComplete{})
+		case *pgproto3.Describe:
+			var st *statement
+			if msg.ObjectType == 'S' {
+				st = statements[msg.Name]
+			} else {
+				st = portals[msg.Name]
+			}
+			if st == nil {
+				sendError(fmt.Errorf("unknown statement %q", msg.Name))
+				failed = true
+				continue
+			}
+			if msg.ObjectType == 'S' {
+				// the types are unknown, the client sends the params as text
+				backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: make([]uint32, paramCount(st.sql))})
+			}
+			result, err := s.handler(st.sql, nil)
+			if err != nil {
+				sendError(err)
+				failed = true
+				continue
+			}
+			if len(result.Columns) == 0 {
+				backend.Send(&pgproto3.NoData{})
+			} else {
+				backend.Send(rowDescription(result.Columns, st.resultFormats))
+			}
+		case *pgproto3.Bind:
+			st := statements[msg.PreparedStatement]
+			if st == nil {
+				sendError(fmt.Errorf("unknown statement %q", msg.PreparedStatement))
+				failed = true
+				continue
+			}
+			args := make([]*string, len(msg.Parameters))
+			for i, param := range msg.Parameters {
+				if param != nil {
+					value := string(param)
+					args[i] = &value
+				}
+			}
+			portals[msg.DestinationPortal] = &statement{sql: st.sql, args: args, resultFormats: msg.ResultFormatCodes}
+			backend.Send(&pgproto3.BindComplete{})
+		case *pgproto3.Execute:
+			st := portals[msg.Portal]
+			if st == nil {
+				sendError(fmt.Errorf("unknown portal %q", msg.Portal))
+				failed = true
+				continue
+			}
+			if tag, ok := txCommand(st.sql, &txStatus); ok {
+				backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(tag)})
+			} else if err := s.execute(backend, typeMap, st, false); err != nil {
+				sendError(err)
+				failed = true
+			}
+		case *pgproto3.Close:
+			if msg.ObjectType == 'S' {
+				delete(statements, msg.Name)
+			} else {
+				delete(portals, msg.Name)
+			}
+			backend.Send(&pgproto3.CloseComplete{})
+		case *pgproto3.Sync:
+			failed = false
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+		case *pgproto3.Flush:
+		case *pgproto3.Terminate:
+			return
+		default:
+			sendError(fmt.Errorf("unsupported message %T", msg))
+		}
+
+		if err := backend.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// About answer the transaction commands, the status is tracked for the ready for query
+func txCommand(sql string, txStatus *byte) (string, bool) {
+	command := strings.ToUpper(strings.TrimRight(strings.TrimSpace(sql), ";"))
+	switch {
+	case strings.HasPrefix(command, "BEGIN"), strings.HasPrefix(command, "START TRANSACTION"):
+		*txStatus = 'T'
+		return "BEGIN", true
+	case command == "COMMIT":
+		*txStatus = 'I'
+		return "COMMIT", true
+	case command == "ROLLBACK":
+		*txStatus = 'I'
+		return "ROLLBACK", true
+	}
+	return "", false
+}
+
+// About the description of the columns, in the formats asked by the client
+func rowDescription(columns []Column, formats []int16) *pgproto3.RowDescription {
+	fields := make([]pgproto3.FieldDescription, len(columns))
+	for i, column := range columns {
+		fields[i] = pgproto3.FieldDescription{	Name: []byte(column.Name),
+												DataTypeOID: column.OID,
+												DataTypeSize: -1,
+												TypeModifier: -1,
+												Format: resultFormat(formats, i),
+		}
+	}
+	return &pgproto3.RowDescription{Fields: fields}
+}
+
+// About the format of a result column, one format applies to all the columns
+func resultFormat(formats []int16, i int) int16 {
+	switch {
+	case len(formats) == 0:
+		return pgtype.TextFormatCode
+	case len(formats) == 1:
+		return formats[0]
+	}
+	return formats[i]
+}
+
+// About run a statement with the handler and send its rows
+func (s *Server) execute(backend *pgproto3.Backend, typeMap *pgtype.Map, st *statement, describe bool) error {
+	s.mu.Lock()
+	s.queries = append(s.queries, Query{SQL: st.sql, Args: st.args})
+	s.mu.Unlock()
+
+	args := st.args
+	if args == nil {
+		args = []*string{}
+	}
+	result, err := s.handler(st.sql, args)
+	if err != nil {
+		return err
+	}
+
+	// the simple protocol describes the rows with them
+	if describe && len(result.Columns) > 0 {
+		backend.Send(rowDescription(result.Columns, nil))
+	}
+	for _, row := range result.Rows {
+		values := make([][]byte, len(row))
+		for i, value := range row {
+			if value == nil {
+				continue
+			}
+			// a non nil buffer, a empty value is not a NULL
+			encoded, err := typeMap.Encode(result.Columns[i].OID, resultFormat(st.resultFormats, i), value, []byte{})
+			if err != nil {
+				return fmt.Errorf("encode column %s: %w", result.Columns[i].Name, err)
+			}
+			values[i] = encoded
+		}
+		backend.Send(&pgproto3.DataRow{Values: values})
+	}
+
+	tag := result.Tag
+	if tag == "" {
+		tag = "SELECT " + strconv.Itoa(len(result.Rows))
+	}
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(tag)})
+	return nil
+}
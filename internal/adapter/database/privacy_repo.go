@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// About record a audit event of a person in the transaction of the change
+func (w WorkerRepository) AddAudit(ctx context.Context, tx pgx.Tx, audit *model.Audit) (err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","AddAudit").Str("action", audit.Action).Send()
+
+	query := `INSERT INTO public.person_audit (	person_id,
+												tenant_id,
+												action,
+												actor,
+												trace_id,
+												created_at)
+				VALUES($1, NULLIF($2,''), $3, NULLIF($4,''), NULLIF($5,''), $6) RETURNING id`
+
+	audit.CreatedAt = time.Now()
+
+	return tx.QueryRow(ctx, query,	audit.PersonID,
+									audit.TenantID,
+									audit.Action,
+									audit.Actor,
+									audit.TraceID,
+									audit.CreatedAt).Scan(&audit.ID)
+}
+
+// About list the audit history of a person with the one of the persons merged into it, oldest first
+func (w WorkerRepository) ListAudit(ctx context.Context, personID string) (_ []model.Audit, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListAudit").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListAudit", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	// the history of the persons merged into it (and into them) is its history too
+	query := `WITH RECURSIVE merged(person_id) AS (
+					SELECT $1::varchar
+					UNION
+					SELECT m.merged_person_id
+						FROM public.person_merge m
+						JOIN merged ON m.survivor_person_id = merged.person_id
+				)
+				SELECT id,
+					person_id,
+					COALESCE(tenant_id, ''),
+					action,
+					COALESCE(actor, ''),
+					COALESCE(trace_id, ''),
+					created_at
+				FROM public.person_audit
+				WHERE person_id IN (SELECT person_id FROM merged)
+				ORDER BY created_at asc, id asc`
+
+	rows, err := conn.Query(ctx, query, personID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_audit_list := []model.Audit{}
+	for rows.Next() {
+		res_audit := model.Audit{}
+		err := rows.Scan(	&res_audit.ID,
+							&res_audit.PersonID,
+							&res_audit.TenantID,
+							&res_audit.Action,
+							&res_audit.Actor,
+							&res_audit.TraceID,
+							&res_audit.CreatedAt,
+						)
+		if err != nil {
+			return nil, err
+		}
+		res_audit_list = append(res_audit_list, res_audit)
+	}
+	return res_audit_list, rows.Err()
+}
+
+// About anonymize a person locked by the transaction: the PII is cleared, the
+// child collections and the traces of the person are deleted, the row is hidden
+// from the reads and the tombstone recorded
+func (w WorkerRepository) ErasePerson(ctx context.Context, tx pgx.Tx, id int, tombstone *model.Tombstone) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ErasePerson").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ErasePerson", tracing.PersonAttributes("", tombstone.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	tombstone.ErasedAt = time.Now()
+
+	if tombstone.TenantID, err = anonymizePerson(ctx, tx, id, tombstone.ErasedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM public.person_address WHERE fk_person_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM public.person_document WHERE fk_person_id = $1`, id); err != nil {
+		return err
+	}
+	if err := purgePerson(ctx, tx, tombstone.PersonID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO public.person_tombstone (	person_id,
+													tenant_id,
+													actor,
+													trace_id,
+													erased_at)
+				VALUES($1, NULLIF($2,''), NULLIF($3,''), NULLIF($4,''), $5)
+				ON CONFLICT (person_id) DO NOTHING`
+
+	_, err = tx.Exec(ctx, query,	tombstone.PersonID,
+									tombstone.TenantID,
+									tombstone.Actor,
+									tombstone.TraceID,
+									tombstone.ErasedAt)
+	return err
+}
+
+// About purge the traces of a erased person kept out of its rows: the duplicates it is
+// part of, its webhook deliveries (the attempts and the errors of the receivers go
+// with them) and the entries of the import results naming it, the line is kept
+func purgePerson(ctx context.Context, tx pgx.Tx, personID string) error {
+	query := `DELETE FROM public.person_duplicate WHERE person_id = $1 OR candidate_id = $1`
+	if _, err := tx.Exec(ctx, query, personID); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM public.webhook_delivery WHERE person_id = $1`
+	if _, err := tx.Exec(ctx, query, personID); err != nil {
+		return err
+	}
+
+	query = `UPDATE public.job
+				SET result = jsonb_set(result, '{errors}', (SELECT jsonb_agg(CASE WHEN e->>'person_id' = $1
+																				THEN jsonb_build_object('line', e->'line', 'error', 'erased')
+																				ELSE e END ORDER BY n)
+															FROM jsonb_array_elements(result->'errors') WITH ORDINALITY AS t(e, n)))
+				WHERE jsonb_typeof(result->'errors') = 'array'
+					AND result->'errors' @> jsonb_build_array(jsonb_build_object('person_id', $1::text))`
+	_, err := tx.Exec(ctx, query, personID)
+	return err
+}
+
+// About clear the PII of a person and hide it from the reads, returns its tenant
+func anonymizePerson(ctx context.Context, tx pgx.Tx, id int, at time.Time) (string, error) {
+	query := `UPDATE public.person
+				SET name = '',
+					birth_date = NULL,
+					email = NULL,
+					phone = NULL,
+					nationality = NULL,
+					tax_id = NULL,
+					email_bidx = NULL,
+					tax_id_bidx = NULL,
+					name_trgm_bidx = NULL,
+					updated_at = $2,
+					erased_at = $2
+				WHERE id = $1
+				RETURNING COALESCE(tenant_id, '')`
+
+	var tenantID string
+	err := tx.QueryRow(ctx, query, id, at).Scan(&tenantID)
+	return tenantID, err
+}
+
+// About get the tombstone of a erased person, always from the primary
+func (w WorkerRepository) GetTombstone(ctx context.Context, personID string) (_ *model.Tombstone, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetTombstone").Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetTombstone", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT person_id,
+					COALESCE(tenant_id, ''),
+					COALESCE(actor, ''),
+					COALESCE(trace_id, ''),
+					erased_at,
+					objects_deleted,
+					objects_deleted_at
+				FROM public.person_tombstone
+				WHERE person_id = $1`
+
+	tombstone := model.Tombstone{}
+	err = w.DatabasePGServer.GetConnection().QueryRow(ctx, query, personID).Scan(	&tombstone.PersonID,
+																					&tombstone.TenantID,
+																					&tombstone.Actor,
+																					&tombstone.TraceID,
+																					&tombstone.ErasedAt,
+																					&tombstone.ObjectsDeleted,
+																					&tombstone.ObjectsDeletedAt)
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tombstone, nil
+}
+
+// About add the bucket objects deleted by a erasure to its tombstone
+func (w WorkerRepository) AddTombstoneObjects(ctx context.Context, tombstone *model.Tombstone, deleted int) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddTombstoneObjects").Int("deleted", deleted).Send()
+
+	deletedAt := time.Now()
+
+	query := `UPDATE public.person_tombstone
+				SET objects_deleted = objects_deleted + $2,
+					objects_deleted_at = $3
+				WHERE person_id = $1
+				RETURNING objects_deleted`
+
+	err = w.DatabasePGServer.GetConnection().QueryRow(ctx, query, tombstone.PersonID, deleted, deletedAt).Scan(&tombstone.ObjectsDeleted)
+	if err != nil {
+		return err
+	}
+	tombstone.ObjectsDeletedAt = &deletedAt
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"sync"
+	"time"
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+)
+
+// Pools a read can be routed to
+const (
+	PoolPrimary	= "primary"
+	PoolReader	= "reader"
+)
+
+type primaryKey struct{}
+
+// About force the reads of the context to the primary, e.g. a read inside a write flow
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadRouter sends the queries to the reader pool (a replica or the reader endpoint).
+// The reads go to the primary when the reader is unhealthy, when the context asks
+// for it or, for a while after a write, when they come from the client that wrote
+// (read your writes). The writes are tracked per pod, the clients are not sticky
+// so a read landing in another pod may still lag behind.
+type ReadRouter struct {
+	reader			*DatabasePGServer
+	readYourWrites	time.Duration
+	healthy			atomic.Bool
+
+	mu			sync.Mutex
+	lastWrite	map[string]time.Time
+	lastSweep	time.Time
+}
+
+// About create a read router, readYourWrites 0 disables the window
+func NewReadRouter(reader *DatabasePGServer, readYourWrites time.Duration) *ReadRouter {
+	childLogger.Info().Str("func","NewReadRouter").Str("read_your_writes", readYourWrites.String()).Send()
+
+	r := &ReadRouter{
+		reader: reader,
+		readYourWrites: readYourWrites,
+		lastWrite: map[string]time.Time{},
+		lastSweep: time.Now(),
+	}
+	r.healthy.Store(true)
+
+	return r
+}
+
+// About the client of the request, the reads stay in the primary after its writes
+func client(ctx context.Context) string {
+	if correlation := logging.CorrelationFrom(ctx); correlation != nil {
+		return correlation.Client
+	}
+	return ""
+}
+
+// About pick the pool of a read and why, a nil router always picks the primary
+func (r *ReadRouter) route(ctx context.Context) (string, string) {
+	if r == nil {
+		return PoolPrimary, "no_reader"
+	}
+	if forced, _ := ctx.Value(primaryKey{}).(bool); forced {
+		return PoolPrimary, "forced"
+	}
+	if !r.healthy.Load() {
+		return PoolPrimary, "reader_unhealthy"
+	}
+	if r.readYourWrites > 0 {
+		if client := client(ctx); client != "" {
+			r.mu.Lock()
+			last, ok := r.lastWrite[client]
+			r.mu.Unlock()
+			if ok && time.Since(last) < r.readYourWrites {
+				return PoolPrimary, "read_your_writes"
+			}
+		}
+	}
+	return PoolReader, "default"
+}
+
+// About record a committed write of the request client
+func (r *ReadRouter) MarkWrite(ctx context.Context) {
+	if r == nil || r.readYourWrites == 0 {
+		return
+	}
+	client := client(ctx)
+	if client == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.lastWrite[client] = now
+
+	if now.Sub(r.lastSweep) < time.Minute {
+		return
+	}
+	r.lastSweep = now
+	for key, last := range r.lastWrite {
+		if now.Sub(last) >= r.readYourWrites {
+			delete(r.lastWrite, key)
+		}
+	}
+}
+
+// About mark the reader unhealthy, the reads go to the primary until a ping succeeds
+func (r *ReadRouter) markUnhealthy(ctx context.Context, err error) {
+	if r.healthy.Swap(false) {
+		logging.Ctx(ctx, childLogger).Error().Err(err).Msg("reader unhealthy, reads fall back to the primary")
+	}
+}
+
+// About ping the reader until the context is done
+func (r *ReadRouter) Watch(ctx context.Context, interval time.Duration) {
+	childLogger.Info().Str("func","Watch").Str("interval", interval.String()).Send()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ctxPing, cancel := context.WithTimeout(ctx, interval)
+			err := r.reader.Ping(ctxPing)
+			cancel()
+			if err != nil {
+				r.markUnhealthy(ctx, err)
+				continue
+			}
+			if !r.healthy.Swap(true) {
+				logging.Ctx(ctx, childLogger).Info().Msg("reader healthy again, reads are routed to it")
+			}
+		}
+	}
+}
+
+// About acquire a connection of the pool picked for a read,
+// a reader failing to give a connection sends the read to the primary
+func (w WorkerRepository) acquireRead(ctx context.Context) (*pgxpool.Conn, error) {
+	pool, reason := w.ReadRouter.route(ctx)
+	if pool == PoolReader {
+		conn, err := w.ReadRouter.reader.Acquire(ctx)
+		if err == nil {
+			metrics.DbReads.WithLabelValues(PoolReader, reason).Inc()
+			return conn, nil
+		}
+		// a request canceled or timed out says nothing of the reader, the primary would fail it too
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		w.ReadRouter.markUnhealthy(ctx, err)
+		reason = "reader_unhealthy"
+	}
+	metrics.DbReads.WithLabelValues(PoolPrimary, reason).Inc()
+
+	return w.DatabasePGServer.Acquire(ctx)
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// A name matches from this share of the query trigrams, the pg_trgm default of
+// the word similarity (pg_trgm.word_similarity_threshold)
+const searchThreshold = 0.6
+
+// About search the persons of a tenant by the name, accent insensitive and fuzzy, the
+// most relevant first. The plaintext names are searched by the name_search tsvector and
+// the pg_trgm word similarity; the encrypted names by the blind indexes of their trigrams,
+// the share of the query trigrams a name has is its score.
+// One more than the limit is read, so the caller knows whether there is a next page.
+func (w WorkerRepository) SearchPerson(ctx context.Context, tenantID string, query string, limit int, offset int) (_ []model.PersonMatch, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","SearchPerson").Int("limit", limit).Int("offset", offset).Send()
+
+	ctx, span := tracing.Start(ctx, "database.SearchPerson", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	return w.searchPerson(ctx, conn, tenantID, query, limit, offset)
+}
+
+// About search the persons of a tenant by the name in a transaction, it takes no other
+// connection of the pool
+func (w WorkerRepository) SearchPersonTx(ctx context.Context, tx pgx.Tx, tenantID string, query string, limit int, offset int) (_ []model.PersonMatch, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","SearchPersonTx").Int("limit", limit).Int("offset", offset).Send()
+
+	ctx, span := tracing.Start(ctx, "database.SearchPersonTx", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	return w.searchPerson(ctx, tx, tenantID, query, limit, offset)
+}
+
+func (w WorkerRepository) searchPerson(ctx context.Context, q querier, tenantID string, query string, limit int, offset int) ([]model.PersonMatch, error) {
+	sql := `WITH q AS (SELECT public.f_unaccent(lower($2)) AS term,
+							plainto_tsquery('simple', public.f_unaccent(lower($2))) AS tsq)
+			SELECT p.id,
+					p.person_id,
+					p.name,
+					p.person_type,
+					COALESCE(to_char(p.birth_date, 'YYYY-MM-DD'), ''),
+					COALESCE(p.email, ''),
+					COALESCE(p.phone, ''),
+					COALESCE(p.nationality, ''),
+					COALESCE(p.tax_id, ''),
+					p.created_at,
+					p.updated_at,
+					COALESCE(p.tenant_id, ''),
+					(ts_rank(p.name_search, q.tsq) + word_similarity(q.term, public.f_unaccent(lower(p.name))))::float8 AS score
+				FROM public.person p, q
+				WHERE COALESCE(p.tenant_id, '') = $1
+					AND p.erased_at IS NULL
+					AND (p.name_search @@ q.tsq OR public.f_unaccent(lower(p.name)) %> q.term)
+				ORDER BY score desc, p.person_id asc
+				LIMIT $3 OFFSET $4`
+	args := []any{tenantID, query, limit + 1, offset}
+
+	if w.Keyring != nil {
+		// the rows not encrypted yet have no trigram index, they are found once re-encrypted
+		sql = `SELECT id,
+					person_id,
+					name,
+					person_type,
+					COALESCE(to_char(birth_date, 'YYYY-MM-DD'), ''),
+					COALESCE(email, ''),
+					COALESCE(phone, ''),
+					COALESCE(nationality, ''),
+					COALESCE(tax_id, ''),
+					created_at,
+					updated_at,
+					COALESCE(tenant_id, ''),
+					score
+				FROM (SELECT p.*,
+							cardinality(ARRAY(SELECT unnest(p.name_trgm_bidx) INTERSECT SELECT unnest($2::text[])))::float8 / $5 AS score
+						FROM public.person p
+						WHERE COALESCE(p.tenant_id, '') = $1
+							AND p.erased_at IS NULL
+							AND p.name_trgm_bidx && $2::text[]) p
+				WHERE score >= $6
+				ORDER BY score desc, cardinality(name_trgm_bidx) asc, person_id asc
+				LIMIT $3 OFFSET $4`
+		trigrams := w.nameTrigramIndexes(query)
+		args = []any{tenantID, trigrams, limit + 1, offset, len(trigrams), searchThreshold}
+	}
+
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_match_list := []model.PersonMatch{}
+	for rows.Next() {
+		res_person := model.Person{}
+		res_match := model.PersonMatch{Person: &res_person}
+
+		err := rows.Scan( 	&res_person.ID,
+							&res_person.PersonID,
+							&res_person.Name,
+							&res_person.PersonType,
+							&res_person.BirthDate,
+							&res_person.Email,
+							&res_person.Phone,
+							&res_person.Nationality,
+							&res_person.TaxID,
+							&res_person.CreatedAt,
+							&res_person.UpdatedAt,
+							&res_person.TenantID,
+							&res_match.Score,
+						)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.openPerson(ctx, &res_person); err != nil {
+			return nil, err
+		}
+		res_match_list = append(res_match_list, res_match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res_match_list, nil
+}
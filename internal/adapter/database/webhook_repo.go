@@ -0,0 +1,489 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The status of a webhook delivery
+const (
+	WebhookPending		= "pending"
+	WebhookDelivered	= "delivered"
+	WebhookDead			= "dead"
+)
+
+// the field the secrets of the subscriptions are encrypted as
+const fieldWebhookSecret = "webhook.secret"
+
+// About add a webhook subscription, the secret is encrypted by the keyring
+func (w WorkerRepository) AddWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddWebhookSubscription").Send()
+
+	ctx, span := tracing.Start(ctx, "database.AddWebhookSubscription", tracing.PersonAttributes(subscription.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	secret, err := w.Keyring.Encrypt(fieldWebhookSecret, subscription.Secret)
+	if err != nil {
+		return err
+	}
+	subscription.CreatedAt = time.Now()
+
+	query := `INSERT INTO public.webhook_subscription (	tenant_id,
+														url,
+														events,
+														secret,
+														description,
+														is_active,
+														created_at)
+				VALUES(NULLIF($1,''), $2, $3, $4, NULLIF($5,''), $6, $7) RETURNING id`
+
+	return w.DatabasePGServer.GetConnection().QueryRow(ctx, query,	subscription.TenantID,
+																	subscription.URL,
+																	subscription.Events,
+																	secret,
+																	subscription.Description,
+																	subscription.IsActive,
+																	subscription.CreatedAt).Scan(&subscription.ID)
+}
+
+// the columns of a subscription, the secret is not read back
+const webhookSubscriptionColumns = `id,
+					COALESCE(tenant_id, ''),
+					url,
+					events,
+					COALESCE(description, ''),
+					is_active,
+					created_at,
+					updated_at`
+
+func scanWebhookSubscription(row pgx.Row) (*model.WebhookSubscription, error) {
+	subscription := model.WebhookSubscription{}
+	err := row.Scan(&subscription.ID,
+					&subscription.TenantID,
+					&subscription.URL,
+					&subscription.Events,
+					&subscription.Description,
+					&subscription.IsActive,
+					&subscription.CreatedAt,
+					&subscription.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// About list the webhook subscriptions of a tenant
+func (w WorkerRepository) ListWebhookSubscription(ctx context.Context, tenantID string) (_ []model.WebhookSubscription, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListWebhookSubscription").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListWebhookSubscription", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT ` + webhookSubscriptionColumns + `
+				FROM public.webhook_subscription
+				WHERE COALESCE(tenant_id, '') = $1
+				ORDER BY id asc`
+
+	rows, err := conn.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_subscription_list := []model.WebhookSubscription{}
+	for rows.Next() {
+		res_subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		res_subscription_list = append(res_subscription_list, *res_subscription)
+	}
+	return res_subscription_list, rows.Err()
+}
+
+// About get a webhook subscription of a tenant
+func (w WorkerRepository) GetWebhookSubscription(ctx context.Context, tenantID string, id int64) (_ *model.WebhookSubscription, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetWebhookSubscription").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetWebhookSubscription", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT ` + webhookSubscriptionColumns + `
+				FROM public.webhook_subscription
+				WHERE id = $1
+					AND COALESCE(tenant_id, '') = $2`
+
+	res, err := scanWebhookSubscription(conn.QueryRow(ctx, query, id, tenantID))
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	return res, err
+}
+
+// About update the endpoint, the events and the state of a webhook subscription of
+// its tenant, the tenant and the secret are kept
+func (w WorkerRepository) UpdateWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) (_ *model.WebhookSubscription, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateWebhookSubscription").Int64("id", subscription.ID).Send()
+
+	ctx, span := tracing.Start(ctx, "database.UpdateWebhookSubscription", tracing.PersonAttributes(subscription.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE public.webhook_subscription
+				SET url = $2,
+					events = $3,
+					description = NULLIF($4,''),
+					is_active = $5,
+					updated_at = $6
+				WHERE id = $1
+					AND COALESCE(tenant_id, '') = $7
+				RETURNING ` + webhookSubscriptionColumns
+
+	res, err := scanWebhookSubscription(w.DatabasePGServer.GetConnection().QueryRow(ctx, query,	subscription.ID,
+																									subscription.URL,
+																									subscription.Events,
+																									subscription.Description,
+																									subscription.IsActive,
+																									time.Now(),
+																									subscription.TenantID))
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	return res, err
+}
+
+// About delete a webhook subscription of a tenant with its deliveries
+func (w WorkerRepository) DeleteWebhookSubscription(ctx context.Context, tenantID string, id int64) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteWebhookSubscription").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.DeleteWebhookSubscription", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `DELETE FROM public.webhook_subscription WHERE id = $1 AND COALESCE(tenant_id, '') = $2`
+
+	res, err := w.DatabasePGServer.GetConnection().Exec(ctx, query, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return erro.ErrNotFound
+	}
+	return nil
+}
+
+// About add the deliveries of a event to the active subscriptions of the tenant in the
+// transaction of the change, so a event is delivered if and only if the change is
+// committed. A event without tenant is of the tenant of the person. Returns the
+// deliveries added.
+func (w WorkerRepository) AddWebhookEvent(ctx context.Context, tx pgx.Tx, event *model.WebhookEvent) (_ int64, err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","AddWebhookEvent").Str("event", event.Event).Send()
+
+	query := `WITH event AS (
+					SELECT COALESCE(NULLIF($1,''),
+						(SELECT COALESCE(tenant_id, '') FROM public.person WHERE person_id = $2), '') AS tenant_id
+				)
+				INSERT INTO public.webhook_delivery (	subscription_id,
+														tenant_id,
+														event_id,
+														event,
+														person_id,
+														document_id,
+														occurred_at,
+														status,
+														next_attempt_at,
+														created_at)
+				SELECT s.id, NULLIF(e.tenant_id, ''), $3, $4, $2, NULLIF($5, 0), $6, $7, $6, $6
+					FROM public.webhook_subscription s, event e
+					WHERE COALESCE(s.tenant_id, '') = e.tenant_id
+						AND s.is_active
+						AND ($4 = ANY(s.events) OR '*' = ANY(s.events))`
+
+	res, err := tx.Exec(ctx, query,	event.TenantID,
+									event.PersonID,
+									event.ID,
+									event.Event,
+									event.DocumentID,
+									event.OccurredAt,
+									WebhookPending)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// the columns of a delivery, with the event
+const webhookDeliveryColumns = `d.id,
+					d.subscription_id,
+					d.event_id,
+					d.event,
+					COALESCE(d.tenant_id, ''),
+					d.person_id,
+					COALESCE(d.document_id, 0),
+					d.occurred_at,
+					d.status,
+					d.attempts,
+					d.next_attempt_at,
+					COALESCE(d.last_status_code, 0),
+					COALESCE(d.last_error, ''),
+					d.created_at,
+					d.delivered_at`
+
+func scanWebhookDelivery(row pgx.Row, extra ...any) (*model.WebhookDelivery, error) {
+	delivery := model.WebhookDelivery{}
+	err := row.Scan(append([]any{	&delivery.ID,
+									&delivery.SubscriptionID,
+									&delivery.Event.ID,
+									&delivery.Event.Event,
+									&delivery.Event.TenantID,
+									&delivery.Event.PersonID,
+									&delivery.Event.DocumentID,
+									&delivery.Event.OccurredAt,
+									&delivery.Status,
+									&delivery.Attempts,
+									&delivery.NextAttemptAt,
+									&delivery.LastStatusCode,
+									&delivery.LastError,
+									&delivery.CreatedAt,
+									&delivery.DeliveredAt,
+	}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// About claim the pending deliveries due of the active subscriptions, oldest first.
+// They are leased: the other replicas skip them (SKIP LOCKED) and they are due again
+// when the lease is over, so a delivery of a replica gone is sent again.
+func (w WorkerRepository) ClaimWebhookDelivery(ctx context.Context, batch int, lease time.Duration) (_ []model.WebhookDelivery, err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","ClaimWebhookDelivery").Send()
+
+	ctx, span := tracing.Start(ctx, "database.ClaimWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+
+	query := `UPDATE public.webhook_delivery d
+				SET next_attempt_at = $2
+				FROM public.webhook_subscription s
+				WHERE s.id = d.subscription_id
+					AND d.id IN (SELECT q.id
+									FROM public.webhook_delivery q
+									JOIN public.webhook_subscription qs ON qs.id = q.subscription_id AND qs.is_active
+									WHERE q.status = $3
+										AND q.next_attempt_at <= $1
+									ORDER BY q.next_attempt_at asc
+									LIMIT $4
+									FOR UPDATE OF q SKIP LOCKED)
+				RETURNING ` + webhookDeliveryColumns + `, s.url, s.secret`
+
+	rows, err := w.DatabasePGServer.GetConnection().Query(ctx, query, now, now.Add(lease), WebhookPending, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_delivery_list := []model.WebhookDelivery{}
+	for rows.Next() {
+		var url, secret string
+		res_delivery, err := scanWebhookDelivery(rows, &url, &secret)
+		if err != nil {
+			return nil, err
+		}
+		res_delivery.URL = url
+		res_delivery.Secret = secret
+		res_delivery_list = append(res_delivery_list, *res_delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range res_delivery_list {
+		if res_delivery_list[i].Secret, err = w.Keyring.Decrypt(ctx, fieldWebhookSecret, res_delivery_list[i].Secret); err != nil {
+			return nil, err
+		}
+	}
+	return res_delivery_list, nil
+}
+
+// About record a attempt of a delivery and its outcome: the status, the attempts,
+// the next attempt and the last status code and error of the delivery
+func (w WorkerRepository) AddWebhookAttempt(ctx context.Context, tx pgx.Tx, delivery *model.WebhookDelivery, attempt *model.WebhookAttempt) (err error){
+	logging.Ctx(ctx, childLogger).Debug().Str("func","AddWebhookAttempt").Int64("id", delivery.ID).Send()
+
+	query := `INSERT INTO public.webhook_attempt (	delivery_id,
+													attempt,
+													status_code,
+													error,
+													duration_ms,
+													attempted_at)
+				VALUES($1, $2, NULLIF($3, 0), NULLIF($4,''), $5, $6)`
+
+	_, err = tx.Exec(ctx, query,	delivery.ID,
+									attempt.Attempt,
+									attempt.StatusCode,
+									attempt.Error,
+									attempt.DurationMs,
+									attempt.AttemptedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE public.webhook_delivery
+				SET status = $2,
+					attempts = $3,
+					next_attempt_at = COALESCE($4, next_attempt_at),
+					last_status_code = NULLIF($5, 0),
+					last_error = NULLIF($6,''),
+					delivered_at = $7
+				WHERE id = $1`
+
+	_, err = tx.Exec(ctx, query,	delivery.ID,
+									delivery.Status,
+									delivery.Attempts,
+									delivery.NextAttemptAt,
+									delivery.LastStatusCode,
+									delivery.LastError,
+									delivery.DeliveredAt)
+	return err
+}
+
+// About list the deliveries of a subscription of a tenant, newest first, of a status
+// when given. One more than the limit is read to tell a next page.
+func (w WorkerRepository) ListWebhookDelivery(ctx context.Context, tenantID string, subscriptionID int64, status string, limit int, offset int) (_ []model.WebhookDelivery, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListWebhookDelivery").Int64("subscription_id", subscriptionID).Send()
+
+	ctx, span := tracing.Start(ctx, "database.ListWebhookDelivery", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT ` + webhookDeliveryColumns + `
+				FROM public.webhook_delivery d
+				WHERE d.subscription_id = $1
+					AND COALESCE(d.tenant_id, '') = $5
+					AND ($2 = '' OR d.status = $2)
+				ORDER BY d.created_at desc, d.id desc
+				LIMIT $3 OFFSET $4`
+
+	rows, err := conn.Query(ctx, query, subscriptionID, status, limit + 1, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_delivery_list := []model.WebhookDelivery{}
+	for rows.Next() {
+		res_delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		res_delivery_list = append(res_delivery_list, *res_delivery)
+	}
+	return res_delivery_list, rows.Err()
+}
+
+// About get a delivery of a subscription of a tenant with the log of its attempts
+func (w WorkerRepository) GetWebhookDelivery(ctx context.Context, tenantID string, subscriptionID int64, id int64) (_ *model.WebhookDelivery, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetWebhookDelivery").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.GetWebhookDelivery", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	conn, err := w.acquireRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.DatabasePGServer.Release(conn)
+
+	query := `SELECT ` + webhookDeliveryColumns + `
+				FROM public.webhook_delivery d
+				WHERE d.subscription_id = $1
+					AND d.id = $2
+					AND COALESCE(d.tenant_id, '') = $3`
+
+	res_delivery, err := scanWebhookDelivery(conn.QueryRow(ctx, query, subscriptionID, id, tenantID))
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `SELECT attempt,
+					COALESCE(status_code, 0),
+					COALESCE(error, ''),
+					duration_ms,
+					attempted_at
+				FROM public.webhook_attempt
+				WHERE delivery_id = $1
+				ORDER BY attempted_at asc, id asc`
+
+	rows, err := conn.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res_delivery.AttemptLog = []model.WebhookAttempt{}
+	for rows.Next() {
+		res_attempt := model.WebhookAttempt{}
+		err := rows.Scan(	&res_attempt.Attempt,
+							&res_attempt.StatusCode,
+							&res_attempt.Error,
+							&res_attempt.DurationMs,
+							&res_attempt.AttemptedAt)
+		if err != nil {
+			return nil, err
+		}
+		res_delivery.AttemptLog = append(res_delivery.AttemptLog, res_attempt)
+	}
+	return res_delivery, rows.Err()
+}
+
+// About send a delivery of a tenant again now, with all its attempts: a dead one is
+// taken out of the dead letter, a delivered one is sent once more
+func (w WorkerRepository) RetryWebhookDelivery(ctx context.Context, tenantID string, subscriptionID int64, id int64) (_ *model.WebhookDelivery, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","RetryWebhookDelivery").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "database.RetryWebhookDelivery", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE public.webhook_delivery d
+				SET status = $3,
+					attempts = 0,
+					next_attempt_at = $4,
+					delivered_at = NULL
+				WHERE d.subscription_id = $1
+					AND d.id = $2
+					AND COALESCE(d.tenant_id, '') = $5
+				RETURNING ` + webhookDeliveryColumns
+
+	res, err := scanWebhookDelivery(w.DatabasePGServer.GetConnection().QueryRow(ctx, query, subscriptionID, id, WebhookPending, time.Now(), tenantID))
+	if err == pgx.ErrNoRows {
+		return nil, erro.ErrNotFound
+	}
+	return res, err
+}
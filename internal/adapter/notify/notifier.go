@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"io"
+	"fmt"
+	"net"
+	"time"
+	"bytes"
+	"context"
+	"syscall"
+	"net/http"
+	"net/netip"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/internal/core/webhook"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.adapter.notify").Logger()
+
+// the response bodies are drained up to it, so the connection is reused. They are
+// never kept, a endpoint could echo what it should not in them.
+const maxDrainBody = 4096
+
+// Notifier posts the signed webhook deliveries to the endpoints of the subscriptions
+type Notifier struct {
+	client	*http.Client
+}
+
+// About create a notifier, each delivery must be answered within the timeout.
+// The redirects are not followed, the signature is bound to the endpoint subscribed.
+// The connections to the private addresses are refused unless allowPrivate (the local
+// receivers of the development), no proxy is used so the address checked is the one reached.
+func NewNotifier(timeout time.Duration, allowPrivate bool) *Notifier {
+	childLogger.Info().Str("func","NewNotifier").Str("timeout", timeout.String()).Bool("allow_private", allowPrivate).Send()
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = publicOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Notifier{
+		client: &http.Client{	Timeout: timeout,
+								Transport: transport,
+								CheckRedirect: func(req *http.Request, via []*http.Request) error {
+									return http.ErrUseLastResponse
+								},
+		},
+	}
+}
+
+// About refuse a connection to a address not public. It is checked on the address
+// dialed, after the resolution, so a host resolving to another address since it was
+// subscribed (DNS rebinding) is refused too.
+func publicOnly(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !webhook.PublicAddress(addr) {
+		return fmt.Errorf("%w: %s", webhook.ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+// About post a delivery signed with the secret, a status other than 2xx is a error.
+// The status code is returned when there is a response.
+func (n *Notifier) Post(ctx context.Context, url string, secret string, deliveryID string, event string, body []byte) (_ int, err error) {
+	logging.Ctx(ctx, childLogger).Debug().Str("func","Post").Str("delivery_id", deliveryID).Str("event", event).Send()
+
+	ctx, span := tracing.Start(ctx, "adapter.notify.Post")
+	defer func() { tracing.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-onboarding-webhook")
+	req.Header.Set(webhook.HeaderEvent, event)
+	req.Header.Set(webhook.HeaderDelivery, deliveryID)
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, time.Now(), body))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainBody))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"time"
+	"errors"
+	"context"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-onboarding/internal/core/webhook"
+)
+
+// the endpoints on private addresses are refused when the connection is dialed
+func TestPostRefusesPrivateAddress(t *testing.T) {
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = true
+	}))
+	defer receiver.Close()
+
+	notifier := NewNotifier(time.Second, false)
+	_, err := notifier.Post(context.Background(), receiver.URL, "whsec_test", "1", webhook.EventPersonCreated, []byte(`{}`))
+	if !errors.Is(err, webhook.ErrPrivateAddress) {
+		t.Fatalf("post to %s: %v, want %v", receiver.URL, err, webhook.ErrPrivateAddress)
+	}
+	if received {
+		t.Errorf("the private endpoint was reached")
+	}
+}
+
+// the error of a failed delivery has the status only, not the body answered
+func TestPostErrorWithoutBody(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+		rw.Write([]byte("internal details"))
+	}))
+	defer receiver.Close()
+
+	notifier := NewNotifier(time.Second, true)
+	statusCode, err := notifier.Post(context.Background(), receiver.URL, "whsec_test", "1", webhook.EventPersonCreated, []byte(`{}`))
+	if statusCode != http.StatusBadGateway || err == nil || err.Error() != "endpoint answered 502" {
+		t.Errorf("post: status %d error %v", statusCode, err)
+	}
+}
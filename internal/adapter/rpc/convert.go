@@ -0,0 +1,145 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/go-onboarding/internal/core/model"
+	onboardingv1 "github.com/go-onboarding/proto/onboarding/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// About a optional timestamp, nil when it is not set
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromPerson(person *model.Person) *onboardingv1.Person {
+	if person == nil {
+		return nil
+	}
+	res := &onboardingv1.Person{	PersonId: person.PersonID,
+									Name: person.Name,
+									PersonType: person.PersonType,
+									BirthDate: person.BirthDate,
+									Email: person.Email,
+									Phone: person.Phone,
+									Nationality: person.Nationality,
+									TaxId: person.TaxID,
+									CreatedAt: toTimestamp(&person.CreatedAt),
+									UpdatedAt: toTimestamp(person.UpdatedAt),
+									TenantId: person.TenantID,
+	}
+	for i := range person.Addresses {
+		res.Addresses = append(res.Addresses, fromAddress(&person.Addresses[i]))
+	}
+	for i := range person.Documents {
+		res.Documents = append(res.Documents, fromDocument(&person.Documents[i]))
+	}
+	return res
+}
+
+// About the person of a request, the timestamps are set by the service
+func toPerson(person *onboardingv1.Person) *model.Person {
+	res := &model.Person{	PersonID: person.GetPersonId(),
+							Name: person.GetName(),
+							PersonType: person.GetPersonType(),
+							BirthDate: person.GetBirthDate(),
+							Email: person.GetEmail(),
+							Phone: person.GetPhone(),
+							Nationality: person.GetNationality(),
+							TaxID: person.GetTaxId(),
+							TenantID: person.GetTenantId(),
+	}
+	for _, address := range person.GetAddresses() {
+		res.Addresses = append(res.Addresses, toAddress(address))
+	}
+	for _, document := range person.GetDocuments() {
+		res.Documents = append(res.Documents, *toDocument(document))
+	}
+	return res
+}
+
+func fromAddress(address *model.Address) *onboardingv1.Address {
+	return &onboardingv1.Address{	Id: int64(address.ID),
+									Type: address.Type,
+									Street: address.Street,
+									Number: address.Number,
+									Complement: address.Complement,
+									District: address.District,
+									City: address.City,
+									State: address.State,
+									PostalCode: address.PostalCode,
+									Country: address.Country,
+									IsPrimary: address.IsPrimary,
+									CreatedAt: toTimestamp(&address.CreatedAt),
+									UpdatedAt: toTimestamp(address.UpdatedAt),
+	}
+}
+
+func toAddress(address *onboardingv1.Address) model.Address {
+	return model.Address{	ID: int(address.GetId()),
+							Type: address.GetType(),
+							Street: address.GetStreet(),
+							Number: address.GetNumber(),
+							Complement: address.GetComplement(),
+							District: address.GetDistrict(),
+							City: address.GetCity(),
+							State: address.GetState(),
+							PostalCode: address.GetPostalCode(),
+							Country: address.GetCountry(),
+							IsPrimary: address.GetIsPrimary(),
+	}
+}
+
+func fromDocument(document *model.Document) *onboardingv1.Document {
+	return &onboardingv1.Document{	Id: int64(document.ID),
+									Type: document.Type,
+									Number: document.Number,
+									IssuingCountry: document.IssuingCountry,
+									IssuingAuthority: document.IssuingAuthority,
+									IssueDate: document.IssueDate,
+									ExpiryDate: document.ExpiryDate,
+									CreatedAt: toTimestamp(&document.CreatedAt),
+									UpdatedAt: toTimestamp(document.UpdatedAt),
+	}
+}
+
+func toDocument(document *onboardingv1.Document) *model.Document {
+	return &model.Document{	ID: int(document.GetId()),
+							Type: document.GetType(),
+							Number: document.GetNumber(),
+							IssuingCountry: document.GetIssuingCountry(),
+							IssuingAuthority: document.GetIssuingAuthority(),
+							IssueDate: document.GetIssueDate(),
+							ExpiryDate: document.GetExpiryDate(),
+	}
+}
+
+func fromDuplicate(duplicate *model.PersonDuplicate) *onboardingv1.PersonDuplicate {
+	return &onboardingv1.PersonDuplicate{	Candidate: fromPerson(duplicate.Candidate),
+											Score: duplicate.Score,
+											Reasons: duplicate.Reasons,
+	}
+}
+
+func fromPersonMatch(match *model.PersonMatch) *onboardingv1.PersonMatch {
+	res := &onboardingv1.PersonMatch{	Person: fromPerson(match.Person),
+										Score: match.Score,
+	}
+	for _, span := range match.Highlight {
+		res.Highlight = append(res.Highlight, &onboardingv1.Span{Start: int32(span.Start), End: int32(span.End)})
+	}
+	return res
+}
+
+func fromTombstone(tombstone *model.Tombstone) *onboardingv1.Tombstone {
+	return &onboardingv1.Tombstone{	PersonId: tombstone.PersonID,
+									TenantId: tombstone.TenantID,
+									ErasedAt: toTimestamp(&tombstone.ErasedAt),
+									ObjectsDeleted: int32(tombstone.ObjectsDeleted),
+	}
+}
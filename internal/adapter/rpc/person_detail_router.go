@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"time"
+	"context"
+
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+	onboardingv1 "github.com/go-onboarding/proto/onboarding/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// About stream the identity documents of a person
+func (h *GrpcRouters) ListDocument(req *onboardingv1.ListDocumentRequest, stream grpc.ServerStreamingServer[onboardingv1.Document]) (err error) {
+	logging.Ctx(stream.Context(), childLogger).Info().Str("func","ListDocument").Send()
+
+	ctx, cancel := context.WithTimeout(stream.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.ListDocument")
+	defer func() { tracing.End(span, err) }()
+
+	if err = h.checkPerson(ctx, req.GetPersonId()); err != nil {
+		return h.ErrorStatus(err)
+	}
+
+	res, err := h.workerService.ListDocument(ctx, req.GetPersonId())
+	if err != nil {
+		return h.ErrorStatus(err)
+	}
+
+	for i := range res {
+		if err = stream.Send(fromDocument(&res[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// About add a identity document
+func (h *GrpcRouters) AddDocument(ctx context.Context, req *onboardingv1.AddDocumentRequest) (_ *onboardingv1.Document, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddDocument").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.AddDocument")
+	defer func() { tracing.End(span, err) }()
+
+	if err = h.checkPerson(ctx, req.GetPersonId()); err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	res, err := h.workerService.AddDocument(ctx, req.GetPersonId(), toDocument(req.GetDocument()))
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	return fromDocument(res), nil
+}
+
+// About update a identity document, by the id of the document
+func (h *GrpcRouters) UpdateDocument(ctx context.Context, req *onboardingv1.UpdateDocumentRequest) (_ *onboardingv1.Document, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateDocument").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.UpdateDocument")
+	defer func() { tracing.End(span, err) }()
+
+	if err = h.checkPerson(ctx, req.GetPersonId()); err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	res, err := h.workerService.UpdateDocument(ctx, req.GetPersonId(), toDocument(req.GetDocument()))
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	return fromDocument(res), nil
+}
+
+// About delete a identity document
+func (h *GrpcRouters) DeleteDocument(ctx context.Context, req *onboardingv1.DeleteDocumentRequest) (_ *emptypb.Empty, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteDocument").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.DeleteDocument")
+	defer func() { tracing.End(span, err) }()
+
+	if err = h.checkPerson(ctx, req.GetPersonId()); err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	err = h.workerService.DeleteDocument(ctx, req.GetPersonId(), int(req.GetDocumentId()))
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,339 @@
+package rpc
+
+import (
+	"time"
+	"errors"
+	"context"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/internal/core/service"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+	onboardingv1 "github.com/go-onboarding/proto/onboarding/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+var childLogger = log.With().Str("component", "go-onboarding").Str("package", "internal.adapter.rpc").Logger()
+
+// The person and document operations of the WorkerService over grpc, the same as the http routes
+type GrpcRouters struct {
+	onboardingv1.UnimplementedOnboardingServiceServer
+	workerService 	*service.WorkerService
+	ctxTimeout		time.Duration
+}
+
+// Above create the grpc routers
+func NewGrpcRouters(workerService *service.WorkerService,
+					ctxTimeout	time.Duration) GrpcRouters {
+	childLogger.Info().Str("func","NewGrpcRouters").Send()
+
+	return GrpcRouters{
+		workerService: workerService,
+		ctxTimeout: ctxTimeout,
+	}
+}
+
+// About map a error to a grpc status, as the ErrorHandler of the http routes
+func (h *GrpcRouters) ErrorStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if strings.Contains(err.Error(), "context deadline exceeded") {
+		err = erro.ErrTimeout
+	}
+	// the validation errors keep the invalid fields in the message
+	if errors.Is(err, erro.ErrInvalid) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var duplicate *model.DuplicateError
+	if errors.As(err, &duplicate) {
+		// the candidates are returned with the conflict, to be reviewed or merged
+		details := []protoadapt.MessageV1{}
+		for i := range duplicate.Candidates {
+			details = append(details, fromDuplicate(&duplicate.Candidates[i]))
+		}
+		st, errDetails := status.New(codes.AlreadyExists, err.Error()).WithDetails(details...)
+		if errDetails != nil {
+			return status.Error(codes.AlreadyExists, err.Error())
+		}
+		return st.Err()
+	}
+
+	switch err {
+	case erro.ErrBadRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case erro.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case erro.ErrTimeout:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case erro.ErrDuplicateTaxID, erro.ErrDuplicatePerson:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case erro.ErrEncryptionDisabled, erro.ErrWebhookDisabled, erro.ErrJobDisabled:
+		return status.Error(codes.Unimplemented, err.Error())
+	case erro.ErrJobFinished:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case erro.ErrUnauthorized:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case erro.ErrHTTPForbiden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case erro.ErrTooManyRequests:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case erro.ErrOverloaded:
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// About the tenant given by the caller in the x-tenant-id metadata, empty when the
+// caller may reach all the tenants
+func callerTenant(ctx context.Context) string {
+	if correlation := logging.CorrelationFrom(ctx); correlation != nil {
+		return correlation.Tenant
+	}
+	return ""
+}
+
+// About the tenant of a request: a caller bound to a tenant may not ask for another one
+func (h *GrpcRouters) tenant(ctx context.Context, tenantID string) (string, error) {
+	caller := callerTenant(ctx)
+	if caller == "" {
+		logging.SetTenant(ctx, tenantID)
+		return tenantID, nil
+	}
+	if tenantID != "" && tenantID != caller {
+		return "", status.Error(codes.PermissionDenied, "tenant not allowed for the caller")
+	}
+	return caller, nil
+}
+
+// About check a person belongs to the tenant of the caller, the persons of the
+// other tenants are not found
+func owned(ctx context.Context, tenantID string) error {
+	if caller := callerTenant(ctx); caller != "" && caller != tenantID {
+		return erro.ErrNotFound
+	}
+	return nil
+}
+
+// About get a person of the tenant of the caller
+func (h *GrpcRouters) getPerson(ctx context.Context, personID string) (*model.Person, error) {
+	res, err := h.workerService.GetPerson(ctx, &model.Onboarding{Person: &model.Person{PersonID: personID}})
+	if err != nil {
+		return nil, err
+	}
+	if err := owned(ctx, res.Person.TenantID); err != nil {
+		return nil, err
+	}
+	return res.Person, nil
+}
+
+// About check the person of a request belongs to the tenant of the caller
+func (h *GrpcRouters) checkPerson(ctx context.Context, personID string) error {
+	if callerTenant(ctx) == "" {
+		return nil
+	}
+	_, err := h.getPerson(ctx, personID)
+	return err
+}
+
+// About add person
+func (h *GrpcRouters) AddPerson(ctx context.Context, req *onboardingv1.AddPersonRequest) (_ *onboardingv1.AddPersonResponse, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddPerson").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.AddPerson")
+	defer func() { tracing.End(span, err) }()
+
+	if req.GetPerson() == nil {
+		return nil, h.ErrorStatus(erro.ErrBadRequest)
+	}
+	person := toPerson(req.GetPerson())
+	if person.TenantID, err = h.tenant(ctx, person.TenantID); err != nil {
+		return nil, err
+	}
+
+	res, err := h.workerService.AddPerson(ctx, &model.Onboarding{Person: person})
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	response := &onboardingv1.AddPersonResponse{Person: fromPerson(res.Person)}
+	for i := range res.Duplicates {
+		response.Duplicates = append(response.Duplicates, fromDuplicate(&res.Duplicates[i]))
+	}
+	return response, nil
+}
+
+// About get person
+func (h *GrpcRouters) GetPerson(ctx context.Context, req *onboardingv1.GetPersonRequest) (_ *onboardingv1.Person, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetPerson").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.GetPerson")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := h.getPerson(ctx, req.GetPersonId())
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	return fromPerson(res), nil
+}
+
+// About update person
+func (h *GrpcRouters) UpdatePerson(ctx context.Context, req *onboardingv1.UpdatePersonRequest) (_ *onboardingv1.Person, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdatePerson").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.UpdatePerson")
+	defer func() { tracing.End(span, err) }()
+
+	if req.GetPerson() == nil {
+		return nil, h.ErrorStatus(erro.ErrBadRequest)
+	}
+	person := toPerson(req.GetPerson())
+	if person.TenantID, err = h.tenant(ctx, person.TenantID); err != nil {
+		return nil, err
+	}
+	if err = h.checkPerson(ctx, person.PersonID); err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	res, err := h.workerService.UpdatePerson(ctx, &model.Onboarding{Person: person})
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	return fromPerson(res.Person), nil
+}
+
+// About stream the persons from a person_id on
+func (h *GrpcRouters) ListPerson(req *onboardingv1.ListPersonRequest, stream grpc.ServerStreamingServer[onboardingv1.Person]) (err error) {
+	logging.Ctx(stream.Context(), childLogger).Info().Str("func","ListPerson").Send()
+
+	ctx, cancel := context.WithTimeout(stream.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.ListPerson")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := h.workerService.ListPerson(ctx, &model.Onboarding{Person: &model.Person{PersonID: req.GetFromPersonId()}})
+	if err != nil {
+		return h.ErrorStatus(err)
+	}
+
+	for _, onboarding := range *res {
+		if owned(ctx, onboarding.Person.TenantID) != nil {
+			continue
+		}
+		if err = stream.Send(fromPerson(onboarding.Person)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// About stream the persons of a tenant by the exact tax_id or email
+func (h *GrpcRouters) LookupPerson(req *onboardingv1.LookupPersonRequest, stream grpc.ServerStreamingServer[onboardingv1.Person]) (err error) {
+	logging.Ctx(stream.Context(), childLogger).Info().Str("func","LookupPerson").Send()
+
+	ctx, cancel := context.WithTimeout(stream.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.LookupPerson")
+	defer func() { tracing.End(span, err) }()
+
+	tenantID, err := h.tenant(ctx, req.GetTenantId())
+	if err != nil {
+		return err
+	}
+
+	res, err := h.workerService.LookupPerson(ctx, &model.Onboarding{Person: &model.Person{	TenantID: tenantID,
+																							TaxID: req.GetTaxId(),
+																							Email: req.GetEmail(),
+	}})
+	if err != nil {
+		return h.ErrorStatus(err)
+	}
+
+	for _, onboarding := range *res {
+		if err = stream.Send(fromPerson(onboarding.Person)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// About stream the persons of a tenant by the name, the most relevant first
+func (h *GrpcRouters) SearchPerson(req *onboardingv1.SearchPersonRequest, stream grpc.ServerStreamingServer[onboardingv1.PersonMatch]) (err error) {
+	logging.Ctx(stream.Context(), childLogger).Info().Str("func","SearchPerson").Send()
+
+	ctx, cancel := context.WithTimeout(stream.Context(), h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.SearchPerson")
+	defer func() { tracing.End(span, err) }()
+
+	tenantID, err := h.tenant(ctx, req.GetTenantId())
+	if err != nil {
+		return err
+	}
+
+	res, err := h.workerService.SearchPerson(ctx, &model.PersonSearch{	Query: req.GetQuery(),
+																		TenantID: tenantID,
+																		Limit: int(req.GetLimit()),
+																		Offset: int(req.GetOffset()),
+	})
+	if err != nil {
+		return h.ErrorStatus(err)
+	}
+
+	for i := range res.Items {
+		if err = stream.Send(fromPersonMatch(&res.Items[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// About erase a person, it may be called again until it succeeds
+func (h *GrpcRouters) ErasePerson(ctx context.Context, req *onboardingv1.ErasePersonRequest) (_ *onboardingv1.Tombstone, err error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","ErasePerson").Send()
+
+	ctx, cancel := context.WithTimeout(ctx, h.ctxTimeout * time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "adapter.rpc.ErasePerson")
+	defer func() { tracing.End(span, err) }()
+
+	// a person erased already is not found, its tombstone tells the tenant
+	if err = h.checkPerson(ctx, req.GetPersonId()); err != nil && !errors.Is(err, erro.ErrNotFound) {
+		return nil, h.ErrorStatus(err)
+	}
+
+	res, err := h.workerService.ErasePerson(ctx, req.GetPersonId())
+	if err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+	if err = owned(ctx, res.TenantID); err != nil {
+		return nil, h.ErrorStatus(err)
+	}
+
+	return fromTombstone(res), nil
+}
@@ -0,0 +1,111 @@
+package dedup
+
+import(
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/search"
+	"github.com/go-onboarding/internal/core/validation"
+)
+
+// The signals two persons are compared by, they are the reasons of a match
+const (
+	ReasonTaxID		= "tax_id"
+	ReasonDocument	= "document"
+	ReasonName		= "name"
+	ReasonBirthDate	= "birth_date"
+	ReasonEmail		= "email"
+	ReasonPhone		= "phone"
+)
+
+// The weight of each signal, a same tax id or identity document is the same person.
+// The name alone is not enough to be reviewed, the name, the birth date and a
+// contact are enough to be refused.
+const (
+	weightName		= 0.50
+	weightBirthDate	= 0.25
+	weightEmail		= 0.15
+	weightPhone		= 0.10
+)
+
+// The names are similar from this trigram similarity
+const nameSimilarity = 0.5
+
+// Policy of the onboarding: a person scoring at least MatchThreshold against
+// a candidate is refused as a duplicate, at least ReviewThreshold is onboarded
+// and flagged for review. The zero policy disables the deduplication.
+type Policy struct {
+	MatchThreshold	float64
+	ReviewThreshold	float64
+}
+
+// About whether the deduplication is enabled
+func (p Policy) IsEnabled() bool {
+	return p.MatchThreshold > 0 || p.ReviewThreshold > 0
+}
+
+// About the candidates refused or flagged by the policy, the highest score first
+func (p Policy) Classify(candidates []model.PersonDuplicate) (match []model.PersonDuplicate, review []model.PersonDuplicate) {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	for _, candidate := range candidates {
+		switch {
+		case p.MatchThreshold > 0 && candidate.Score >= p.MatchThreshold:
+			match = append(match, candidate)
+		case p.ReviewThreshold > 0 && candidate.Score >= p.ReviewThreshold:
+			review = append(review, candidate)
+		}
+	}
+	return match, review
+}
+
+// About score how likely a candidate is the same person (0 to 1) and the signals matched.
+// Both persons are expected normalized by the validation.
+func Score(person *model.Person, candidate *model.Person) (float64, []string) {
+	if person.TaxID != "" && person.TaxID == candidate.TaxID {
+		return 1, []string{ReasonTaxID}
+	}
+	if sameDocument(person.Documents, candidate.Documents) {
+		return 1, []string{ReasonDocument}
+	}
+
+	score, reasons := 0.0, []string{}
+	if similarity := search.Similarity(person.Name, candidate.Name); similarity >= nameSimilarity {
+		score += weightName * similarity
+		reasons = append(reasons, ReasonName)
+	}
+	if person.BirthDate != "" && person.BirthDate == candidate.BirthDate {
+		score += weightBirthDate
+		reasons = append(reasons, ReasonBirthDate)
+	}
+	if person.Email != "" && person.Email == candidate.Email {
+		score += weightEmail
+		reasons = append(reasons, ReasonEmail)
+	}
+	if person.Phone != "" && person.Phone == candidate.Phone {
+		score += weightPhone
+		reasons = append(reasons, ReasonPhone)
+	}
+	// rounded, the sum of the weights is compared to the thresholds
+	return math.Round(score * 10000) / 10000, reasons
+}
+
+// About whether two persons share a identity document, the number compared without punctuation
+func sameDocument(documents []model.Document, candidates []model.Document) bool {
+	for _, document := range documents {
+		for _, candidate := range candidates {
+			if document.Type == candidate.Type &&
+				strings.EqualFold(document.IssuingCountry, candidate.IssuingCountry) &&
+				DocumentNumber(document.Number) == DocumentNumber(candidate.Number) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// About the form the document numbers are compared in, the formatting stripped
+func DocumentNumber(number string) string {
+	return validation.NormalizeTaxID(number)
+}
@@ -1,21 +1,29 @@
-package erro
-
-import (
-	"errors"
-)
-
-var (
-	ErrNotFound 		= errors.New("item not found")
-	ErrBadRequest 		= errors.New("bad request ! check parameters")
-	ErrCertTls 			= errors.New("cert tls is encrypted or invalid")
-	ErrInsert 			= errors.New("insert data error")
-	ErrUpdate			= errors.New("update unsuccessful")
-	ErrUpdateRows		= errors.New("update affect 0 rows")
-	ErrDelete 			= errors.New("delete data error")
-	ErrUnmarshal 		= errors.New("unmarshal json error")
-	ErrUnauthorized 	= errors.New("not authorized")
-	ErrServer		 	= errors.New("server identified error")
-	ErrHTTPForbiden		= errors.New("forbiden request")
-	ErrInvalid			= errors.New("invalid data")
-	ErrTimeout			= errors.New("timeout: context deadline exceeded.")
+package erro
+
+import (
+	"errors"
+)
+
+var (
+	ErrNotFound 		= errors.New("item not found")
+	ErrBadRequest 		= errors.New("bad request ! check parameters")
+	ErrCertTls 			= errors.New("cert tls is encrypted or invalid")
+	ErrInsert 			= errors.New("insert data error")
+	ErrUpdate			= errors.New("update unsuccessful")
+	ErrUpdateRows		= errors.New("update affect 0 rows")
+	ErrDelete 			= errors.New("delete data error")
+	ErrUnmarshal 		= errors.New("unmarshal json error")
+	ErrUnauthorized 	= errors.New("not authorized")
+	ErrServer		 	= errors.New("server identified error")
+	ErrHTTPForbiden		= errors.New("forbiden request")
+	ErrInvalid			= errors.New("invalid data")
+	ErrTimeout			= errors.New("timeout: context deadline exceeded.")
+	ErrTooManyRequests	= errors.New("too many requests, retry later")
+	ErrOverloaded		= errors.New("service overloaded, retry later")
+	ErrDuplicateTaxID	= errors.New("tax id already onboarded in the tenant")
+	ErrEncryptionDisabled	= errors.New("field encryption is not enabled")
+	ErrDuplicatePerson	= errors.New("person already onboarded, matched by the deduplication")
+	ErrWebhookDisabled	= errors.New("webhooks are not enabled")
+	ErrJobDisabled		= errors.New("asynchronous jobs are not enabled")
+	ErrJobFinished		= errors.New("job already finished")
 )
\ No newline at end of file
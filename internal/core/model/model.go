@@ -2,6 +2,9 @@ package model
 
 import (
 	"time"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/core/erro"
 	go_core_pg "github.com/eliezerraj/go-core/database/pg"
 	go_core_observ "github.com/eliezerraj/go-core/observability" 
 )
@@ -10,9 +13,24 @@ type AppServer struct {
 	InfoPod 		*InfoPod 					`json:"info_pod"`
 	Server     		*Server     				`json:"server"`
 	ConfigOTEL		*go_core_observ.ConfigOTEL	`json:"otel_config"`
+	TraceConfig		*TraceConfig				`json:"trace_config"`
 	DatabaseConfig	*go_core_pg.DatabaseConfig  `json:"database"`
+	DatabaseAuth	*DatabaseAuth				`json:"database_auth"`
+	DatabaseReader	*DatabaseReader				`json:"database_reader"`
+	DatabaseRetry	*DatabaseRetry				`json:"database_retry"`
 	AwsService		*AwsService					`json:"aws_services"`
 	Cert			*Cert						`json:"cert_tls_server"`
+	HealthCheck		*HealthCheck				`json:"health_check"`
+	Log				*Log						`json:"log"`
+	RateLimit		*RateLimit					`json:"rate_limit"`
+	LoadShed		*LoadShed					`json:"load_shed"`
+	Cache			*Cache						`json:"cache"`
+	Encryption		*Encryption					`json:"encryption"`
+	Dedup			*Dedup						`json:"dedup"`
+	OpenAPI			*OpenAPI					`json:"openapi"`
+	Webhook			*Webhook					`json:"webhook"`
+	Job				*Job						`json:"job"`
+	Metrics			*Metrics					`json:"metrics"`
 }
 
 type InfoPod struct {
@@ -32,6 +50,143 @@ type Server struct {
 	WriteTimeout	int `json:"writeTimeout"`
 	IdleTimeout		int `json:"idleTimeout"`
 	CtxTimeout		int `json:"ctxTimeout"`
+	ExportTimeout	int `json:"exportTimeout"`
+	PreStopDelay	int `json:"preStopDelay"`
+	ShutdownTimeout	int `json:"shutdownTimeout"`
+	GrpcPort		int `json:"grpcPort"`
+	GrpcTokenFile	string `json:"grpcTokenFile,omitempty"`
+	AdminTokenFile	string `json:"adminTokenFile,omitempty"`
+	TrustedProxies	[]string `json:"trustedProxies,omitempty"`
+}
+
+type DatabaseAuth struct {
+	Mode				string `json:"mode"`
+	UserFile			string `json:"user_file,omitempty"`
+	PasswordFile		string `json:"password_file,omitempty"`
+	RefreshInterval		int    `json:"refresh_interval,omitempty"`
+	IAMUser				string `json:"iam_user,omitempty"`
+}
+
+type DatabaseReader struct {
+	IsEnabled			bool	`json:"is_enabled"`
+	Host				string	`json:"host,omitempty"`
+	Port				string	`json:"port,omitempty"`
+	MaxConnection		int		`json:"max_connection,omitempty"`
+	ReadYourWrites		int		`json:"read_your_writes,omitempty"`
+	CheckInterval		int		`json:"check_interval,omitempty"`
+}
+
+type DatabaseRetry struct {
+	Attempts			int		`json:"attempts"`
+	BaseDelay			int		`json:"base_delay_ms"`
+	MaxDelay			int		`json:"max_delay_ms"`
+	ConnectAttempts		int		`json:"connect_attempts"`
+	ConnectBaseDelay	int		`json:"connect_base_delay_ms"`
+	ConnectMaxDelay		int		`json:"connect_max_delay_ms"`
+}
+
+type RateLimit struct {
+	IsEnabled		bool		`json:"is_enabled"`
+	Store			string		`json:"store"`
+	RedisURL		string		`json:"-"`
+	ReadRate		int			`json:"read_rate"`
+	ReadBurst		int			`json:"read_burst"`
+	WriteRate		int			`json:"write_rate"`
+	WriteBurst		int			`json:"write_burst"`
+	UploadRate		int			`json:"upload_rate"`
+	UploadBurst		int			`json:"upload_burst"`
+}
+
+type LoadShed struct {
+	IsEnabled				bool	`json:"is_enabled"`
+	InitialLimit			int		`json:"initial_limit"`
+	MinLimit				int		`json:"min_limit"`
+	MaxLimit				int		`json:"max_limit"`
+	LatencyTolerancePercent	int		`json:"latency_tolerance_percent"`
+	WriteSharePercent		int		`json:"write_share_percent"`
+	Window					int		`json:"window_ms"`
+}
+
+type Cache struct {
+	IsEnabled		bool	`json:"is_enabled"`
+	Size			int		`json:"size"`
+	TTL				int		`json:"ttl"`
+	NegativeTTL		int		`json:"negative_ttl"`
+	SharedBackend	string	`json:"shared_backend,omitempty"`
+	RedisURL		string	`json:"-"`
+}
+
+type Encryption struct {
+	IsEnabled			bool	`json:"is_enabled"`
+	KeyProvider			string	`json:"key_provider"`
+	KeyFile				string	`json:"key_file,omitempty"`
+	KMSKeyID			string	`json:"kms_key_id,omitempty"`
+	ReloadInterval		int		`json:"reload_interval"`
+	ReencryptInterval	int		`json:"reencrypt_interval"`
+	ReencryptBatch		int		`json:"reencrypt_batch"`
+}
+
+type Dedup struct {
+	IsEnabled		bool	`json:"is_enabled"`
+	MatchPercent	int		`json:"match_percent"`
+	ReviewPercent	int		`json:"review_percent"`
+}
+
+type OpenAPI struct {
+	ValidateRequest		bool	`json:"validate_request"`
+	ValidateResponse	bool	`json:"validate_response"`
+}
+
+type Webhook struct {
+	IsEnabled		bool	`json:"is_enabled"`
+	Interval		int		`json:"interval"`
+	Batch			int		`json:"batch"`
+	Concurrency		int		`json:"concurrency"`
+	Timeout			int		`json:"timeout"`
+	MaxAttempts		int		`json:"max_attempts"`
+	BaseDelay		int		`json:"base_delay"`
+	MaxDelay		int		`json:"max_delay"`
+	AllowHTTP		bool	`json:"allow_http"`
+}
+
+type Job struct {
+	IsEnabled		bool	`json:"is_enabled"`
+	Concurrency		int		`json:"concurrency"`
+	PollInterval	int		`json:"poll_interval"`
+	Lease			int		`json:"lease"`
+	MaxAttempts		int		`json:"max_attempts"`
+	BaseDelay		int		`json:"base_delay"`
+	MaxDelay		int		`json:"max_delay"`
+}
+
+type EncryptionStatus struct {
+	ActiveVersion	int			`json:"active_version"`
+	Versions		[]int		`json:"versions"`
+	Pending			int			`json:"pending"`
+	Rewrapped		int			`json:"rewrapped,omitempty"`
+	CheckedAt		time.Time	`json:"checked_at"`
+}
+
+type Metrics struct {
+	Tenants		[]string	`json:"tenants"`
+}
+
+type Log struct {
+	Level			string		`json:"level"`
+	Format			string		`json:"format"`
+	MaskFields		[]string	`json:"mask_fields"`
+}
+
+type TraceConfig struct {
+	Propagators		[]string `json:"propagators"`
+}
+
+type HealthCheck struct {
+	Timeout					int 	`json:"timeout"`
+	CacheTTL				int 	`json:"cache_ttl"`
+	PoolSaturationPercent	int 	`json:"pool_saturation_percent"`
+	DiskPath				string	`json:"disk_path"`
+	DiskMinFreeMB			int 	`json:"disk_min_free_mb"`
 }
 
 type AwsService struct {
@@ -45,7 +200,8 @@ type MessageRouter struct {
 }
 
 type Onboarding struct {
-	Person 			*Person `json:"person"`
+	Person 			*Person 			`json:"person"`
+	Duplicates		[]PersonDuplicate	`json:"duplicates,omitempty"`
 }
 
 type Cert struct {
@@ -60,14 +216,245 @@ type Person struct {
 	ID			int 		`json:"id,omitempty"`
 	PersonID	string		`json:"person_id,omitempty"`
 	Name 		string 		`json:"name,omitempty"`
+	PersonType	string		`json:"person_type,omitempty"`
+	BirthDate	string		`json:"birth_date,omitempty"`
+	Email		string		`json:"email,omitempty"`
+	Phone		string		`json:"phone,omitempty"`
+	Nationality	string		`json:"nationality,omitempty"`
+	TaxID		string		`json:"tax_id,omitempty"`
+	Addresses	[]Address	`json:"addresses,omitempty"`
+	Documents	[]Document	`json:"documents,omitempty"`
 	CreatedAt	time.Time 	`json:"created_at,omitempty"`
 	UpdatedAt	*time.Time 	`json:"updated_at,omitempty"`
 	TenantID	string 		`json:"tenant_id,omitempty"`
 }
 
+type Address struct {
+	ID			int 		`json:"id,omitempty"`
+	Type		string		`json:"type,omitempty"`
+	Street		string		`json:"street,omitempty"`
+	Number		string		`json:"number,omitempty"`
+	Complement	string		`json:"complement,omitempty"`
+	District	string		`json:"district,omitempty"`
+	City		string		`json:"city,omitempty"`
+	State		string		`json:"state,omitempty"`
+	PostalCode	string		`json:"postal_code,omitempty"`
+	Country		string		`json:"country,omitempty"`
+	IsPrimary	bool		`json:"is_primary"`
+	CreatedAt	time.Time 	`json:"created_at,omitempty"`
+	UpdatedAt	*time.Time 	`json:"updated_at,omitempty"`
+}
+
+type Document struct {
+	ID				int 		`json:"id,omitempty"`
+	Type			string		`json:"type,omitempty"`
+	Number			string		`json:"number,omitempty"`
+	IssuingCountry	string		`json:"issuing_country,omitempty"`
+	IssuingAuthority string		`json:"issuing_authority,omitempty"`
+	IssueDate		string		`json:"issue_date,omitempty"`
+	ExpiryDate		string		`json:"expiry_date,omitempty"`
+	CreatedAt		time.Time 	`json:"created_at,omitempty"`
+	UpdatedAt		*time.Time 	`json:"updated_at,omitempty"`
+}
+
 type OnboardingFile struct {
+	PersonID	string	`json:"person_id,omitempty"`
 	BucketName	string	`json:"bucket_name,omitempty"`
 	FilePath	string 	`json:"file_path"`
 	FileName	string	`json:"file_name,omitempty"`
 	File		[]byte	`json:"file,omitempty"`
-}
\ No newline at end of file
+}
+
+type Audit struct {
+	ID			int64		`json:"id"`
+	PersonID	string		`json:"person_id"`
+	TenantID	string		`json:"tenant_id,omitempty"`
+	Action		string		`json:"action"`
+	Actor		string		`json:"actor,omitempty"`
+	TraceID		string		`json:"trace_id,omitempty"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+type Tombstone struct {
+	PersonID			string		`json:"person_id"`
+	TenantID			string		`json:"tenant_id,omitempty"`
+	Actor				string		`json:"actor,omitempty"`
+	TraceID				string		`json:"trace_id,omitempty"`
+	ErasedAt			time.Time	`json:"erased_at"`
+	ObjectsDeleted		int			`json:"objects_deleted"`
+	ObjectsDeletedAt	*time.Time	`json:"objects_deleted_at,omitempty"`
+}
+
+type PersonExport struct {
+	Person		*Person		`json:"person"`
+	Audit		[]Audit		`json:"audit"`
+	Files		[]string	`json:"files"`
+	ExportedAt	time.Time	`json:"exported_at"`
+}
+
+type Span struct {
+	Start	int	`json:"start"`
+	End		int	`json:"end"`
+}
+
+type PersonMatch struct {
+	Person		*Person		`json:"person"`
+	Score		float64		`json:"score"`
+	Highlight	[]Span		`json:"highlight"`
+}
+
+type PersonSearch struct {
+	Query		string			`json:"query"`
+	TenantID	string			`json:"tenant_id,omitempty"`
+	Limit		int				`json:"limit"`
+	Offset		int				`json:"offset"`
+	NextOffset	*int			`json:"next_offset,omitempty"`
+	Items		[]PersonMatch	`json:"items"`
+}
+
+type PersonDuplicate struct {
+	ID			int64		`json:"id,omitempty"`
+	PersonID	string		`json:"person_id,omitempty"`
+	TenantID	string		`json:"tenant_id,omitempty"`
+	Candidate	*Person		`json:"candidate"`
+	Score		float64		`json:"score"`
+	Reasons		[]string	`json:"reasons"`
+	Status		string		`json:"status,omitempty"`
+	CreatedAt	*time.Time	`json:"created_at,omitempty"`
+	ResolvedAt	*time.Time	`json:"resolved_at,omitempty"`
+}
+
+// DuplicateError refuses a onboarding, the person matched the candidates
+type DuplicateError struct {
+	Candidates	[]PersonDuplicate	`json:"candidates"`
+}
+
+func (e *DuplicateError) Error() string {
+	return erro.ErrDuplicatePerson.Error()
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return erro.ErrDuplicatePerson
+}
+
+type PersonMerge struct {
+	SurvivorPersonID	string		`json:"survivor_person_id"`
+	MergedPersonID		string		`json:"merged_person_id"`
+	TenantID			string		`json:"tenant_id,omitempty"`
+	Actor				string		`json:"actor,omitempty"`
+	TraceID				string		`json:"trace_id,omitempty"`
+	MergedAt			time.Time	`json:"merged_at"`
+	ObjectsMoved		int			`json:"objects_moved"`
+	ObjectsMovedAt		*time.Time	`json:"objects_moved_at,omitempty"`
+}
+
+type WebhookSubscription struct {
+	ID			int64		`json:"id,omitempty"`
+	TenantID	string		`json:"tenant_id,omitempty"`
+	URL			string		`json:"url"`
+	Events		[]string	`json:"events"`
+	Secret		string		`json:"secret,omitempty"`
+	Description	string		`json:"description,omitempty"`
+	IsActive	bool		`json:"is_active"`
+	CreatedAt	time.Time	`json:"created_at,omitempty"`
+	UpdatedAt	*time.Time	`json:"updated_at,omitempty"`
+}
+
+// WebhookEvent is the body of a delivery, the receivers get the person by its person_id
+type WebhookEvent struct {
+	ID			string		`json:"id"`
+	Event		string		`json:"event"`
+	TenantID	string		`json:"tenant_id,omitempty"`
+	PersonID	string		`json:"person_id"`
+	DocumentID	int			`json:"document_id,omitempty"`
+	OccurredAt	time.Time	`json:"occurred_at"`
+}
+
+type WebhookDelivery struct {
+	ID				int64				`json:"id"`
+	SubscriptionID	int64				`json:"subscription_id"`
+	Event			WebhookEvent		`json:"event"`
+	Status			string				`json:"status"`
+	Attempts		int					`json:"attempts"`
+	NextAttemptAt	*time.Time			`json:"next_attempt_at,omitempty"`
+	LastStatusCode	int					`json:"last_status_code,omitempty"`
+	LastError		string				`json:"last_error,omitempty"`
+	CreatedAt		time.Time			`json:"created_at"`
+	DeliveredAt		*time.Time			`json:"delivered_at,omitempty"`
+	AttemptLog		[]WebhookAttempt	`json:"attempt_log,omitempty"`
+	URL				string				`json:"-"`
+	Secret			string				`json:"-"`
+}
+
+type WebhookDeliveryList struct {
+	SubscriptionID	int64				`json:"subscription_id"`
+	Status			string				`json:"status,omitempty"`
+	Limit			int					`json:"limit"`
+	Offset			int					`json:"offset"`
+	NextOffset		*int				`json:"next_offset,omitempty"`
+	Items			[]WebhookDelivery	`json:"items"`
+}
+
+type WebhookAttempt struct {
+	Attempt		int			`json:"attempt"`
+	StatusCode	int			`json:"status_code,omitempty"`
+	Error		string		`json:"error,omitempty"`
+	DurationMs	int			`json:"duration_ms"`
+	AttemptedAt	time.Time	`json:"attempted_at"`
+}
+
+// AsyncJob is a unit of long running work, run by the job workers out of the requests
+type AsyncJob struct {
+	ID				int64				`json:"id,omitempty"`
+	Kind			string				`json:"kind"`
+	TenantID		string				`json:"tenant_id,omitempty"`
+	Payload			json.RawMessage		`json:"payload,omitempty"`
+	Status			string				`json:"status,omitempty"`
+	Attempts		int					`json:"attempts"`
+	MaxAttempts		int					`json:"max_attempts,omitempty"`
+	Progress		JobProgress			`json:"progress"`
+	Result			json.RawMessage		`json:"result,omitempty"`
+	Error			string				`json:"error,omitempty"`
+	CancelRequested	bool				`json:"cancel_requested,omitempty"`
+	RunAt			*time.Time			`json:"run_at,omitempty"`
+	Actor			string				`json:"actor,omitempty"`
+	TraceID			string				`json:"trace_id,omitempty"`
+	CreatedAt		time.Time			`json:"created_at"`
+	StartedAt		*time.Time			`json:"started_at,omitempty"`
+	FinishedAt		*time.Time			`json:"finished_at,omitempty"`
+}
+
+type AsyncJobList struct {
+	TenantID	string		`json:"tenant_id,omitempty"`
+	Kind		string		`json:"kind,omitempty"`
+	Status		string		`json:"status,omitempty"`
+	Limit		int			`json:"limit"`
+	Offset		int			`json:"offset"`
+	NextOffset	*int		`json:"next_offset,omitempty"`
+	Items		[]AsyncJob	`json:"items"`
+}
+
+type JobProgress struct {
+	Done	int		`json:"done"`
+	Total	int		`json:"total"`
+}
+
+type PersonImport struct {
+	Imported	int					`json:"imported"`
+	Skipped		int					`json:"skipped"`
+	Failed		int					`json:"failed"`
+	Errors		[]PersonImportError	`json:"errors,omitempty"`
+}
+
+type PersonImportError struct {
+	Line		int		`json:"line"`
+	PersonID	string	`json:"person_id,omitempty"`
+	Error		string	`json:"error"`
+}
+
+type TenantExport struct {
+	TenantID	string	`json:"tenant_id"`
+	BucketName	string	`json:"bucket_name,omitempty"`
+	Key			string	`json:"key,omitempty"`
+	Persons		int		`json:"persons"`
+}
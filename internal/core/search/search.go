@@ -0,0 +1,115 @@
+package search
+
+import(
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// the accents are split from the letters (NFD) and dropped, João becomes Joao
+var unaccent = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// Words shorter than this are not highlighted by a prefix or a similarity
+const minFuzzyWord = 3
+
+// Two words are similar from this trigram similarity, the pg_trgm default is 0.3
+// for the whole name, the words alone need more
+const wordSimilarity = 0.5
+
+// About the form the names are compared in: without accents, lower case,
+// and the punctuation as spaces (the same as f_unaccent(lower(name)) plus the tokenizer)
+func Normalize(value string) string {
+	return strings.Join(Words(value), " ")
+}
+
+// About the normalized words of a value
+func Words(value string) []string {
+	unaccented, _, err := transform.String(unaccent, value)
+	if err != nil {
+		unaccented = value
+	}
+	return strings.FieldsFunc(strings.ToLower(unaccented), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// About the trigrams of a value as pg_trgm builds them: each word padded with
+// two spaces ahead and one behind, without repetitions
+func Trigrams(value string) []string {
+	seen := map[string]bool{}
+	trigrams := []string{}
+	for _, word := range Words(value) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i + 3 <= len(padded); i++ {
+			trigram := string(padded[i:i+3])
+			if !seen[trigram] {
+				seen[trigram] = true
+				trigrams = append(trigrams, trigram)
+			}
+		}
+	}
+	return trigrams
+}
+
+// About the trigram similarity of two values, shared trigrams over all the trigrams (0 to 1)
+func Similarity(a string, b string) float64 {
+	trigramsA, trigramsB := Trigrams(a), Trigrams(b)
+	if len(trigramsA) == 0 || len(trigramsB) == 0 {
+		return 0
+	}
+	inA := make(map[string]bool, len(trigramsA))
+	for _, trigram := range trigramsA {
+		inA[trigram] = true
+	}
+	shared := 0
+	for _, trigram := range trigramsB {
+		if inA[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(trigramsA) + len(trigramsB) - shared)
+}
+
+// About the words of a name matching the query, as rune offsets of the name.
+// A word matches a query word when they are equal without accents, when it starts
+// with it or when they are similar (a typo).
+func Highlight(name string, query string) []model.Span {
+	queryWords := Words(query)
+	spans := []model.Span{}
+
+	nameRunes := []rune(name)
+	for start := 0; start < len(nameRunes); {
+		if isSeparator(nameRunes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(nameRunes) && !isSeparator(nameRunes[end]) {
+			end++
+		}
+		if matches(Normalize(string(nameRunes[start:end])), queryWords) {
+			spans = append(spans, model.Span{Start: start, End: end})
+		}
+		start = end
+	}
+	return spans
+}
+
+func matches(word string, queryWords []string) bool {
+	for _, queryWord := range queryWords {
+		if word == queryWord {
+			return true
+		}
+		if len(queryWord) >= minFuzzyWord && (strings.HasPrefix(word, queryWord) || Similarity(word, queryWord) >= wordSimilarity) {
+			return true
+		}
+	}
+	return false
+}
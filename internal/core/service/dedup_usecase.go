@@ -0,0 +1,261 @@
+package service
+
+import(
+	"sort"
+	"context"
+
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/core/dedup"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/search"
+	"github.com/go-onboarding/internal/core/validation"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// the candidates looked at by the onboarding, by identifier and by name each
+const dedupCandidates = 10
+
+// About set the deduplication policy of the onboarding, the zero policy disables it
+func (s *WorkerService) SetDedupPolicy(dedupPolicy dedup.Policy) {
+	s.dedupPolicy = dedupPolicy
+}
+
+// About find and score the persons of the tenant a person may be a duplicate of, in the
+// transaction holding the lock of the tenant: a onboarding waiting for the lock holds a
+// connection, the lock holder takes no other one of the pool
+func (s *WorkerService) findDuplicates(ctx context.Context, tx pgx.Tx, person *model.Person) (_ []model.PersonDuplicate, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","findDuplicates").Send()
+
+	ctx, span := tracing.Start(ctx, "service.findDuplicates", tracing.PersonAttributes(person.TenantID, person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	personIDs, err := s.workerRepository.FindDuplicateCandidates(ctx, tx, person, dedupCandidates)
+	if err != nil {
+		return nil, err
+	}
+	if search.Normalize(person.Name) != "" {
+		matches, err := s.workerRepository.SearchPersonTx(ctx, tx, person.TenantID, person.Name, dedupCandidates, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			personIDs = append(personIDs, match.Person.PersonID)
+		}
+	}
+
+	seen := map[string]bool{person.PersonID: true}
+	duplicates := []model.PersonDuplicate{}
+	for _, personID := range personIDs {
+		if seen[personID] {
+			continue
+		}
+		seen[personID] = true
+
+		// with its documents, the search results have none
+		res, err := s.workerRepository.GetPersonTx(ctx, tx, &model.Onboarding{Person: &model.Person{PersonID: personID}})
+		if err == erro.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		score, reasons := dedup.Score(person, res.Person)
+		// the candidate is shown by its name only, the reasons tell what matched
+		duplicates = append(duplicates, model.PersonDuplicate{	PersonID: person.PersonID,
+																TenantID: person.TenantID,
+																Candidate: &model.Person{	PersonID: res.Person.PersonID,
+																							Name: res.Person.Name,
+																							PersonType: res.Person.PersonType,
+																							CreatedAt: res.Person.CreatedAt,
+																},
+																Score: score,
+																Reasons: reasons,
+		})
+	}
+	return duplicates, nil
+}
+
+// About list the possible duplicates of a tenant, the pending ones when no status is given
+func (s *WorkerService) ListDuplicate(ctx context.Context, tenantID string, status string) (_ []model.PersonDuplicate, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListDuplicate").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListDuplicate", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if tenantID, err = requestTenant(ctx, tenantID); err != nil {
+		return nil, err
+	}
+
+	if status == "" {
+		status = database.DuplicatePending
+	}
+	if validation.OneOf(database.DuplicatePending, database.DuplicateMerged, database.DuplicateDismissed)(status) != nil {
+		return nil, erro.ErrBadRequest
+	}
+	return s.workerRepository.ListDuplicate(ctx, tenantID, status)
+}
+
+// About dismiss a possible duplicate, the persons were reviewed and are not the same
+func (s *WorkerService) DismissDuplicate(ctx context.Context, id int64) (_ *model.PersonDuplicate, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DismissDuplicate").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.DismissDuplicate")
+	defer func() { tracing.End(span, err) }()
+
+	callerTenant, err := callerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *model.PersonDuplicate
+	err = s.withTx(ctx, "DismissDuplicate", func(ctx context.Context, tx pgx.Tx) (err error) {
+		res, err = s.workerRepository.DismissDuplicate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		// the duplicates of the other tenants are not found, the dismiss is rolled back
+		if callerTenant != "" && res.TenantID != callerTenant {
+			return erro.ErrNotFound
+		}
+		return s.audit(ctx, tx, res.PersonID, res.TenantID, "DismissDuplicate")
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveEvent(res.TenantID, "person_duplicate_dismissed")
+
+	return res, nil
+}
+
+// About merge a duplicate person into the survivor: the empty fields of the survivor are
+// filled from the merged person, its addresses, documents and files move to the survivor
+// and its audit history is listed with the one of the survivor. The merged person is
+// anonymized and hidden. It is idempotent, a merge run again returns the merge and
+// moves the files left by a failed run.
+func (s *WorkerService) MergePerson(ctx context.Context, merge *model.PersonMerge) (_ *model.PersonMerge, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","MergePerson").Send()
+
+	ctx, span := tracing.Start(ctx, "service.MergePerson", tracing.PersonAttributes(merge.TenantID, merge.SurvivorPersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if merge.SurvivorPersonID == "" || merge.MergedPersonID == "" || merge.SurvivorPersonID == merge.MergedPersonID {
+		return nil, erro.ErrBadRequest
+	}
+	// a caller bound to a tenant only merges the persons of its tenant
+	callerTenant, err := callerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := false
+	res, err := s.workerRepository.GetMerge(ctx, merge.MergedPersonID)
+	switch {
+	case err == nil && callerTenant != "" && res.TenantID != callerTenant:
+		return nil, erro.ErrNotFound
+	case err == nil && res.SurvivorPersonID != merge.SurvivorPersonID:
+		// merged already, into another person
+		return nil, erro.ErrBadRequest
+	case err == nil:
+		merge = res
+	case err != erro.ErrNotFound:
+		return nil, err
+	default:
+		err = s.withTx(ctx, "MergePerson", func(ctx context.Context, tx pgx.Tx) error {
+			ids, err := s.lockPersons(ctx, tx, merge.SurvivorPersonID, merge.MergedPersonID)
+			if err != nil {
+				return err
+			}
+			survivor, err := s.workerRepository.GetPersonTx(ctx, tx, &model.Onboarding{Person: &model.Person{PersonID: merge.SurvivorPersonID, TenantID: callerTenant}})
+			if err != nil {
+				return err
+			}
+			duplicate, err := s.workerRepository.GetPersonTx(ctx, tx, &model.Onboarding{Person: &model.Person{PersonID: merge.MergedPersonID, TenantID: callerTenant}})
+			if err != nil {
+				return err
+			}
+			if survivor.Person.TenantID != duplicate.Person.TenantID {
+				return erro.ErrBadRequest
+			}
+
+			merge.TenantID = survivor.Person.TenantID
+			if correlation := logging.CorrelationFrom(ctx); correlation != nil {
+				merge.Actor = correlation.Client
+				merge.TraceID = correlation.TraceID
+			}
+			// before the survivor is updated, the tax id of the merged person is released
+			if err := s.workerRepository.MergePerson(ctx, tx, ids[merge.MergedPersonID], ids[merge.SurvivorPersonID], merge); err != nil {
+				return err
+			}
+
+			fillPerson(survivor.Person, duplicate.Person)
+			if _, err := s.workerRepository.UpdatePerson(ctx, tx, survivor); err != nil {
+				return err
+			}
+
+			if err := s.audit(ctx, tx, merge.MergedPersonID, merge.TenantID, "MergePerson"); err != nil {
+				return err
+			}
+			return s.audit(ctx, tx, merge.SurvivorPersonID, merge.TenantID, "MergePerson")
+		})
+		if err != nil {
+			return nil, err
+		}
+		merged = true
+		s.invalidatePerson(ctx, merge.TenantID, merge.SurvivorPersonID, merge.MergedPersonID)
+		s.workerRepository.ReadRouter.MarkWrite(ctx)
+	}
+
+	moved, err := s.personBucket.MoveAll(ctx, merge.MergedPersonID, merge.SurvivorPersonID)
+	if moved > 0 || (err == nil && merge.ObjectsMovedAt == nil) {
+		if errMerge := s.workerRepository.AddMergeObjects(ctx, merge, moved); errMerge != nil && err == nil {
+			err = errMerge
+		}
+	}
+	if err != nil {
+		// the persons are merged, the files left are moved when it is run again
+		logging.Ctx(ctx, childLogger).Error().Err(err).Int("moved", moved).Msg("error move person files")
+		return nil, err
+	}
+	if merged {
+		metrics.ObserveEvent(merge.TenantID, "person_merged")
+	}
+
+	return merge, nil
+}
+
+// About lock persons in the order of their person_id, two transactions locking the
+// same persons in a different order would deadlock. Returns their internal ids.
+func (s *WorkerService) lockPersons(ctx context.Context, tx pgx.Tx, personIDs ...string) (map[string]int, error) {
+	sorted := append([]string{}, personIDs...)
+	sort.Strings(sorted)
+
+	ids := map[string]int{}
+	for _, personID := range sorted {
+		id, _, err := s.workerRepository.LockPerson(ctx, tx, personID)
+		if err != nil {
+			return nil, err
+		}
+		ids[personID] = id
+	}
+	return ids, nil
+}
+
+// About fill the empty fields of the survivor of a merge from the merged person
+func fillPerson(survivor *model.Person, merged *model.Person) {
+	fill := func(value *string, from string) {
+		if *value == "" {
+			*value = from
+		}
+	}
+	fill(&survivor.BirthDate, merged.BirthDate)
+	fill(&survivor.Email, merged.Email)
+	fill(&survivor.Phone, merged.Phone)
+	fill(&survivor.Nationality, merged.Nationality)
+	fill(&survivor.TaxID, merged.TaxID)
+}
@@ -0,0 +1,122 @@
+package service
+
+import(
+	"time"
+	"context"
+	"strings"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/validation"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// About find the persons of a tenant by the exact tax id or email, they may be encrypted
+func (s *WorkerService) LookupPerson(ctx context.Context, onboarding *model.Onboarding) (_ *[]model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","LookupPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "service.LookupPerson", tracing.PersonAttributes(onboarding.Person.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if onboarding.Person.TenantID, err = requestTenant(ctx, onboarding.Person.TenantID); err != nil {
+		return nil, err
+	}
+
+	// normalized as they are when stored
+	taxID := validation.NormalizeTaxID(onboarding.Person.TaxID)
+	email := strings.ToLower(strings.TrimSpace(onboarding.Person.Email))
+	if taxID == "" && email == "" {
+		return nil, erro.ErrBadRequest
+	}
+
+	return s.workerRepository.FindPerson(ctx, onboarding.Person.TenantID, taxID, email)
+}
+
+// About the encryption keys and the persons waiting for the re-encryption
+func (s *WorkerService) EncryptionStatus(ctx context.Context) (_ *model.EncryptionStatus, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","EncryptionStatus").Send()
+
+	ctx, span := tracing.Start(ctx, "service.EncryptionStatus")
+	defer func() { tracing.End(span, err) }()
+
+	if s.workerRepository.Keyring == nil {
+		return nil, erro.ErrEncryptionDisabled
+	}
+	return s.workerRepository.EncryptionStatus(ctx)
+}
+
+// About create a new data key version, the persons are moved to it by the re-encryption
+func (s *WorkerService) RotateEncryptionKey(ctx context.Context) (_ *model.EncryptionStatus, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","RotateEncryptionKey").Send()
+
+	ctx, span := tracing.Start(ctx, "service.RotateEncryptionKey")
+	defer func() { tracing.End(span, err) }()
+
+	if s.workerRepository.Keyring == nil {
+		return nil, erro.ErrEncryptionDisabled
+	}
+	if _, err = s.workerRepository.Keyring.Rotate(ctx); err != nil {
+		return nil, err
+	}
+	return s.workerRepository.EncryptionStatus(ctx)
+}
+
+// About wrap the data keys with the current master key, after the master key is rotated
+func (s *WorkerService) RewrapEncryptionKeys(ctx context.Context) (_ *model.EncryptionStatus, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","RewrapEncryptionKeys").Send()
+
+	ctx, span := tracing.Start(ctx, "service.RewrapEncryptionKeys")
+	defer func() { tracing.End(span, err) }()
+
+	if s.workerRepository.Keyring == nil {
+		return nil, erro.ErrEncryptionDisabled
+	}
+	rewrapped, err := s.workerRepository.Keyring.Rewrap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := s.workerRepository.EncryptionStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Rewrapped = rewrapped
+
+	return status, nil
+}
+
+// About encrypt again, with the active data key, the persons of older keys (or in
+// plaintext) every interval until the context is done. The batches are run back to
+// back until none is left, each batch in its own transaction.
+func (s *WorkerService) Reencrypt(ctx context.Context, interval time.Duration, batch int) {
+	childLogger.Info().Str("func","Reencrypt").Str("interval", interval.String()).Int("batch", batch).Send()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				var count int
+				err := s.withTx(ctx, "ReencryptPerson", func(ctx context.Context, tx pgx.Tx) (err error) {
+					count, err = s.workerRepository.ReencryptPerson(ctx, tx, batch)
+					return err
+				})
+				if err != nil {
+					logging.Ctx(ctx, childLogger).Error().Err(err).Msg("error reencrypt person")
+					break
+				}
+				metrics.Reencrypted.Add(float64(count))
+				if count < batch {
+					break
+				}
+			}
+		}
+	}
+}
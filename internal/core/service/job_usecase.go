@@ -0,0 +1,470 @@
+package service
+
+import(
+	"io"
+	"fmt"
+	"bytes"
+	"errors"
+	"context"
+	"strings"
+	"net/url"
+	"encoding/csv"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/validation"
+	"github.com/go-onboarding/internal/infra/jobs"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The kinds of job
+const (
+	JobReencrypt		= "reencrypt"		// encrypt again the persons of older data keys
+	JobExportTenant		= "export_tenant"	// write the persons of the tenant to the bucket, as json lines
+	JobImportPersons	= "import_persons"	// add the persons of a csv file uploaded to the bucket
+)
+
+const (
+	jobListLimit		= 50
+	jobListMaxLimit		= 500
+	jobReencryptBatch	= 100
+	exportPageSize		= 200
+	// the row errors kept in the result of a import
+	importMaxErrors		= 100
+)
+
+// the csv columns of a import, the header row names them in any order
+var importColumns = []string{"person_id", "name", "person_type", "birth_date", "email", "phone", "nationality", "tax_id", "tenant_id"}
+
+// payload of the reencrypt job
+type reencryptPayload struct {
+	Batch		int		`json:"batch,omitempty"`
+}
+
+// payload of the import_persons job, the file is uploaded with the upload file route
+type importPayload struct {
+	FileName	string	`json:"file_name"`
+}
+
+// About set the job queue, nil disables the jobs. A job is tried up to the max attempts.
+func (s *WorkerService) SetJobQueue(jobQueue *database.JobRepository, maxAttempts int) {
+	s.jobQueue = jobQueue
+	s.jobMaxAttempts = maxAttempts
+}
+
+// About register the handlers of the kinds of job in the pool running them
+func (s *WorkerService) RegisterJobs(pool *jobs.Pool) {
+	pool.Register(JobReencrypt, s.runReencrypt)
+	pool.Register(JobExportTenant, s.runExportTenant)
+	pool.Register(JobImportPersons, s.runImportPersons)
+}
+
+// About queue a job, its payload is checked now so a invalid job is refused up front
+func (s *WorkerService) EnqueueJob(ctx context.Context, job *model.AsyncJob) (_ *model.AsyncJob, err error){
+	logging.SetTenant(ctx, job.TenantID)
+	logging.Ctx(ctx, childLogger).Info().Str("func","EnqueueJob").Str("kind", job.Kind).Send()
+
+	ctx, span := tracing.Start(ctx, "service.EnqueueJob", tracing.PersonAttributes(job.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if s.jobQueue == nil {
+		return nil, erro.ErrJobDisabled
+	}
+
+	var errs validation.Errors
+	switch job.Kind {
+	case JobReencrypt:
+		payload := reencryptPayload{}
+		if err = decodePayload(job.Payload, &payload); err != nil {
+			return nil, err
+		}
+		if payload.Batch < 0 {
+			errs.Add("payload.batch", "must not be negative")
+		}
+		if s.workerRepository.Keyring == nil {
+			return nil, erro.ErrEncryptionDisabled
+		}
+	case JobExportTenant:
+		if s.awsService.BucketName == "" {
+			return nil, erro.ErrBadRequest
+		}
+	case JobImportPersons:
+		payload := importPayload{}
+		if err = decodePayload(job.Payload, &payload); err != nil {
+			return nil, err
+		}
+		errs.Check("payload.file_name", payload.FileName, validation.Required(500))
+		errs.Check("payload.file_name", payload.FileName, fileName)
+		if s.personBucket == nil {
+			return nil, erro.ErrBadRequest
+		}
+	default:
+		errs.Check("kind", job.Kind, validation.OneOf(JobReencrypt, JobExportTenant, JobImportPersons))
+	}
+	if err = errs.Err(); err != nil {
+		return nil, err
+	}
+
+	job.ID = 0
+	job.MaxAttempts = s.jobMaxAttempts
+	job.Actor, job.TraceID = "", ""
+	if correlation := logging.CorrelationFrom(ctx); correlation != nil {
+		job.Actor = correlation.Client
+		job.TraceID = correlation.TraceID
+	}
+
+	if err = s.jobQueue.AddJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// About decode the payload of a job, a empty one is the zero payload
+func decodePayload(raw json.RawMessage, payload any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		var errs validation.Errors
+		errs.Add("payload", err.Error())
+		return errs.Err()
+	}
+	return nil
+}
+
+// a file name of the upload path, it can not leave it
+func fileName(value string) error {
+	if strings.HasPrefix(value, "/") || strings.Contains(value, "..") {
+		return errors.New("must be a file name in the upload path")
+	}
+	return nil
+}
+
+// About get a job with its progress
+func (s *WorkerService) GetJob(ctx context.Context, id int64) (_ *model.AsyncJob, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetJob").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.GetJob")
+	defer func() { tracing.End(span, err) }()
+
+	if s.jobQueue == nil {
+		return nil, erro.ErrJobDisabled
+	}
+	return s.jobQueue.GetJob(ctx, id)
+}
+
+// About list the jobs of a tenant, newest first
+func (s *WorkerService) ListJob(ctx context.Context, jobList *model.AsyncJobList) (_ *model.AsyncJobList, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListJob").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListJob", tracing.PersonAttributes(jobList.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if s.jobQueue == nil {
+		return nil, erro.ErrJobDisabled
+	}
+	if jobList.Offset < 0 || jobList.Limit < 0 {
+		return nil, erro.ErrBadRequest
+	}
+	if jobList.Status != "" && validation.OneOf(jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusCanceled)(jobList.Status) != nil {
+		return nil, erro.ErrBadRequest
+	}
+	if jobList.Limit == 0 {
+		jobList.Limit = jobListLimit
+	}
+	if jobList.Limit > jobListMaxLimit {
+		jobList.Limit = jobListMaxLimit
+	}
+
+	items, err := s.jobQueue.ListJob(ctx, jobList.TenantID, jobList.Kind, jobList.Status, jobList.Limit, jobList.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	jobList.NextOffset = nil
+	if len(items) > jobList.Limit {
+		items = items[:jobList.Limit]
+		next := jobList.Offset + jobList.Limit
+		jobList.NextOffset = &next
+	}
+	jobList.Items = items
+
+	return jobList, nil
+}
+
+// About cancel a job, a running one stops with the next heartbeat of its worker
+func (s *WorkerService) CancelJob(ctx context.Context, id int64) (_ *model.AsyncJob, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","CancelJob").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.CancelJob")
+	defer func() { tracing.End(span, err) }()
+
+	if s.jobQueue == nil {
+		return nil, erro.ErrJobDisabled
+	}
+	return s.jobQueue.CancelJob(ctx, id)
+}
+
+// the reencrypt job, the batches are run back to back until none is left
+func (s *WorkerService) runReencrypt(ctx context.Context, job *model.AsyncJob, progress jobs.Progress) (any, error) {
+	payload := reencryptPayload{}
+	if err := decodePayload(job.Payload, &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	if payload.Batch == 0 {
+		payload.Batch = jobReencryptBatch
+	}
+	if s.workerRepository.Keyring == nil {
+		return nil, jobs.Permanent(erro.ErrEncryptionDisabled)
+	}
+
+	total, err := s.workerRepository.CountReencryptPending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := 0
+	progress(done, total)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var count int
+		err := s.withTx(ctx, "ReencryptPerson", func(ctx context.Context, tx pgx.Tx) (err error) {
+			count, err = s.workerRepository.ReencryptPerson(ctx, tx, payload.Batch)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		done += count
+		progress(done, max(total, done))
+		if count < payload.Batch {
+			return map[string]int{"reencrypted": done}, nil
+		}
+	}
+}
+
+// the export_tenant job, the file is written to <file path>job/<id>/
+func (s *WorkerService) runExportTenant(ctx context.Context, job *model.AsyncJob, progress jobs.Progress) (any, error) {
+	if s.personBucket == nil {
+		return nil, jobs.Permanent(erro.ErrBadRequest)
+	}
+
+	key := fmt.Sprintf("%sjob/%d/tenant-%s.jsonl", s.awsService.FilePath, job.ID, url.PathEscape(job.TenantID))
+
+	// streamed to the bucket as it is written, a tenant of any size is not held in memory
+	reader, writer := io.Pipe()
+	var persons int
+	var errExport error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		persons, errExport = s.ExportTenant(ctx, job.TenantID, writer, progress)
+		writer.CloseWithError(errExport)
+	}()
+	err := s.personBucket.Upload(ctx, key, reader)
+	// a upload failed stops the export writing to it
+	reader.CloseWithError(err)
+	<-done
+	if errExport != nil {
+		return nil, errExport
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.TenantExport{	TenantID: job.TenantID,
+								BucketName: s.awsService.BucketName,
+								Key: key,
+								Persons: persons,
+	}, nil
+}
+
+// the import_persons job, the file is read from the upload path
+func (s *WorkerService) runImportPersons(ctx context.Context, job *model.AsyncJob, progress jobs.Progress) (any, error) {
+	payload := importPayload{}
+	if err := decodePayload(job.Payload, &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	if s.personBucket == nil {
+		return nil, jobs.Permanent(erro.ErrBadRequest)
+	}
+
+	file, err := s.personBucket.Open(ctx, s.awsService.FilePath + payload.FileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return s.ImportPersons(ctx, job.TenantID, file, progress)
+}
+
+// About write the persons of a tenant, with their addresses and documents, as json
+// lines. Returns how many were written, the progress (when given) is reported per page.
+func (s *WorkerService) ExportTenant(ctx context.Context, tenantID string, out io.Writer, progress jobs.Progress) (_ int, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ExportTenant").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ExportTenant", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	// from the primary, the export must be complete
+	ctx = database.UsePrimary(ctx)
+
+	total, err := s.workerRepository.CountTenantPerson(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(out)
+	written, after := 0, ""
+	for {
+		if progress != nil {
+			progress(written, max(total, written))
+		}
+		personIDs, err := s.workerRepository.ListTenantPersonID(ctx, tenantID, after, exportPageSize)
+		if err != nil {
+			return written, err
+		}
+		for _, personID := range personIDs {
+			res, err := s.workerRepository.GetPerson(ctx, &model.Onboarding{Person: &model.Person{PersonID: personID}})
+			if errors.Is(err, erro.ErrNotFound) {
+				// erased since it was listed
+				continue
+			}
+			if err != nil {
+				return written, err
+			}
+			if err = encoder.Encode(res.Person); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(personIDs) < exportPageSize {
+			return written, nil
+		}
+		after = personIDs[len(personIDs) - 1]
+	}
+}
+
+// About add the persons of a csv, its header row names the columns (person_id, name,
+// person_type, birth_date, email, phone, nationality, tax_id, tenant_id). The persons
+// already added are skipped, so a import run again goes on where it stopped.
+// A row refused (invalid or duplicate) is reported and the import goes on, any other
+// error stops it. A tenant given is the one of all the rows.
+func (s *WorkerService) ImportPersons(ctx context.Context, tenantID string, in io.Reader, progress jobs.Progress) (_ *model.PersonImport, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ImportPersons").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ImportPersons", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	reader := csv.NewReader(in)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("read csv header: %w", err))
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"person_id", "name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, jobs.Permanent(fmt.Errorf("csv header without the column %s", name))
+		}
+	}
+	value := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	res := model.PersonImport{}
+	reject := func(line int, personID string, err error) {
+		res.Failed++
+		if len(res.Errors) < importMaxErrors {
+			res.Errors = append(res.Errors, model.PersonImportError{Line: line, PersonID: personID, Error: err.Error()})
+		}
+	}
+
+	for line := 2; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(line - 2, 0)
+		}
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				reject(line, "", err)
+				continue
+			}
+			return nil, err
+		}
+
+		person := model.Person{}
+		for _, name := range importColumns {
+			field := value(record, name)
+			switch name {
+			case "person_id": person.PersonID = field
+			case "name": person.Name = field
+			case "person_type": person.PersonType = field
+			case "birth_date": person.BirthDate = field
+			case "email": person.Email = field
+			case "phone": person.Phone = field
+			case "nationality": person.Nationality = field
+			case "tax_id": person.TaxID = field
+			case "tenant_id": person.TenantID = field
+			}
+		}
+		if tenantID != "" {
+			if person.TenantID != "" && person.TenantID != tenantID {
+				reject(line, person.PersonID, fmt.Errorf("tenant_id %s is not the tenant of the import", person.TenantID))
+				continue
+			}
+			person.TenantID = tenantID
+		}
+
+		if person.PersonID != "" {
+			_, err = s.workerRepository.GetPerson(database.UsePrimary(ctx), &model.Onboarding{Person: &model.Person{PersonID: person.PersonID}})
+			if err == nil {
+				res.Skipped++
+				continue
+			}
+			if !errors.Is(err, erro.ErrNotFound) {
+				return nil, err
+			}
+		}
+
+		_, err = s.AddPerson(ctx, &model.Onboarding{Person: &person})
+		switch {
+		case err == nil:
+			res.Imported++
+		case errors.Is(err, erro.ErrInvalid), errors.Is(err, erro.ErrDuplicateTaxID), errors.Is(err, erro.ErrDuplicatePerson):
+			reject(line, person.PersonID, err)
+		default:
+			return nil, err
+		}
+	}
+
+	if progress != nil {
+		rows := res.Imported + res.Skipped + res.Failed
+		progress(rows, rows)
+	}
+	return &res, nil
+}
@@ -1,26 +1,48 @@
 package service
 
 import(
+	"errors"
+	"slices"
 	"context"
+	"encoding/json"
 
+	"github.com/go-onboarding/internal/adapter/bucket"
 	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/notify"
 	"github.com/rs/zerolog/log"
 
+	"github.com/go-onboarding/internal/core/dedup"
 	"github.com/go-onboarding/internal/core/model"
 	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/validation"
+	"github.com/go-onboarding/internal/core/webhook"
+	"github.com/go-onboarding/internal/infra/cache"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/retry"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
 
 	go_core_pg "github.com/eliezerraj/go-core/database/pg"
-	go_core_observ "github.com/eliezerraj/go-core/observability"
 	go_core_s3_bucket "github.com/eliezerraj/go-core/aws/bucket_s3"
 )
 
-var tracerProvider go_core_observ.TracerProvider
 var childLogger = log.With().Str("component","go-payment").Str("package","internal.core.service").Logger()
 
 type WorkerService struct {
 	workerRepository 	*database.WorkerRepository
 	workerBucketS3 		*go_core_s3_bucket.AwsBucketS3
 	awsService			*model.AwsService
+	personCache			*cache.Cache
+	retryPolicy			retry.Policy
+	personBucket		*bucket.PersonBucket
+	dedupPolicy			dedup.Policy
+	notifier			*notify.Notifier
+	webhookPolicy		retry.Policy
+	webhookAllowHTTP	bool
+	jobQueue			*database.JobRepository
+	jobMaxAttempts		int
 }
 
 // About create a new worker service
@@ -36,104 +58,289 @@ func NewWorkerService(	workerRepository *database.WorkerRepository,
 	}
 }
 
+// About set the cache of the get person, nil disables it
+func (s *WorkerService) SetPersonCache(personCache *cache.Cache) {
+	s.personCache = personCache
+}
+
+// About set the retry policy of the transactions, the zero policy runs them once
+func (s *WorkerService) SetRetryPolicy(retryPolicy retry.Policy) {
+	s.retryPolicy = retryPolicy
+}
+
+// About run fn in a transaction, committed when fn succeeds and rolled back otherwise.
+// The whole transaction is run again when it fails with a retryable error.
+func (s *WorkerService) withTx(ctx context.Context, operation string, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	return retry.Do(ctx, s.retryPolicy, operation, database.IsRetryable, func(ctx context.Context) (err error) {
+		tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
+		if err != nil {
+			return err
+		}
+		defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
+
+		defer func() {
+			if err != nil {
+				tx.Rollback(ctx)
+			} else {
+				err = s.workerRepository.DatabasePGServer.CommitTx(ctx, tx)
+			}
+			metrics.ObserveTx(operation, err)
+		}()
+
+		return fn(ctx, tx)
+	})
+}
+
+// About the cache key of a person as read by a caller bound to a tenant ("" when not bound),
+// the entries of a tenant are never returned to the callers of another one
+func personKey(tenantID string, personID string) string {
+	return "person:" + tenantID + ":" + personID
+}
+
+// About the tenant the caller reaches: the one it is bound to (X-Tenant-Id), all of
+// them ("") for a admin calling without one. The other callers are refused, a caller
+// not bound to a tenant would reach the persons of every tenant.
+func callerScope(ctx context.Context) (string, error) {
+	if tenantID := logging.CallerTenant(ctx); tenantID != "" {
+		return tenantID, nil
+	}
+	if logging.CallerAdmin(ctx) {
+		return "", nil
+	}
+	return "", erro.ErrUnauthorized
+}
+
+// About the tenant of a request: the one the caller is bound to, it may not ask for
+// another one. A admin asks for any.
+func requestTenant(ctx context.Context, tenantID string) (string, error) {
+	caller, err := callerScope(ctx)
+	if err != nil {
+		return "", err
+	}
+	if caller == "" {
+		return tenantID, nil
+	}
+	if tenantID != "" && tenantID != caller {
+		return "", erro.ErrHTTPForbiden
+	}
+	return caller, nil
+}
+
+// About drop the cached persons once a write is committed: the entries of their tenant,
+// of the tenant of the caller and of the callers not bound to a tenant
+func (s *WorkerService) invalidatePerson(ctx context.Context, tenantID string, personIDs ...string) {
+	tenants := []string{""}
+	for _, tenant := range []string{tenantID, logging.CallerTenant(ctx)} {
+		if tenant != "" && !slices.Contains(tenants, tenant) {
+			tenants = append(tenants, tenant)
+		}
+	}
+
+	keys := []string{}
+	for _, personID := range personIDs {
+		for _, tenant := range tenants {
+			keys = append(keys, personKey(tenant, personID))
+		}
+	}
+	s.personCache.Invalidate(ctx, keys...)
+}
+
+// the cached persons are encrypted as a whole when the encryption is enabled,
+// the shared backend would keep the PII in plaintext otherwise
+const personCacheField = "cache.person"
+
 // About handle/convert http status code
 func (s *WorkerService) Stat(ctx context.Context) (go_core_pg.PoolStats){
-	childLogger.Info().Str("func","Stat").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+	logging.Ctx(ctx, childLogger).Info().Str("func","Stat").Send()
 
 	return s.workerRepository.Stat(ctx)
 }
 
-// About create a person
-func (s *WorkerService) AddPerson(ctx context.Context, onboarding *model.Onboarding) (*model.Onboarding, error){
-	childLogger.Info().Str("func","AddPerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Interface("onboarding", onboarding).Send()
+// About create a person, refused when it matches a person of the tenant
+// and flagged for review when it may be a duplicate of one
+func (s *WorkerService) AddPerson(ctx context.Context, onboarding *model.Onboarding) (_ *model.Onboarding, err error){
+	if onboarding.Person.TenantID, err = requestTenant(ctx, onboarding.Person.TenantID); err != nil {
+		return nil, err
+	}
+	logging.SetTenant(ctx, onboarding.Person.TenantID)
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddPerson").Interface("onboarding", logging.Mask(onboarding)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.AddPerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
 
-	span := tracerProvider.Span(ctx, "service.AddPerson")
-	defer span.End()
-	
-	tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
-	if err != nil {
+	if err = validation.Person(onboarding.Person); err != nil {
 		return nil, err
 	}
-	defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
 
-	defer func() {
-		if err != nil {
-			tx.Rollback(ctx)
-		} else {
-			tx.Commit(ctx)
+	var res *model.Onboarding
+	var review []model.PersonDuplicate
+	err = s.withTx(ctx, "AddPerson", func(ctx context.Context, tx pgx.Tx) (err error) {
+		review = nil
+		if s.dedupPolicy.IsEnabled() {
+			// the onboardings of the tenant wait for this one, the duplicates they
+			// are searched against are all committed
+			if err = s.workerRepository.LockTenantOnboarding(ctx, tx, onboarding.Person.TenantID); err != nil {
+				return err
+			}
+			var candidates, match []model.PersonDuplicate
+			if candidates, err = s.findDuplicates(ctx, tx, onboarding.Person); err != nil {
+				return err
+			}
+			if match, review = s.dedupPolicy.Classify(candidates); len(match) > 0 {
+				return &model.DuplicateError{Candidates: match}
+			}
 		}
-		span.End()
-	}()
 
-	res, err := s.workerRepository.AddPerson(ctx, tx, onboarding)
+		res, err = s.workerRepository.AddPerson(ctx, tx, onboarding)
+		if err != nil {
+			return err
+		}
+		if err = s.audit(ctx, tx, onboarding.Person.PersonID, onboarding.Person.TenantID, "AddPerson"); err != nil {
+			return err
+		}
+		if err = s.publish(ctx, tx, onboarding.Person.TenantID, onboarding.Person.PersonID, webhook.EventPersonCreated, 0); err != nil {
+			return err
+		}
+		for i := range review {
+			if err = s.workerRepository.AddDuplicate(ctx, tx, &review[i]); err != nil {
+				return err
+			}
+		}
+		for i := range onboarding.Person.Addresses {
+			if _, err = s.workerRepository.AddAddress(ctx, tx, res.Person.ID, &onboarding.Person.Addresses[i]); err != nil {
+				return err
+			}
+		}
+		for i := range onboarding.Person.Documents {
+			if _, err = s.workerRepository.AddDocument(ctx, tx, res.Person.ID, &onboarding.Person.Documents[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var duplicate *model.DuplicateError
+	if errors.As(err, &duplicate) {
+		metrics.ObserveEvent(onboarding.Person.TenantID, "person_duplicate_refused")
+	}
 	if err != nil {
 		return nil, err
 	}
+	s.invalidatePerson(ctx, onboarding.Person.TenantID, onboarding.Person.PersonID)
+	s.workerRepository.ReadRouter.MarkWrite(ctx)
+	metrics.ObserveEvent(onboarding.Person.TenantID, "person_added")
+	if len(review) > 0 {
+		res.Duplicates = review
+		metrics.ObserveEvent(onboarding.Person.TenantID, "person_duplicate_flagged")
+	}
 
 	return res, nil
 }
 
 // About get a person
-func (s *WorkerService) GetPerson(ctx context.Context, onboarding *model.Onboarding) (*model.Onboarding, error){
-	childLogger.Info().Str("func","GetPerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Interface("onboarding", onboarding).Send()
+func (s *WorkerService) GetPerson(ctx context.Context, onboarding *model.Onboarding) (_ *model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetPerson").Interface("onboarding", logging.Mask(onboarding)).Send()
 
-	span := tracerProvider.Span(ctx, "service.GetPerson")
-	defer span.End()
-	
-	res, err := s.workerRepository.GetPerson(ctx, onboarding)
+	ctx, span := tracing.Start(ctx, "service.GetPerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	// a caller bound to a tenant only reads the persons of its tenant
+	tenantID, err := callerScope(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
-}
+	onboarding.Person.TenantID = tenantID
 
-// About update a person
-func (s *WorkerService) UpdatePerson(ctx context.Context, onboarding *model.Onboarding) (*model.Onboarding, error){
-	childLogger.Info().Str("func","UpdatePerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Interface("onboarding", onboarding).Send()
+	if s.personCache == nil {
+		return s.workerRepository.GetPerson(ctx, onboarding)
+	}
 
-	span := tracerProvider.Span(ctx, "service.UpdatePerson")
-	defer span.End()
-	
-	tx, conn, err := s.workerRepository.DatabasePGServer.StartTx(ctx)
+	value, err := s.personCache.GetOrLoad(ctx, personKey(onboarding.Person.TenantID, onboarding.Person.PersonID), func(ctx context.Context) ([]byte, error) {
+		res, err := s.workerRepository.GetPerson(ctx, onboarding)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := s.workerRepository.Keyring.Encrypt(personCacheField, string(value))
+		return []byte(sealed), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	opened, err := s.workerRepository.Keyring.Decrypt(ctx, personCacheField, string(value))
 	if err != nil {
 		return nil, err
 	}
-	defer s.workerRepository.DatabasePGServer.ReleaseTx(conn)
 
-	defer func() {
-		if err != nil {
-			tx.Rollback(ctx)
-		} else {
-			tx.Commit(ctx)
-		}
-		span.End()
-	}()
+	res := model.Onboarding{}
+	if err = json.Unmarshal([]byte(opened), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
 
-	//Check data exists
-	_, err = s.workerRepository.GetPerson(ctx, onboarding)
-	if err != nil {
+// About update a person
+func (s *WorkerService) UpdatePerson(ctx context.Context, onboarding *model.Onboarding) (_ *model.Onboarding, err error){
+	if onboarding.Person.TenantID, err = requestTenant(ctx, onboarding.Person.TenantID); err != nil {
 		return nil, err
 	}
+	logging.SetTenant(ctx, onboarding.Person.TenantID)
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdatePerson").Interface("onboarding", logging.Mask(onboarding)).Send()
 
-	// Do update
-	res_update, err := s.workerRepository.UpdatePerson(ctx, tx, onboarding)
-	if err != nil {
+	ctx, span := tracing.Start(ctx, "service.UpdatePerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	// the child collections have their own routes, they are not updated here
+	onboarding.Person.Addresses = nil
+	onboarding.Person.Documents = nil
+	if err = validation.Person(onboarding.Person); err != nil {
 		return nil, err
 	}
-	if (res_update == 0) {
-		return nil, erro.ErrUpdate
+
+	err = s.withTx(ctx, "UpdatePerson", func(ctx context.Context, tx pgx.Tx) error {
+		//Check data exists, in the primary as a replica may lag behind
+		_, err := s.workerRepository.GetPerson(database.UsePrimary(ctx), onboarding)
+		if err != nil {
+			return err
+		}
+
+		// Do update
+		res_update, err := s.workerRepository.UpdatePerson(ctx, tx, onboarding)
+		if err != nil {
+			return err
+		}
+		if (res_update == 0) {
+			return erro.ErrUpdate
+		}
+		if err = s.audit(ctx, tx, onboarding.Person.PersonID, onboarding.Person.TenantID, "UpdatePerson"); err != nil {
+			return err
+		}
+		return s.publish(ctx, tx, onboarding.Person.TenantID, onboarding.Person.PersonID, webhook.EventPersonUpdated, 0)
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.invalidatePerson(ctx, onboarding.Person.TenantID, onboarding.Person.PersonID)
+	s.workerRepository.ReadRouter.MarkWrite(ctx)
+	metrics.ObserveEvent(onboarding.Person.TenantID, "person_updated")
 
 	return onboarding, nil
 }
 
 // About list a person
-func (s *WorkerService) ListPerson(ctx context.Context, onboarding *model.Onboarding) (*[]model.Onboarding, error){
-	childLogger.Info().Str("func","ListPerson").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Interface("onboarding", onboarding).Send()
+func (s *WorkerService) ListPerson(ctx context.Context, onboarding *model.Onboarding) (_ *[]model.Onboarding, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListPerson").Interface("onboarding", logging.Mask(onboarding)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListPerson", tracing.PersonAttributes(onboarding.Person.TenantID, onboarding.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	// a caller bound to a tenant only lists the persons of its tenant
+	if onboarding.Person.TenantID, err = callerScope(ctx); err != nil {
+		return nil, err
+	}
 
-	span := tracerProvider.Span(ctx, "service.ListPerson")
-	defer span.End()
-	
 	res, err := s.workerRepository.ListPerson(ctx, onboarding)
 	if err != nil {
 		return nil, err
@@ -142,16 +349,27 @@ func (s *WorkerService) ListPerson(ctx context.Context, onboarding *model.Onboar
 }
 
 // About upload file
-func (s *WorkerService) UploadFile(ctx context.Context, onboardingFile *model.OnboardingFile) (error){
-	childLogger.Info().Str("func","UploadFile").Interface("trace-resquest-id", ctx.Value("trace-request-id")).Send()
+func (s *WorkerService) UploadFile(ctx context.Context, onboardingFile *model.OnboardingFile) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UploadFile").Send()
 
-	span := tracerProvider.Span(ctx, "service.UploadFile")
-	defer span.End()
+	ctx, span := tracing.Start(ctx, "service.UploadFile")
+	defer func() { tracing.End(span, err) }()
 	
 	onboardingFile.BucketName = s.awsService.BucketName
 	onboardingFile.FilePath = s.awsService.FilePath
 
-	err := s.workerBucketS3.PutObject(	ctx, 
+	// the files of a person are kept apart, so they are exported and erased with it
+	if onboardingFile.PersonID != "" {
+		if s.personBucket == nil {
+			return erro.ErrBadRequest
+		}
+		if _, err = s.workerRepository.GetPerson(database.UsePrimary(ctx), &model.Onboarding{Person: &model.Person{PersonID: onboardingFile.PersonID}}); err != nil {
+			return err
+		}
+		onboardingFile.FilePath = s.personBucket.Prefix(onboardingFile.PersonID)
+	}
+
+	err = s.workerBucketS3.PutObject(	ctx, 
 										onboardingFile.BucketName,
 										onboardingFile.FilePath, 
 										onboardingFile.FileName,
@@ -159,6 +377,8 @@ func (s *WorkerService) UploadFile(ctx context.Context, onboardingFile *model.On
 	if err != nil {
 		return err
 	}
+	metrics.ObserveUpload(len(onboardingFile.File))
+	metrics.ObserveEvent("", "file_uploaded")
 
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,213 @@
+package service
+
+import(
+	"context"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/validation"
+	"github.com/go-onboarding/internal/core/webhook"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// About change a child collection of a person in a transaction, the person row is
+// locked first and the cached person is dropped once committed. The persons of the
+// tenants other than the one of the caller are not found.
+func (s *WorkerService) withPerson(ctx context.Context, operation string, personID string, fn func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error) error {
+	callerTenant, err := callerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tenantID := ""
+	err = s.withTx(ctx, operation, func(ctx context.Context, tx pgx.Tx) error {
+		id, tenant, err := s.workerRepository.LockPerson(ctx, tx, personID)
+		if err != nil {
+			return err
+		}
+		if callerTenant != "" && tenant != callerTenant {
+			return erro.ErrNotFound
+		}
+		tenantID = tenant
+		if err := fn(ctx, tx, id, tenant); err != nil {
+			return err
+		}
+		return s.audit(ctx, tx, personID, tenant, operation)
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidatePerson(ctx, tenantID, personID)
+	s.workerRepository.ReadRouter.MarkWrite(ctx)
+
+	return nil
+}
+
+// About the not found of a child row
+func affected(rows int64) error {
+	if rows == 0 {
+		return erro.ErrNotFound
+	}
+	return nil
+}
+
+// About list the addresses of a person
+func (s *WorkerService) ListAddress(ctx context.Context, personID string) (_ []model.Address, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListAddress").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	tenantID, err := callerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.workerRepository.ListAddress(ctx, tenantID, personID)
+}
+
+// About add a address to a person
+func (s *WorkerService) AddAddress(ctx context.Context, personID string, address *model.Address) (_ *model.Address, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddAddress").Interface("address", logging.Mask(address)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.AddAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err = validation.Address(address); err != nil {
+		return nil, err
+	}
+
+	err = s.withPerson(ctx, "AddAddress", personID, func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error {
+		_, err := s.workerRepository.AddAddress(ctx, tx, id, address)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// About update a address of a person
+func (s *WorkerService) UpdateAddress(ctx context.Context, personID string, address *model.Address) (_ *model.Address, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateAddress").Interface("address", logging.Mask(address)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.UpdateAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err = validation.Address(address); err != nil {
+		return nil, err
+	}
+
+	err = s.withPerson(ctx, "UpdateAddress", personID, func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error {
+		rows, err := s.workerRepository.UpdateAddress(ctx, tx, id, address)
+		if err != nil {
+			return err
+		}
+		return affected(rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// About delete a address of a person
+func (s *WorkerService) DeleteAddress(ctx context.Context, personID string, addressID int) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteAddress").Int("address_id", addressID).Send()
+
+	ctx, span := tracing.Start(ctx, "service.DeleteAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	return s.withPerson(ctx, "DeleteAddress", personID, func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error {
+		rows, err := s.workerRepository.DeleteAddress(ctx, tx, id, addressID)
+		if err != nil {
+			return err
+		}
+		return affected(rows)
+	})
+}
+
+// About list the identity documents of a person
+func (s *WorkerService) ListDocument(ctx context.Context, personID string) (_ []model.Document, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListDocument").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	tenantID, err := callerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.workerRepository.ListDocument(ctx, tenantID, personID)
+}
+
+// About add a identity document to a person
+func (s *WorkerService) AddDocument(ctx context.Context, personID string, document *model.Document) (_ *model.Document, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddDocument").Interface("document", logging.Mask(document)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.AddDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err = validation.Document(document); err != nil {
+		return nil, err
+	}
+
+	err = s.withPerson(ctx, "AddDocument", personID, func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error {
+		if _, err := s.workerRepository.AddDocument(ctx, tx, id, document); err != nil {
+			return err
+		}
+		return s.publish(ctx, tx, tenantID, personID, webhook.EventDocumentAdded, document.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// About update a identity document of a person
+func (s *WorkerService) UpdateDocument(ctx context.Context, personID string, document *model.Document) (_ *model.Document, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateDocument").Interface("document", logging.Mask(document)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.UpdateDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err = validation.Document(document); err != nil {
+		return nil, err
+	}
+
+	err = s.withPerson(ctx, "UpdateDocument", personID, func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error {
+		rows, err := s.workerRepository.UpdateDocument(ctx, tx, id, document)
+		if err != nil {
+			return err
+		}
+		if err := affected(rows); err != nil {
+			return err
+		}
+		return s.publish(ctx, tx, tenantID, personID, webhook.EventDocumentUpdated, document.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// About delete a identity document of a person
+func (s *WorkerService) DeleteDocument(ctx context.Context, personID string, documentID int) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteDocument").Int("document_id", documentID).Send()
+
+	ctx, span := tracing.Start(ctx, "service.DeleteDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	return s.withPerson(ctx, "DeleteDocument", personID, func(ctx context.Context, tx pgx.Tx, id int, tenantID string) error {
+		rows, err := s.workerRepository.DeleteDocument(ctx, tx, id, documentID)
+		if err != nil {
+			return err
+		}
+		if err := affected(rows); err != nil {
+			return err
+		}
+		return s.publish(ctx, tx, tenantID, personID, webhook.EventDocumentDeleted, documentID)
+	})
+}
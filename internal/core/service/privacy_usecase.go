@@ -0,0 +1,217 @@
+package service
+
+import(
+	"io"
+	"time"
+	"context"
+	"strings"
+	"net/url"
+	"archive/zip"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/adapter/bucket"
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// About set the bucket of the person files, nil when there is no upload bucket
+func (s *WorkerService) SetPersonBucket(personBucket *bucket.PersonBucket) {
+	s.personBucket = personBucket
+}
+
+// About record a audit event of a person in the transaction of the change,
+// the actor is the client of the request (X-Client-Id or the client ip)
+func (s *WorkerService) audit(ctx context.Context, tx pgx.Tx, personID string, tenantID string, action string) error {
+	audit := model.Audit{PersonID: personID, TenantID: tenantID, Action: action}
+	if correlation := logging.CorrelationFrom(ctx); correlation != nil {
+		audit.Actor = correlation.Client
+		audit.TraceID = correlation.TraceID
+		if audit.TenantID == "" {
+			audit.TenantID = correlation.Tenant
+		}
+	}
+	return s.workerRepository.AddAudit(ctx, tx, &audit)
+}
+
+// About gather all the data held about a person: the person with its addresses and
+// documents metadata, the audit history and the files uploaded for it.
+// The export is audited before it is returned. A caller bound to a tenant only
+// exports the persons of its tenant, only a admin calls without one.
+func (s *WorkerService) ExportPerson(ctx context.Context, personID string) (_ *model.PersonExport, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ExportPerson").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ExportPerson", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	tenantID, err := callerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// from the primary and not the cache, the export must be complete
+	res, err := s.workerRepository.GetPerson(database.UsePrimary(ctx), &model.Onboarding{Person: &model.Person{	PersonID: personID,
+																													TenantID: tenantID}})
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.personBucket.List(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withTx(ctx, "ExportPerson", func(ctx context.Context, tx pgx.Tx) error {
+		return s.audit(ctx, tx, personID, res.Person.TenantID, "ExportPerson")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit, err := s.workerRepository.ListAudit(database.UsePrimary(ctx), personID)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveEvent(res.Person.TenantID, "person_exported")
+
+	return &model.PersonExport{	Person: res.Person,
+								Audit: audit,
+								Files: files,
+								ExportedAt: time.Now(),
+	}, nil
+}
+
+// About write a export as a zip: person.json, audit.json and the files under files/
+func (s *WorkerService) WriteExportArchive(ctx context.Context, export *model.PersonExport, out io.Writer) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","WriteExportArchive").Int("files", len(export.Files)).Send()
+
+	ctx, span := tracing.Start(ctx, "service.WriteExportArchive", tracing.PersonAttributes("", export.Person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	archive := zip.NewWriter(out)
+
+	if err = writeJSON(archive, "person.json", export.Person); err != nil {
+		return err
+	}
+	if err = writeJSON(archive, "audit.json", export.Audit); err != nil {
+		return err
+	}
+
+	for _, key := range export.Files {
+		prefix := s.personBucket.Prefix(export.Person.PersonID)
+		name, err := url.PathUnescape(strings.TrimPrefix(key, prefix))
+		if err != nil {
+			name = strings.TrimPrefix(key, prefix)
+		}
+		if err = s.copyFile(ctx, archive, "files/" + name, key); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// About copy a file of the bucket into the archive
+func (s *WorkerService) copyFile(ctx context.Context, archive *zip.Writer, name string, key string) error {
+	body, err := s.personBucket.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, body)
+	return err
+}
+
+// About write a value as a json entry of the archive
+func writeJSON(archive *zip.Writer, name string, value any) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// About erase a person: the row is anonymized, the child collections and the files
+// are deleted, its traces in the duplicates, webhook deliveries and job results are
+// purged and a tombstone is recorded. It is idempotent, a erasure run again returns
+// the tombstone and deletes the files left by a failed run. A caller bound to a
+// tenant only erases the persons of its tenant, only a admin calls without one.
+func (s *WorkerService) ErasePerson(ctx context.Context, personID string) (_ *model.Tombstone, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ErasePerson").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ErasePerson", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	callerTenant, err := callerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	erased := false
+	tenantID := ""
+	err = s.withTx(ctx, "ErasePerson", func(ctx context.Context, tx pgx.Tx) error {
+		id, tenant, err := s.workerRepository.LockPerson(ctx, tx, personID)
+		if err == erro.ErrNotFound {
+			// erased already (or never existed), told apart by the tombstone
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if callerTenant != "" && tenant != callerTenant {
+			return erro.ErrNotFound
+		}
+		tenantID = tenant
+
+		tombstone := model.Tombstone{PersonID: personID}
+		if correlation := logging.CorrelationFrom(ctx); correlation != nil {
+			tombstone.Actor = correlation.Client
+			tombstone.TraceID = correlation.TraceID
+		}
+		if err := s.workerRepository.ErasePerson(ctx, tx, id, &tombstone); err != nil {
+			return err
+		}
+		erased = true
+		return s.audit(ctx, tx, personID, tombstone.TenantID, "ErasePerson")
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidatePerson(ctx, tenantID, personID)
+	s.workerRepository.ReadRouter.MarkWrite(ctx)
+
+	tombstone, err := s.workerRepository.GetTombstone(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	if callerTenant != "" && tombstone.TenantID != callerTenant {
+		return nil, erro.ErrNotFound
+	}
+
+	deleted, err := s.personBucket.DeleteAll(ctx, personID)
+	if deleted > 0 || (err == nil && tombstone.ObjectsDeletedAt == nil) {
+		if errTombstone := s.workerRepository.AddTombstoneObjects(ctx, tombstone, deleted); errTombstone != nil && err == nil {
+			err = errTombstone
+		}
+	}
+	if err != nil {
+		// the person is erased, the files left are deleted when it is run again
+		logging.Ctx(ctx, childLogger).Error().Err(err).Int("deleted", deleted).Msg("error delete person files")
+		return nil, err
+	}
+	if erased {
+		metrics.ObserveEvent(tombstone.TenantID, "person_erased")
+	}
+
+	return tombstone, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import(
+	"context"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/search"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// the page size of a search, when not given and at most
+const (
+	searchLimit		= 20
+	searchMaxLimit	= 100
+)
+
+// About search the persons of a tenant by the name, accent insensitive and fuzzy,
+// the most relevant first with the words of the name matching the query highlighted
+func (s *WorkerService) SearchPerson(ctx context.Context, personSearch *model.PersonSearch) (_ *model.PersonSearch, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","SearchPerson").Int("limit", personSearch.Limit).Int("offset", personSearch.Offset).Send()
+
+	ctx, span := tracing.Start(ctx, "service.SearchPerson", tracing.PersonAttributes(personSearch.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if personSearch.TenantID, err = requestTenant(ctx, personSearch.TenantID); err != nil {
+		return nil, err
+	}
+
+	// a query of punctuation only has no words to match
+	if search.Normalize(personSearch.Query) == "" || personSearch.Offset < 0 || personSearch.Limit < 0 {
+		return nil, erro.ErrBadRequest
+	}
+	if personSearch.Limit == 0 {
+		personSearch.Limit = searchLimit
+	}
+	if personSearch.Limit > searchMaxLimit {
+		personSearch.Limit = searchMaxLimit
+	}
+
+	matches, err := s.workerRepository.SearchPerson(ctx, personSearch.TenantID, personSearch.Query, personSearch.Limit, personSearch.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	personSearch.NextOffset = nil
+	if len(matches) > personSearch.Limit {
+		matches = matches[:personSearch.Limit]
+		next := personSearch.Offset + personSearch.Limit
+		personSearch.NextOffset = &next
+	}
+	// highlighted here, the names may be encrypted in the database
+	for i := range matches {
+		matches[i].Highlight = search.Highlight(matches[i].Person.Name, personSearch.Query)
+	}
+	personSearch.Items = matches
+
+	return personSearch, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"io"
+	"time"
+	"context"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/database/pgtest"
+	"github.com/go-onboarding/internal/adapter/notify"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/webhook"
+	"github.com/go-onboarding/internal/infra/credential"
+	"github.com/go-onboarding/internal/infra/retry"
+
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// the credentials of the fake database, it takes any
+type staticCredentials struct{}
+
+func (staticCredentials) Credentials(ctx context.Context) (credential.Credentials, error) {
+	return credential.Credentials{User: "test", Password: "test"}, nil
+}
+
+// About a receiver answering the statuses in turn, it verifies the signature of each delivery
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, *int) {
+	received := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if err := webhook.Verify(testWebhookSecret, req.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+			t.Errorf("delivery %d: %v", received + 1, err)
+		}
+		if req.Header.Get(webhook.HeaderEvent) != webhook.EventPersonCreated || req.Header.Get(webhook.HeaderDelivery) != "7" {
+			t.Errorf("delivery %d: headers %v", received + 1, req.Header)
+		}
+		rw.WriteHeader(statuses[min(received, len(statuses) - 1)])
+		// echoed back, it must not be kept in the error of the delivery
+		rw.Write(body)
+		received++
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver, &received
+}
+
+// About a service delivering to the receivers, the attempts are recorded in a fake database
+func newWebhookService(t *testing.T, policy retry.Policy) (*WorkerService, *pgtest.Server) {
+	fake, err := pgtest.NewServer(func(sql string, args []*string) (pgtest.Result, error) {
+		switch {
+		case strings.Contains(sql, "INSERT INTO public.webhook_attempt"):
+			return pgtest.Result{Tag: "INSERT 0 1"}, nil
+		case strings.Contains(sql, "UPDATE public.webhook_delivery"):
+			return pgtest.Result{Tag: "UPDATE 1"}, nil
+		}
+		return pgtest.Result{}, nil
+	})
+	if err != nil {
+		t.Fatalf("fake database: %v", err)
+	}
+	t.Cleanup(func() { fake.Close() })
+
+	host, port := fake.HostPort()
+	databasePGServer, err := database.NewDatabasePGServer(context.Background(),
+														go_core_pg.DatabaseConfig{Host: host, Port: port, DatabaseName: "onboarding", DbMax_Connection: 2},
+														staticCredentials{})
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	t.Cleanup(databasePGServer.CloseConnection)
+
+	s := NewWorkerService(database.NewWorkerRepository(databasePGServer), nil, &model.AwsService{})
+	// the receivers are on the loopback
+	s.SetWebhook(notify.NewNotifier(5 * time.Second, true), policy, true)
+	return s, fake
+}
+
+func newDelivery(url string) *model.WebhookDelivery {
+	return &model.WebhookDelivery{	ID: 7,
+									SubscriptionID: 3,
+									Event: model.WebhookEvent{	ID: "evt_1",
+																Event: webhook.EventPersonCreated,
+																PersonID: "P-1",
+																OccurredAt: time.Now().UTC(),
+									},
+									Status: database.WebhookPending,
+									URL: url,
+									Secret: testWebhookSecret,
+	}
+}
+
+// About the args of the last update of the delivery recorded
+func lastDeliveryUpdate(t *testing.T, fake *pgtest.Server) []*string {
+	var args []*string
+	for _, query := range fake.Queries() {
+		if strings.Contains(query.SQL, "UPDATE public.webhook_delivery") {
+			args = query.Args
+		}
+	}
+	if args == nil {
+		t.Fatalf("delivery update not recorded")
+	}
+	return args
+}
+
+func TestDeliverSigned(t *testing.T) {
+	receiver, received := newReceiver(t, http.StatusOK)
+	s, fake := newWebhookService(t, retry.Policy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour})
+
+	delivery := newDelivery(receiver.URL)
+	s.deliver(context.Background(), delivery)
+
+	if *received != 1 {
+		t.Fatalf("%d deliveries received, want 1", *received)
+	}
+	if delivery.Status != database.WebhookDelivered || delivery.DeliveredAt == nil || delivery.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery %+v, want delivered", delivery)
+	}
+	if args := lastDeliveryUpdate(t, fake); *args[1] != database.WebhookDelivered || *args[2] != "1" {
+		t.Errorf("recorded status %s attempts %s", *args[1], *args[2])
+	}
+}
+
+func TestDeliverBackoffAndDeadLetter(t *testing.T) {
+	receiver, received := newReceiver(t, http.StatusInternalServerError)
+	policy := retry.Policy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	s, fake := newWebhookService(t, policy)
+
+	delivery := newDelivery(receiver.URL)
+
+	// the wait after the attempt n is at most BaseDelay * 2^(n-1)
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		start := time.Now()
+		s.deliver(context.Background(), delivery)
+
+		if delivery.Status != database.WebhookPending || delivery.Attempts != attempt {
+			t.Fatalf("attempt %d: status %s attempts %d, want pending", attempt, delivery.Status, delivery.Attempts)
+		}
+		ceiling := policy.BaseDelay << (attempt - 1)
+		if delivery.NextAttemptAt == nil || delivery.NextAttemptAt.Before(start) || delivery.NextAttemptAt.After(time.Now().Add(ceiling)) {
+			t.Errorf("attempt %d: next attempt %v, want within %s", attempt, delivery.NextAttemptAt, ceiling)
+		}
+		if delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError != "endpoint answered 500" {
+			t.Errorf("attempt %d: last status %d error %q", attempt, delivery.LastStatusCode, delivery.LastError)
+		}
+	}
+
+	// the attempts are over, it goes to the dead letter
+	next := delivery.NextAttemptAt
+	s.deliver(context.Background(), delivery)
+
+	if *received != policy.MaxAttempts {
+		t.Errorf("%d deliveries received, want %d", *received, policy.MaxAttempts)
+	}
+	if delivery.Status != database.WebhookDead || delivery.Attempts != policy.MaxAttempts || delivery.NextAttemptAt != next {
+		t.Errorf("delivery %+v, want dead", delivery)
+	}
+	args := lastDeliveryUpdate(t, fake)
+	if *args[1] != database.WebhookDead || *args[2] != "3" {
+		t.Errorf("recorded status %s attempts %s, want dead after 3", *args[1], *args[2])
+	}
+	if strings.Contains(*args[5], "evt_1") {
+		t.Errorf("recorded error %q keeps the response body", *args[5])
+	}
+}
@@ -0,0 +1,352 @@
+package service
+
+import(
+	"sync"
+	"time"
+	"context"
+	"strconv"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/notify"
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/core/validation"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/retry"
+	"github.com/go-onboarding/internal/infra/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The deliveries of a subscription listed by page
+const (
+	webhookDeliveryLimit	= 50
+	webhookDeliveryMaxLimit	= 200
+)
+
+// the error of a failed attempt is kept up to it
+const webhookMaxError = 1000
+
+// About enable the webhooks: the events are delivered by the notifier, a failed delivery
+// is tried again with the backoff of the policy until its attempts are over (dead letter).
+// The endpoints may be plain http when allowHTTP. A nil notifier disables the webhooks.
+func (s *WorkerService) SetWebhook(notifier *notify.Notifier, policy retry.Policy, allowHTTP bool) {
+	s.notifier = notifier
+	s.webhookPolicy = policy
+	s.webhookAllowHTTP = allowHTTP
+}
+
+// About a random id, prefixed by its kind
+func randomID(prefix string, size int) (string, error) {
+	value := make([]byte, size)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(value), nil
+}
+
+// About publish a event of a person in the transaction of the change, it is delivered
+// to the subscriptions of the tenant once committed. A empty tenant is the one of the person.
+func (s *WorkerService) publish(ctx context.Context, tx pgx.Tx, tenantID string, personID string, event string, documentID int) error {
+	if s.notifier == nil {
+		return nil
+	}
+
+	eventID, err := randomID("evt_", 16)
+	if err != nil {
+		return err
+	}
+	_, err = s.workerRepository.AddWebhookEvent(ctx, tx, &model.WebhookEvent{	ID: eventID,
+																				Event: event,
+																				TenantID: tenantID,
+																				PersonID: personID,
+																				DocumentID: documentID,
+																				OccurredAt: time.Now().UTC(),
+	})
+	return err
+}
+
+// About add a webhook subscription to the tenant of the caller (X-Tenant-Id), its
+// secret is returned this once only
+func (s *WorkerService) AddWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) (_ *model.WebhookSubscription, err error){
+	subscription.TenantID = logging.CallerTenant(ctx)
+	logging.SetTenant(ctx, subscription.TenantID)
+	logging.Ctx(ctx, childLogger).Info().Str("func","AddWebhookSubscription").Send()
+
+	ctx, span := tracing.Start(ctx, "service.AddWebhookSubscription", tracing.PersonAttributes(subscription.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	if err = validation.WebhookSubscription(subscription, s.webhookAllowHTTP); err != nil {
+		return nil, err
+	}
+
+	if subscription.Secret, err = randomID("whsec_", 32); err != nil {
+		return nil, err
+	}
+	subscription.IsActive = true
+	if err = s.workerRepository.AddWebhookSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	metrics.ObserveEvent(subscription.TenantID, "webhook_subscribed")
+
+	return subscription, nil
+}
+
+// About list the webhook subscriptions of the tenant of the caller
+func (s *WorkerService) ListWebhookSubscription(ctx context.Context) (_ []model.WebhookSubscription, err error){
+	tenantID := logging.CallerTenant(ctx)
+	logging.SetTenant(ctx, tenantID)
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListWebhookSubscription").Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListWebhookSubscription", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	return s.workerRepository.ListWebhookSubscription(ctx, tenantID)
+}
+
+// About get a webhook subscription of the tenant of the caller, without its secret
+func (s *WorkerService) GetWebhookSubscription(ctx context.Context, id int64) (_ *model.WebhookSubscription, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetWebhookSubscription").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.GetWebhookSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	// in the primary, it is read before a update
+	return s.workerRepository.GetWebhookSubscription(database.UsePrimary(ctx), logging.CallerTenant(ctx), id)
+}
+
+// About update the endpoint, the events and the state of a webhook subscription of the
+// tenant of the caller, a subscription not active is not delivered to (its deliveries wait)
+func (s *WorkerService) UpdateWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) (_ *model.WebhookSubscription, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","UpdateWebhookSubscription").Int64("id", subscription.ID).Send()
+
+	ctx, span := tracing.Start(ctx, "service.UpdateWebhookSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	if err = validation.WebhookSubscription(subscription, s.webhookAllowHTTP); err != nil {
+		return nil, err
+	}
+	subscription.TenantID = logging.CallerTenant(ctx)
+	return s.workerRepository.UpdateWebhookSubscription(ctx, subscription)
+}
+
+// About delete a webhook subscription of the tenant of the caller, its pending
+// deliveries and logs are dropped
+func (s *WorkerService) DeleteWebhookSubscription(ctx context.Context, id int64) (err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","DeleteWebhookSubscription").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.DeleteWebhookSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return erro.ErrWebhookDisabled
+	}
+	return s.workerRepository.DeleteWebhookSubscription(ctx, logging.CallerTenant(ctx), id)
+}
+
+// About list the deliveries of a subscription of the tenant of the caller by page, newest first
+func (s *WorkerService) ListWebhookDelivery(ctx context.Context, deliveryList *model.WebhookDeliveryList) (_ *model.WebhookDeliveryList, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","ListWebhookDelivery").Int64("subscription_id", deliveryList.SubscriptionID).Send()
+
+	ctx, span := tracing.Start(ctx, "service.ListWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	if deliveryList.Offset < 0 || deliveryList.Limit < 0 {
+		return nil, erro.ErrBadRequest
+	}
+	if deliveryList.Status != "" && validation.OneOf(database.WebhookPending, database.WebhookDelivered, database.WebhookDead)(deliveryList.Status) != nil {
+		return nil, erro.ErrBadRequest
+	}
+	if deliveryList.Limit == 0 {
+		deliveryList.Limit = webhookDeliveryLimit
+	}
+	if deliveryList.Limit > webhookDeliveryMaxLimit {
+		deliveryList.Limit = webhookDeliveryMaxLimit
+	}
+
+	tenantID := logging.CallerTenant(ctx)
+	if _, err = s.workerRepository.GetWebhookSubscription(ctx, tenantID, deliveryList.SubscriptionID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.workerRepository.ListWebhookDelivery(ctx, tenantID, deliveryList.SubscriptionID, deliveryList.Status, deliveryList.Limit, deliveryList.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryList.NextOffset = nil
+	if len(deliveries) > deliveryList.Limit {
+		deliveries = deliveries[:deliveryList.Limit]
+		next := deliveryList.Offset + deliveryList.Limit
+		deliveryList.NextOffset = &next
+	}
+	deliveryList.Items = deliveries
+
+	return deliveryList, nil
+}
+
+// About get a delivery of a subscription of the tenant of the caller with the log of its attempts
+func (s *WorkerService) GetWebhookDelivery(ctx context.Context, subscriptionID int64, id int64) (_ *model.WebhookDelivery, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","GetWebhookDelivery").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.GetWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	return s.workerRepository.GetWebhookDelivery(ctx, logging.CallerTenant(ctx), subscriptionID, id)
+}
+
+// About send a delivery of the tenant of the caller again now, a dead one is taken out
+// of the dead letter
+func (s *WorkerService) RetryWebhookDelivery(ctx context.Context, subscriptionID int64, id int64) (_ *model.WebhookDelivery, err error){
+	logging.Ctx(ctx, childLogger).Info().Str("func","RetryWebhookDelivery").Int64("id", id).Send()
+
+	ctx, span := tracing.Start(ctx, "service.RetryWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	if s.notifier == nil {
+		return nil, erro.ErrWebhookDisabled
+	}
+	res, err := s.workerRepository.RetryWebhookDelivery(ctx, logging.CallerTenant(ctx), subscriptionID, id)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveEvent(res.Event.TenantID, "webhook_retried")
+
+	return res, nil
+}
+
+// About deliver the pending webhook deliveries due every interval until the context is
+// done. The batches are claimed back to back until none is left, a batch is delivered
+// by concurrency endpoints at a time. A claim is leased for as long as a batch may take,
+// a delivery of a replica gone is sent again once its lease is over.
+func (s *WorkerService) DispatchWebhook(ctx context.Context, interval time.Duration, batch int, concurrency int, timeout time.Duration) {
+	childLogger.Info().Str("func","DispatchWebhook").Str("interval", interval.String()).Int("batch", batch).Int("concurrency", concurrency).Send()
+
+	lease := timeout * time.Duration((batch + concurrency - 1) / concurrency) + interval
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				deliveries, err := s.workerRepository.ClaimWebhookDelivery(ctx, batch, lease)
+				if err != nil {
+					logging.Ctx(ctx, childLogger).Error().Err(err).Msg("error claim webhook delivery")
+					break
+				}
+
+				slots := make(chan struct{}, concurrency)
+				var wg sync.WaitGroup
+				for i := range deliveries {
+					slots <- struct{}{}
+					wg.Add(1)
+					go func(delivery *model.WebhookDelivery) {
+						defer func() { <-slots; wg.Done() }()
+						s.deliver(ctx, delivery)
+					}(&deliveries[i])
+				}
+				wg.Wait()
+
+				if len(deliveries) < batch {
+					break
+				}
+			}
+		}
+	}
+}
+
+// About send a delivery and record the attempt: it is delivered on a 2xx, else it is
+// tried again after the backoff or it is dead once the attempts are over
+func (s *WorkerService) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	ctx, span := tracing.Start(ctx, "service.deliver", tracing.PersonAttributes(delivery.Event.TenantID, delivery.Event.PersonID)...)
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		logging.Ctx(ctx, childLogger).Error().Err(err).Int64("delivery_id", delivery.ID).Msg("error marshal webhook event")
+		return
+	}
+
+	start := time.Now()
+	statusCode, err := s.notifier.Post(ctx, delivery.URL, delivery.Secret, strconv.FormatInt(delivery.ID, 10), delivery.Event.Event, body)
+	elapsed := time.Since(start)
+
+	delivery.Attempts++
+	attempt := model.WebhookAttempt{	Attempt: delivery.Attempts,
+										StatusCode: statusCode,
+										DurationMs: int(elapsed.Milliseconds()),
+										AttemptedAt: start,
+	}
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	result := database.WebhookDelivered
+	switch {
+	case err == nil:
+		delivery.Status = database.WebhookDelivered
+		delivery.DeliveredAt = &start
+	case delivery.Attempts >= s.webhookPolicy.MaxAttempts:
+		result = database.WebhookDead
+		delivery.Status = database.WebhookDead
+	default:
+		result = "failed"
+		next := time.Now().Add(s.webhookPolicy.Backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+		if len(attempt.Error) > webhookMaxError {
+			attempt.Error = attempt.Error[:webhookMaxError]
+		}
+		delivery.LastError = attempt.Error
+	}
+	metrics.ObserveWebhook(delivery.Event.Event, result, elapsed)
+
+	errAttempt := s.withTx(ctx, "AddWebhookAttempt", func(ctx context.Context, tx pgx.Tx) error {
+		return s.workerRepository.AddWebhookAttempt(ctx, tx, delivery, &attempt)
+	})
+	if errAttempt != nil {
+		// the delivery is claimed again when its lease is over
+		logging.Ctx(ctx, childLogger).Error().Err(errAttempt).Int64("delivery_id", delivery.ID).Msg("error record webhook attempt")
+	}
+
+	event := logging.Ctx(ctx, childLogger).Info()
+	if result == database.WebhookDead {
+		event = logging.Ctx(ctx, childLogger).Error()
+	} else if err != nil {
+		event = logging.Ctx(ctx, childLogger).Warn()
+	}
+	event.Err(err).
+		Int64("delivery_id", delivery.ID).
+		Str("event", delivery.Event.Event).
+		Int("attempt", delivery.Attempts).
+		Int("status_code", statusCode).
+		Str("result", result).
+		Msg("webhook delivery")
+}
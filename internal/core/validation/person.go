@@ -0,0 +1,115 @@
+package validation
+
+import(
+	"strings"
+	"strconv"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+var (
+	AddressTypes	= []string{"home", "work", "billing", "mailing"}
+	DocumentTypes	= []string{"national_id", "passport", "driver_license", "residence_permit", "other"}
+)
+
+// About normalize and validate a person and its child collections
+func Person(person *model.Person) error {
+	var errs Errors
+
+	person.Name = strings.TrimSpace(person.Name)
+	person.PersonType = strings.ToLower(strings.TrimSpace(person.PersonType))
+	if person.PersonType == "" {
+		person.PersonType = PersonNatural
+	}
+	person.Email = strings.ToLower(strings.TrimSpace(person.Email))
+	person.Phone = strings.ReplaceAll(strings.TrimSpace(person.Phone), " ", "")
+	person.Nationality = strings.ToUpper(strings.TrimSpace(person.Nationality))
+
+	errs.Check("person_id", person.PersonID, Required(100))
+	errs.Check("name", person.Name, Required(200))
+	errs.Check("person_type", person.PersonType, OneOf(PersonTypes...))
+	if person.BirthDate != "" {
+		errs.Check("birth_date", person.BirthDate, BirthDate)
+	}
+	if person.Email != "" {
+		errs.Check("email", person.Email, Email)
+	}
+	if person.Phone != "" {
+		errs.Check("phone", person.Phone, Phone)
+	}
+	if person.Nationality != "" {
+		errs.Check("nationality", person.Nationality, Country)
+	}
+	// the tax id is checked against the nationality, the country of incorporation of a legal person,
+	// so it is required with a tax id: a CPF or CNPJ without it would skip the check digits
+	if person.TaxID != "" {
+		if person.Nationality == "" {
+			errs.Add("nationality", "is required with a tax id")
+		}
+		taxID, err := TaxID(person.Nationality, person.PersonType, person.TaxID)
+		person.TaxID = taxID
+		if err != nil {
+			errs.Add("tax_id", err.Error())
+		}
+		errs.Check("tax_id", person.TaxID, MaxLen(50))
+	}
+
+	for i := range person.Addresses {
+		address(&errs, "addresses[" + strconv.Itoa(i) + "].", &person.Addresses[i])
+	}
+	for i := range person.Documents {
+		document(&errs, "documents[" + strconv.Itoa(i) + "].", &person.Documents[i])
+	}
+
+	return errs.Err()
+}
+
+// About normalize and validate a address
+func Address(addr *model.Address) error {
+	var errs Errors
+	address(&errs, "", addr)
+	return errs.Err()
+}
+
+// About normalize and validate a identity document
+func Document(doc *model.Document) error {
+	var errs Errors
+	document(&errs, "", doc)
+	return errs.Err()
+}
+
+func address(errs *Errors, prefix string, addr *model.Address) {
+	addr.Type = strings.ToLower(strings.TrimSpace(addr.Type))
+	addr.Country = strings.ToUpper(strings.TrimSpace(addr.Country))
+	addr.PostalCode = strings.TrimSpace(addr.PostalCode)
+
+	errs.Check(prefix + "type", addr.Type, OneOf(AddressTypes...))
+	errs.Check(prefix + "street", addr.Street, Required(200))
+	errs.Check(prefix + "number", addr.Number, MaxLen(20))
+	errs.Check(prefix + "complement", addr.Complement, MaxLen(100))
+	errs.Check(prefix + "district", addr.District, MaxLen(100))
+	errs.Check(prefix + "city", addr.City, Required(100))
+	errs.Check(prefix + "state", addr.State, MaxLen(50))
+	errs.Check(prefix + "postal_code", addr.PostalCode, Required(20))
+	errs.Check(prefix + "country", addr.Country, Country)
+}
+
+func document(errs *Errors, prefix string, doc *model.Document) {
+	doc.Type = strings.ToLower(strings.TrimSpace(doc.Type))
+	doc.Number = strings.TrimSpace(doc.Number)
+	doc.IssuingCountry = strings.ToUpper(strings.TrimSpace(doc.IssuingCountry))
+
+	errs.Check(prefix + "type", doc.Type, OneOf(DocumentTypes...))
+	errs.Check(prefix + "number", doc.Number, Required(50))
+	errs.Check(prefix + "issuing_country", doc.IssuingCountry, Country)
+	errs.Check(prefix + "issuing_authority", doc.IssuingAuthority, MaxLen(100))
+	if doc.IssueDate != "" {
+		errs.Check(prefix + "issue_date", doc.IssueDate, Date)
+	}
+	if doc.ExpiryDate != "" {
+		errs.Check(prefix + "expiry_date", doc.ExpiryDate, Date)
+	}
+	if doc.IssueDate != "" && doc.ExpiryDate != "" && doc.ExpiryDate < doc.IssueDate {
+		errs.Add(prefix + "expiry_date", "is before the issue date")
+	}
+}
@@ -0,0 +1,111 @@
+package validation
+
+import(
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Person types, a natural person (individual) or a legal person (company)
+const (
+	PersonNatural	= "natural"
+	PersonLegal		= "legal"
+)
+
+var PersonTypes = []string{PersonNatural, PersonLegal}
+
+// TaxIDValidator checks the national tax id of a country
+type TaxIDValidator struct {
+	Name		string
+	Normalize	func(string) string
+	Validate	func(string) error
+}
+
+var taxIDValidators = map[string]TaxIDValidator{}
+
+// About register the tax id of a country and person type, e.g. BR natural is the CPF
+func RegisterTaxID(country string, personType string, validator TaxIDValidator) {
+	taxIDValidators[country + ":" + personType] = validator
+}
+
+func init() {
+	RegisterTaxID("BR", PersonNatural, TaxIDValidator{Name: "CPF", Normalize: NormalizeTaxID, Validate: CPF})
+	RegisterTaxID("BR", PersonLegal, TaxIDValidator{Name: "CNPJ", Normalize: NormalizeTaxID, Validate: CNPJ})
+}
+
+// About normalize and validate a tax id, the countries without a validator
+// only have the formatting stripped
+func TaxID(country string, personType string, value string) (string, error) {
+	validator, ok := taxIDValidators[country + ":" + personType]
+	if !ok {
+		return NormalizeTaxID(value), nil
+	}
+	normalized := validator.Normalize(value)
+	if err := validator.Validate(normalized); err != nil {
+		return normalized, errors.New("is not a valid " + validator.Name)
+	}
+	return normalized, nil
+}
+
+// About strip the punctuation and spaces of a tax id (123.456.789-09 becomes 12345678909)
+func NormalizeTaxID(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, value)
+}
+
+// About the mod 11 check digit of the brazilian ids, chars worth their ascii - 48
+func mod11(value string, weights []int) byte {
+	sum := 0
+	for i, weight := range weights {
+		sum += int(value[i] - '0') * weight
+	}
+	if rest := sum % 11; rest >= 2 {
+		return byte(11 - rest) + '0'
+	}
+	return '0'
+}
+
+// About a string made of the same char (e.g. 00000000000), it passes the check digits
+func repeated(value string) bool {
+	return strings.Count(value, value[:1]) == len(value)
+}
+
+// About validate a normalized CPF, 11 digits
+func CPF(value string) error {
+	if len(value) != 11 || strings.IndexFunc(value, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return errors.New("a CPF has 11 digits")
+	}
+	if repeated(value) {
+		return errors.New("invalid CPF")
+	}
+	if mod11(value, []int{10, 9, 8, 7, 6, 5, 4, 3, 2}) != value[9] ||
+		mod11(value, []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}) != value[10] {
+		return errors.New("invalid CPF check digits")
+	}
+	return nil
+}
+
+// About validate a normalized CNPJ, 14 chars: the 12 first digits or
+// uppercase letters (alphanumeric CNPJ) and 2 check digits
+func CNPJ(value string) error {
+	if len(value) != 14 {
+		return errors.New("a CNPJ has 14 chars")
+	}
+	for i, r := range value {
+		if (r < '0' || r > '9') && (i >= 12 || r < 'A' || r > 'Z') {
+			return errors.New("invalid CNPJ char")
+		}
+	}
+	if repeated(value) {
+		return errors.New("invalid CNPJ")
+	}
+	if mod11(value, []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) != value[12] ||
+		mod11(value, []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) != value[13] {
+		return errors.New("invalid CNPJ check digits")
+	}
+	return nil
+}
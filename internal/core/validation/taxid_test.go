@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+func TestMod11(t *testing.T) {
+	cases := []struct {
+		name	string
+		value	string
+		weights	[]int
+		want	byte
+	}{
+		{name: "first CPF digit", value: "529982247", weights: []int{10, 9, 8, 7, 6, 5, 4, 3, 2}, want: '2'},
+		{name: "second CPF digit", value: "5299822472", weights: []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}, want: '5'},
+		{name: "rest below 2 is 0", value: "000000040", weights: []int{10, 9, 8, 7, 6, 5, 4, 3, 2}, want: '0'},
+		{name: "letters worth their ascii - 48", value: "12ABC34501DE", weights: []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}, want: '3'},
+	}
+	for _, tc := range cases {
+		if got := mod11(tc.value, tc.weights); got != tc.want {
+			t.Errorf("%s: mod11(%q) = %q, want %q", tc.name, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestCPF(t *testing.T) {
+	cases := []struct {
+		value	string
+		valid	bool
+	}{
+		{"52998224725", true},
+		{"52998224724", false},
+		{"52998224715", false},
+		{"11111111111", false},
+		{"5299822472", false},
+		{"5299822472A", false},
+	}
+	for _, tc := range cases {
+		if err := CPF(tc.value); (err == nil) != tc.valid {
+			t.Errorf("CPF(%q) = %v, want valid %v", tc.value, err, tc.valid)
+		}
+	}
+}
+
+func TestCNPJ(t *testing.T) {
+	cases := []struct {
+		value	string
+		valid	bool
+	}{
+		{"11222333000181", true},
+		{"11222333000182", false},
+		{"12ABC34501DE35", true},
+		{"12ABC34501DE36", false},
+		{"12abc34501de35", false},
+		{"12ABC34501DEA5", false},
+		{"00000000000000", false},
+		{"1122233300018", false},
+	}
+	for _, tc := range cases {
+		if err := CNPJ(tc.value); (err == nil) != tc.valid {
+			t.Errorf("CNPJ(%q) = %v, want valid %v", tc.value, err, tc.valid)
+		}
+	}
+}
+
+func TestTaxID(t *testing.T) {
+	cases := []struct {
+		name		string
+		country		string
+		personType	string
+		value		string
+		want		string
+		valid		bool
+	}{
+		{name: "formatted CPF", country: "BR", personType: PersonNatural, value: "529.982.247-25", want: "52998224725", valid: true},
+		{name: "CPF check digits", country: "BR", personType: PersonNatural, value: "529.982.247-24", want: "52998224724"},
+		{name: "formatted alphanumeric CNPJ", country: "BR", personType: PersonLegal, value: "12.abc.345/01de-35", want: "12ABC34501DE35", valid: true},
+		{name: "CNPJ of a natural person", country: "BR", personType: PersonNatural, value: "11.222.333/0001-81", want: "11222333000181"},
+		{name: "country without validator", country: "US", personType: PersonNatural, value: "123-45-6789", want: "123456789", valid: true},
+	}
+	for _, tc := range cases {
+		got, err := TaxID(tc.country, tc.personType, tc.value)
+		if got != tc.want || (err == nil) != tc.valid {
+			t.Errorf("%s: TaxID(%q) = %q, %v, want %q valid %v", tc.name, tc.value, got, err, tc.want, tc.valid)
+		}
+	}
+}
+
+// a tax id without the nationality would skip the check digits
+func TestPersonTaxIDNationality(t *testing.T) {
+	cases := []struct {
+		name		string
+		nationality	string
+		taxID		string
+		field		string
+	}{
+		{name: "tax id without nationality", taxID: "529.982.247-24", field: "nationality"},
+		{name: "invalid CPF", nationality: "br", taxID: "529.982.247-24", field: "tax_id"},
+		{name: "valid CPF", nationality: "BR", taxID: "529.982.247-25"},
+		{name: "no tax id", nationality: ""},
+	}
+	for _, tc := range cases {
+		person := model.Person{PersonID: "P-1", Name: "Maria", Nationality: tc.nationality, TaxID: tc.taxID}
+		err := Person(&person)
+		if tc.field == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.field) {
+			t.Errorf("%s: error %v, want one on %s", tc.name, err, tc.field)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package validation
+
+import(
+	"fmt"
+	"errors"
+	"time"
+	"regexp"
+	"strings"
+	"net/mail"
+
+	"github.com/go-onboarding/internal/core/erro"
+)
+
+// A invalid field
+type FieldError struct {
+	Field	string
+	Reason	string
+}
+
+// Errors holds all the invalid fields of a payload, it is a erro.ErrInvalid
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		fields = append(fields, fieldError.Field + ": " + fieldError.Reason)
+	}
+	return erro.ErrInvalid.Error() + ": " + strings.Join(fields, "; ")
+}
+
+func (e Errors) Unwrap() error {
+	return erro.ErrInvalid
+}
+
+// About add a invalid field
+func (e *Errors) Add(field string, reason string) {
+	*e = append(*e, FieldError{Field: field, Reason: reason})
+}
+
+// About add a invalid field when check fails
+func (e *Errors) Check(field string, value string, check func(string) error) {
+	if err := check(value); err != nil {
+		e.Add(field, err.Error())
+	}
+}
+
+// About nil when there is no invalid field
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// About check a phone in the E.164 format (+5511999999999)
+func Phone(value string) error {
+	if !e164.MatchString(value) {
+		return errors.New("is not a E.164 phone number")
+	}
+	return nil
+}
+
+// About check the syntax of a email address, display names are not accepted
+func Email(value string) error {
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+		return errors.New("is not a valid email")
+	}
+	return nil
+}
+
+// About check a ISO 3166-1 alpha-2 country code (BR, US...)
+func Country(value string) error {
+	if !countries[value] {
+		return errors.New("is not a ISO 3166-1 alpha-2 country code")
+	}
+	return nil
+}
+
+// About check a date in the YYYY-MM-DD format
+func Date(value string) error {
+	if _, err := time.Parse(time.DateOnly, value); err != nil {
+		return errors.New("is not a YYYY-MM-DD date")
+	}
+	return nil
+}
+
+// About check a birth date, a date in the past
+func BirthDate(value string) error {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return errors.New("is not a YYYY-MM-DD date")
+	}
+	if date.After(time.Now()) || date.Year() < 1900 {
+		return errors.New("is not a valid birth date")
+	}
+	return nil
+}
+
+// About check a value is one of the choices
+func OneOf(choices ...string) func(string) error {
+	return func(value string) error {
+		for _, choice := range choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(choices, ", "))
+	}
+}
+
+// About check a value is not empty and fits the column
+func Required(maxLen int) func(string) error {
+	return func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return errors.New("is required")
+		}
+		return MaxLen(maxLen)(value)
+	}
+}
+
+// About check a value fits the column
+func MaxLen(maxLen int) func(string) error {
+	return func(value string) error {
+		if len([]rune(value)) > maxLen {
+			return fmt.Errorf("longer than %d characters", maxLen)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package validation
+
+import(
+	"time"
+	"errors"
+	"context"
+	"strconv"
+	"strings"
+	"net"
+	"net/url"
+	"net/netip"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/webhook"
+)
+
+// the endpoint host of a subscription must resolve within it
+const resolveTimeout = 5 * time.Second
+
+// About normalize and validate a webhook subscription, the endpoints are https on a
+// public address unless allowHTTP (the local receivers of the development)
+func WebhookSubscription(subscription *model.WebhookSubscription, allowHTTP bool) error {
+	var errs Errors
+
+	subscription.URL = strings.TrimSpace(subscription.URL)
+	subscription.Description = strings.TrimSpace(subscription.Description)
+
+	errs.Check("url", subscription.URL, Required(2000))
+	if subscription.URL != "" {
+		errs.Check("url", subscription.URL, endpoint(allowHTTP))
+	}
+	errs.Check("description", subscription.Description, MaxLen(200))
+
+	if len(subscription.Events) == 0 {
+		errs.Add("events", "is required")
+	}
+	seen := map[string]bool{}
+	events := []string{}
+	for i, event := range subscription.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		errs.Check("events[" + strconv.Itoa(i) + "]", event, OneOf(webhook.Events...))
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	subscription.Events = events
+
+	return errs.Err()
+}
+
+// About check a absolute url of a endpoint, without credentials, whose host resolves
+// to public addresses only. The notifier checks again the address it connects to.
+func endpoint(allowHTTP bool) func(string) error {
+	return func(value string) error {
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			return errors.New("is not a absolute url")
+		}
+		if u.User != nil {
+			return errors.New("must not have credentials")
+		}
+		if u.Scheme != "https" && (u.Scheme != "http" || !allowHTTP) {
+			return errors.New("must be a https url")
+		}
+		if allowHTTP {
+			return nil
+		}
+		return publicHost(u.Hostname())
+	}
+}
+
+// About check all the addresses of a host are public
+func publicHost(host string) error {
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+
+		if addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host); err != nil || len(addrs) == 0 {
+			return errors.New("host does not resolve")
+		}
+	}
+	for _, addr := range addrs {
+		if !webhook.PublicAddress(addr) {
+			return errors.New("must not resolve to a private address")
+		}
+	}
+	return nil
+}
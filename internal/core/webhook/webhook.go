@@ -0,0 +1,137 @@
+package webhook
+
+import(
+	"time"
+	"errors"
+	"strconv"
+	"strings"
+	"net/netip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// The events a subscription is notified of, EventAll subscribes to all of them
+const (
+	EventPersonCreated		= "person.created"
+	EventPersonUpdated		= "person.updated"
+	EventDocumentAdded		= "document.added"
+	EventDocumentUpdated	= "document.updated"
+	EventDocumentDeleted	= "document.deleted"
+	EventAll				= "*"
+)
+
+var Events = []string{	EventPersonCreated,
+						EventPersonUpdated,
+						EventDocumentAdded,
+						EventDocumentUpdated,
+						EventDocumentDeleted,
+						EventAll,
+}
+
+// The headers of a delivery
+const (
+	HeaderSignature	= "X-Webhook-Signature"
+	HeaderEvent		= "X-Webhook-Event"
+	HeaderDelivery	= "X-Webhook-Delivery"
+)
+
+var (
+	ErrMalformedSignature	= errors.New("malformed webhook signature")
+	ErrInvalidSignature		= errors.New("invalid webhook signature")
+	ErrExpiredSignature		= errors.New("webhook signature timestamp out of tolerance")
+	ErrPrivateAddress		= errors.New("webhook endpoint address is not public")
+)
+
+// the ranges not reachable from the internet the checks of netip do not cover: this
+// network, the carrier grade nat (metadata of some clouds), the protocol assignments,
+// the benchmarking and the reserved ones
+var reservedPrefixes = []netip.Prefix{	netip.MustParsePrefix("0.0.0.0/8"),
+										netip.MustParsePrefix("100.64.0.0/10"),
+										netip.MustParsePrefix("192.0.0.0/24"),
+										netip.MustParsePrefix("198.18.0.0/15"),
+										netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// About tell a address a endpoint may be delivered to: not the loopback, the private
+// and link-local networks (169.254.169.254, the cloud metadata) nor the reserved ones,
+// so a subscription can not reach the services inside the network (SSRF)
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// About the mac of a delivery, HMAC-SHA256 of "<unix timestamp>.<body>" with the secret
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// About the signature header of a delivery, "t=<unix timestamp>,v1=<hex mac>".
+// The timestamp is signed so a delivery captured can not be replayed later.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := timestamp.Unix()
+	return "t=" + strconv.FormatInt(unix, 10) + ",v1=" + hex.EncodeToString(mac(secret, unix, body))
+}
+
+// About check the signature header of a delivery received, the timestamp must be
+// within the tolerance of now (zero does not check it). For the receivers.
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+			timestamp = unix
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrMalformedSignature
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+			return ErrExpiredSignature
+		}
+	}
+
+	// several v1 are sent while a secret is rotated
+	expected := mac(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
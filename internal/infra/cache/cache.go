@@ -0,0 +1,193 @@
+package cache
+
+import(
+	"time"
+	"errors"
+	"context"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.cache").Logger()
+
+// Backend stores the entries, the LRU in the process or a shared one (e.g. redis)
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Entries are stored with a marker so a cached "not found" is told apart from a value
+const (
+	markerValue		= 'v'
+	markerNotFound	= 'n'
+)
+
+// Cache options
+type Config struct {
+	TTL			time.Duration
+	NegativeTTL	time.Duration	// how long a not found is cached, 0 disables it
+	NotFound	error			// error returned by the loader when the item does not exist
+	LoadTimeout	time.Duration	// timeout of a load, shared by the callers waiting for it
+}
+
+// timeout of a load when the config has none
+const defaultLoadTimeout = 10 * time.Second
+
+// Cache is a read-through cache.
+// The local LRU or the shared backend is checked and only then the loader, concurrent
+// misses of the same key share a single load.
+// With a shared backend there is no local layer: the local entries of the other replicas
+// would not be invalidated and would serve a updated or erased person up to the TTL.
+type Cache struct {
+	name		string
+	config		Config
+	local		Backend
+	shared		Backend
+	group		singleflight.Group
+	generation	atomic.Uint64
+}
+
+// About create a cache, shared may be nil. The local backend is only used without a shared one.
+func New(name string, config Config, local Backend, shared Backend) *Cache {
+	childLogger.Info().Str("func","New").Str("cache", name).Interface("config", config).Bool("shared", shared != nil).Send()
+
+	if shared != nil {
+		local = nil
+	}
+	return &Cache{
+		name: name,
+		config: config,
+		local: local,
+		shared: shared,
+	}
+}
+
+// About get the value of a key, calling the loader on a miss.
+// A nil cache always calls the loader.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if c == nil {
+		return load(ctx)
+	}
+
+	if c.local != nil {
+		if value, ok := c.get(ctx, c.local, "local", key); ok {
+			return c.decode(value)
+		}
+	}
+	if c.shared != nil {
+		if value, ok := c.get(ctx, c.shared, "shared", key); ok {
+			return c.decode(value)
+		}
+	}
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		generation := c.generation.Load()
+
+		// the load is shared, the first caller going away must not cancel it for the others
+		loadTimeout := c.config.LoadTimeout
+		if loadTimeout <= 0 {
+			loadTimeout = defaultLoadTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		value, err := load(ctx)
+		switch {
+		case err == nil:
+			value = append([]byte{markerValue}, value...)
+		case c.config.NegativeTTL > 0 && errors.Is(err, c.config.NotFound):
+			value = []byte{markerNotFound}
+		default:
+			return nil, err
+		}
+
+		// a invalidation during the load means the value may be stale already
+		if generation == c.generation.Load() {
+			c.set(ctx, key, value)
+		}
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return c.decode(res.Val.([]byte))
+	}
+}
+
+// About drop keys from all the backends, called after the writes are committed
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) {
+	if c == nil {
+		return
+	}
+	c.generation.Add(1)
+
+	if c.local != nil {
+		c.local.Delete(ctx, keys...)
+	}
+	if c.shared != nil {
+		if err := c.shared.Delete(ctx, keys...); err != nil {
+			logging.Ctx(ctx, childLogger).Error().Err(err).Str("cache", c.name).Strs("keys", keys).Msg("error invalidate shared cache")
+		}
+	}
+}
+
+// About get from a backend, a backend error is a miss
+func (c *Cache) get(ctx context.Context, backend Backend, layer string, key string) ([]byte, bool) {
+	value, ok, err := backend.Get(ctx, key)
+	if err != nil {
+		logging.Ctx(ctx, childLogger).Error().Err(err).Str("cache", c.name).Str("layer", layer).Msg("error get cache")
+	}
+	if err != nil || !ok || len(value) == 0 {
+		metrics.CacheRequests.WithLabelValues(c.name, layer, "miss").Inc()
+		return nil, false
+	}
+
+	result := "hit"
+	if value[0] == markerNotFound {
+		result = "negative_hit"
+	}
+	metrics.CacheRequests.WithLabelValues(c.name, layer, result).Inc()
+
+	return value, true
+}
+
+// About set in all the backends
+func (c *Cache) set(ctx context.Context, key string, value []byte) {
+	ttl := c.ttl(value)
+
+	if c.local != nil {
+		c.local.Set(ctx, key, value, ttl)
+	}
+	if c.shared != nil {
+		if err := c.shared.Set(ctx, key, value, ttl); err != nil {
+			logging.Ctx(ctx, childLogger).Error().Err(err).Str("cache", c.name).Msg("error set shared cache")
+		}
+	}
+}
+
+// About the ttl of a entry
+func (c *Cache) ttl(value []byte) time.Duration {
+	if value[0] == markerNotFound {
+		return c.config.NegativeTTL
+	}
+	return c.config.TTL
+}
+
+// About strip the marker, a cached not found is returned as the NotFound error
+func (c *Cache) decode(value []byte) ([]byte, error) {
+	if value[0] == markerNotFound {
+		return nil, c.config.NotFound
+	}
+	return value[1:], nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import(
+	"sync"
+	"time"
+	"context"
+	"container/list"
+)
+
+type lruEntry struct {
+	key			string
+	value		[]byte
+	expiresAt	time.Time
+}
+
+// LRU is the in-process backend, the least recently used entries are evicted
+// when it is full and the expired ones when they are read
+type LRU struct {
+	mu			sync.Mutex
+	maxEntries	int
+	entries		map[string]*list.Element
+	order		*list.List
+}
+
+// About create a lru holding up to maxEntries
+func NewLRU(maxEntries int) *LRU {
+	childLogger.Info().Str("func","NewLRU").Int("max_entries", maxEntries).Send()
+
+	return &LRU{
+		maxEntries: maxEntries,
+		entries: map[string]*list.Element{},
+		order: list.New(),
+	}
+}
+
+// About get a entry
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.remove(element)
+		return nil, false, nil
+	}
+	l.order.MoveToFront(element)
+
+	return entry.value, true, nil
+}
+
+// About set a entry
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(element)
+		return nil
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for l.order.Len() > l.maxEntries {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+// About delete entries
+func (l *LRU) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.entries[key]; ok {
+			l.remove(element)
+		}
+	}
+	return nil
+}
+
+func (l *LRU) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.entries, element.Value.(*lruEntry).key)
+}
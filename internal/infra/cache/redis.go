@@ -0,0 +1,57 @@
+package cache
+
+import(
+	"time"
+	"errors"
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBackend is a shared backend, the entries are seen by all the replicas
+type RedisBackend struct {
+	client	redis.UniversalClient
+	prefix	string
+}
+
+// About create a redis backend from a redis:// url
+func NewRedisBackend(url string, prefix string) (*RedisBackend, error) {
+	childLogger.Info().Str("func","NewRedisBackend").Send()
+
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisBackend{client: redis.NewClient(options), prefix: prefix}, nil
+}
+
+// About get a entry
+func (r *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix + key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// About set a entry
+func (r *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix + key, value, ttl).Err()
+}
+
+// About delete entries
+func (r *RedisBackend) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, r.prefix + key)
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+// About close the redis client
+func (r *RedisBackend) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,27 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the cache env var
+func GetCacheEnv(values *Values) (model.Cache, error) {
+	childLogger.Info().Str("func","GetCacheEnv").Send()
+
+	var cache	model.Cache
+
+	cache.IsEnabled = values.Bool("CACHE_ENABLED")
+	cache.Size = values.Int("CACHE_SIZE")
+	cache.TTL = values.Int("CACHE_TTL")
+	cache.NegativeTTL = values.Int("CACHE_NEGATIVE_TTL")
+	cache.SharedBackend = values.String("CACHE_SHARED_BACKEND")
+	cache.RedisURL = values.String("CACHE_REDIS_URL")
+
+	if cache.IsEnabled && cache.SharedBackend == "redis" && cache.RedisURL == "" {
+		return cache, errors.New("CACHE_REDIS_URL: is required when CACHE_SHARED_BACKEND is redis")
+	}
+
+	return cache, nil
+}
@@ -1,58 +1,61 @@
-package configuration
-
-import(
-	"os"
-	"encoding/base64"
-
-	"github.com/joho/godotenv"
-	"github.com/go-onboarding/internal/core/model"
-)
-
-// Load the certs TLS
-func GetCertEnv() model.Cert {
-	childLogger.Info().Str("func","GetCertEnv").Send()
-
-	err := godotenv.Load(".env")
-	if err != nil {
-		childLogger.Info().Err(err).Send()
-	}
-
-	var certTls model.Cert
-
-	if os.Getenv("SERVER_WITH_TLS") == "true" {	
-		childLogger.Info().Msg("*** Loading cert.pem AND private_key.pem ***")
-
-		certTls.IsTLS = true
-
-		certTls.CertPEM, err = os.ReadFile("/var/pod/cert/tls.crt") // full_chain_b64.pem
-		if err != nil {
-			childLogger.Error().Err(err).Send()
-			panic(err)
-		} 
-
-		certTls.CertPrivKeyPEM, err = os.ReadFile("/var/pod/cert/tls.key") //decrypted_private_key_b64.pem 
-		if err != nil {
-			childLogger.Error().Err(err).Send()
-			panic(err)
-		}
-
-		// Just to show the cert in plain text 
-		cert_str, err := base64.StdEncoding.DecodeString(string(certTls.CertPEM))
-		if err != nil {
-			childLogger.Error().Err(err).Send()
-			panic(err)
-		}
-		certTls.CertPEMStr = string(cert_str)
-		certTls.CertPEM = cert_str
-
-		cert_str, err = base64.StdEncoding.DecodeString(string(certTls.CertPrivKeyPEM))
-		if err != nil {
-			childLogger.Error().Err(err).Send()
-			panic(err)
-		}
-		certTls.CertPrivKeyPEMStr = string(cert_str)
-		certTls.CertPrivKeyPEM = cert_str
-	}
-
-	return certTls
-}
\ No newline at end of file
+package configuration
+
+import(
+	"os"
+	"fmt"
+	"encoding/base64"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// Load the certs TLS
+func GetCertEnv(values *Values) (model.Cert, error) {
+	childLogger.Info().Str("func","GetCertEnv").Send()
+
+	var certTls model.Cert
+
+	if values.Bool("SERVER_WITH_TLS") {
+		childLogger.Info().Msg("*** Loading cert.pem AND private_key.pem ***")
+
+		return ReadCert(values.String("TLS_CERT_FILE"), values.String("TLS_KEY_FILE"))
+	}
+
+	return certTls, nil
+}
+
+// About read the base64 encoded cert (full chain) and private key files
+func ReadCert(certFile string, keyFile string) (model.Cert, error) {
+	var certTls model.Cert
+
+	certTls.IsTLS = true
+
+	certPEM, err := readBase64File(certFile) // full_chain_b64.pem
+	if err != nil {
+		return certTls, err
+	}
+	certPrivKeyPEM, err := readBase64File(keyFile) //decrypted_private_key_b64.pem
+	if err != nil {
+		return certTls, err
+	}
+
+	// Just to show the cert in plain text
+	certTls.CertPEMStr = string(certPEM)
+	certTls.CertPEM = certPEM
+	certTls.CertPrivKeyPEMStr = string(certPrivKeyPEM)
+	certTls.CertPrivKeyPEM = certPrivKeyPEM
+
+	return certTls, nil
+}
+
+// About read a base64 encoded file
+func readBase64File(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return decoded, nil
+}
@@ -1,52 +1,78 @@
-package configuration
-
-import(
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
-	go_core_pg "github.com/eliezerraj/go-core/database/pg"
-)
-
-func GetDatabaseEnv() go_core_pg.DatabaseConfig {
-	childLogger.Info().Str("func","GetDatabaseEnv").Send()
-
-	err := godotenv.Load(".env")
-	if err != nil {
-		childLogger.Info().Err(err).Send()
-	}
-	
-	var databaseConfig	go_core_pg.DatabaseConfig
-
-	if os.Getenv("DB_HOST") !=  "" {
-		databaseConfig.Host = os.Getenv("DB_HOST")
-	}
-	if os.Getenv("DB_PORT") !=  "" {
-		databaseConfig.Port = os.Getenv("DB_PORT")
-	}
-	if os.Getenv("DB_NAME") !=  "" {	
-		databaseConfig.DatabaseName = os.Getenv("DB_NAME")
-	}
-
-	if os.Getenv("DB_MAX_CONNECTION") !=  "" {
-		intVar, _ := strconv.Atoi(os.Getenv("DB_MAX_CONNECTION"))
-		databaseConfig.DbMax_Connection = intVar
-	}
-
-	// Get Database Secrets
-	file_user, err := os.ReadFile("/var/pod/secret/username")
-	if err != nil {
-		childLogger.Error().Err(err).Send()
-		os.Exit(3)
-	}
-	file_pass, err := os.ReadFile("/var/pod/secret/password")
-	if err != nil {
-		childLogger.Error().Err(err).Send()
-		os.Exit(3)
-	}
-	
-	databaseConfig.User = string(file_user)
-	databaseConfig.Password = string(file_pass)
-
-	return databaseConfig
-}
\ No newline at end of file
+package configuration
+
+import(
+	"os"
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+)
+
+func GetDatabaseEnv(values *Values) (go_core_pg.DatabaseConfig, model.DatabaseAuth, error) {
+	childLogger.Info().Str("func","GetDatabaseEnv").Send()
+
+	var databaseConfig	go_core_pg.DatabaseConfig
+	var databaseAuth	model.DatabaseAuth
+
+	databaseConfig.Host = values.String("DB_HOST")
+	databaseConfig.Port = values.String("DB_PORT")
+	databaseConfig.DatabaseName = values.String("DB_NAME")
+	databaseConfig.DbMax_Connection = values.Int("DB_MAX_CONNECTION")
+
+	// The credentials are read by a credential provider, here just check they are reachable
+	databaseAuth.Mode = values.String("DB_AUTH_MODE")
+	switch databaseAuth.Mode {
+	case "iam":
+		databaseAuth.IAMUser = values.String("DB_IAM_USER")
+		if databaseAuth.IAMUser == "" {
+			return databaseConfig, databaseAuth, errors.New("DB_IAM_USER: is required when DB_AUTH_MODE is iam")
+		}
+	default:
+		databaseAuth.UserFile = values.String("DB_SECRET_USER_FILE")
+		databaseAuth.PasswordFile = values.String("DB_SECRET_PASSWORD_FILE")
+		databaseAuth.RefreshInterval = values.Int("DB_SECRET_REFRESH_INTERVAL")
+
+		_, errUser := os.Stat(databaseAuth.UserFile)
+		_, errPass := os.Stat(databaseAuth.PasswordFile)
+		if err := errors.Join(errUser, errPass); err != nil {
+			return databaseConfig, databaseAuth, err
+		}
+	}
+
+	return databaseConfig, databaseAuth, nil
+}
+
+// About get the reader endpoint env var, the reader uses the same database and credentials
+func GetDatabaseReaderEnv(values *Values) model.DatabaseReader {
+	childLogger.Info().Str("func","GetDatabaseReaderEnv").Send()
+
+	var databaseReader	model.DatabaseReader
+
+	databaseReader.Host = values.String("DB_READER_HOST")
+	if databaseReader.Host == "" {
+		return databaseReader
+	}
+	databaseReader.IsEnabled = true
+	databaseReader.Port = values.String("DB_READER_PORT")
+	databaseReader.MaxConnection = values.Int("DB_READER_MAX_CONNECTION")
+	databaseReader.ReadYourWrites = values.Int("DB_READ_YOUR_WRITES")
+	databaseReader.CheckInterval = values.Int("DB_READER_CHECK_INTERVAL")
+
+	return databaseReader
+}
+
+// About get the retry env var of the transactions and of the startup connect
+func GetDatabaseRetryEnv(values *Values) model.DatabaseRetry {
+	childLogger.Info().Str("func","GetDatabaseRetryEnv").Send()
+
+	var databaseRetry	model.DatabaseRetry
+
+	databaseRetry.Attempts = values.Int("DB_RETRY_ATTEMPTS")
+	databaseRetry.BaseDelay = values.Int("DB_RETRY_BASE_DELAY_MS")
+	databaseRetry.MaxDelay = values.Int("DB_RETRY_MAX_DELAY_MS")
+	databaseRetry.ConnectAttempts = values.Int("DB_CONNECT_ATTEMPTS")
+	databaseRetry.ConnectBaseDelay = values.Int("DB_CONNECT_BASE_DELAY_MS")
+	databaseRetry.ConnectMaxDelay = values.Int("DB_CONNECT_MAX_DELAY_MS")
+
+	return databaseRetry
+}
@@ -0,0 +1,24 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the person deduplication env var
+func GetDedupEnv(values *Values) (model.Dedup, error) {
+	childLogger.Info().Str("func","GetDedupEnv").Send()
+
+	var dedup	model.Dedup
+
+	dedup.IsEnabled = values.Bool("DEDUP_ENABLED")
+	dedup.MatchPercent = values.Int("DEDUP_MATCH_PERCENT")
+	dedup.ReviewPercent = values.Int("DEDUP_REVIEW_PERCENT")
+
+	if dedup.IsEnabled && dedup.ReviewPercent > dedup.MatchPercent {
+		return dedup, errors.New("DEDUP_REVIEW_PERCENT: must not be above DEDUP_MATCH_PERCENT")
+	}
+
+	return dedup, nil
+}
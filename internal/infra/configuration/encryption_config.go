@@ -0,0 +1,31 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the field encryption env var
+func GetEncryptionEnv(values *Values) (model.Encryption, error) {
+	childLogger.Info().Str("func","GetEncryptionEnv").Send()
+
+	var encryption	model.Encryption
+
+	encryption.IsEnabled = values.Bool("ENCRYPTION_ENABLED")
+	encryption.KeyProvider = values.String("ENCRYPTION_KEY_PROVIDER")
+	encryption.KeyFile = values.String("ENCRYPTION_KEY_FILE")
+	encryption.KMSKeyID = values.String("ENCRYPTION_KMS_KEY_ID")
+	encryption.ReloadInterval = values.Int("ENCRYPTION_RELOAD_INTERVAL")
+	encryption.ReencryptInterval = values.Int("ENCRYPTION_REENCRYPT_INTERVAL")
+	encryption.ReencryptBatch = values.Int("ENCRYPTION_REENCRYPT_BATCH")
+
+	if encryption.IsEnabled && encryption.KeyProvider == "local" && encryption.KeyFile == "" {
+		return encryption, errors.New("ENCRYPTION_KEY_FILE: is required when ENCRYPTION_KEY_PROVIDER is local")
+	}
+	if encryption.IsEnabled && encryption.KeyProvider == "kms" && encryption.KMSKeyID == "" {
+		return encryption, errors.New("ENCRYPTION_KMS_KEY_ID: is required when ENCRYPTION_KEY_PROVIDER is kms")
+	}
+
+	return encryption, nil
+}
@@ -0,0 +1,27 @@
+package configuration
+
+import(
+	"os"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the health checks env var
+func GetHealthCheckEnv(values *Values) model.HealthCheck {
+	childLogger.Info().Str("func","GetHealthCheckEnv").Send()
+
+	var healthCheck	model.HealthCheck
+
+	healthCheck.Timeout = values.Int("HEALTH_CHECK_TIMEOUT")
+	healthCheck.CacheTTL = values.Int("HEALTH_CACHE_TTL")
+	healthCheck.PoolSaturationPercent = values.Int("HEALTH_POOL_SATURATION_PERCENT")
+	healthCheck.DiskMinFreeMB = values.Int("HEALTH_DISK_MIN_FREE_MB")
+
+	// multipart uploads bigger than the memory limit are spilled into the temp dir
+	healthCheck.DiskPath = values.String("HEALTH_DISK_PATH")
+	if healthCheck.DiskPath == "" {
+		healthCheck.DiskPath = os.TempDir()
+	}
+
+	return healthCheck
+}
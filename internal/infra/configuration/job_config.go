@@ -0,0 +1,28 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the asynchronous job env var
+func GetJobEnv(values *Values) (model.Job, error) {
+	childLogger.Info().Str("func","GetJobEnv").Send()
+
+	var job	model.Job
+
+	job.IsEnabled = values.Bool("JOB_ENABLED")
+	job.Concurrency = values.Int("JOB_CONCURRENCY")
+	job.PollInterval = values.Int("JOB_POLL_INTERVAL")
+	job.Lease = values.Int("JOB_LEASE")
+	job.MaxAttempts = values.Int("JOB_MAX_ATTEMPTS")
+	job.BaseDelay = values.Int("JOB_BASE_DELAY")
+	job.MaxDelay = values.Int("JOB_MAX_DELAY")
+
+	if job.IsEnabled && job.BaseDelay > job.MaxDelay {
+		return job, errors.New("JOB_BASE_DELAY: must not be above JOB_MAX_DELAY")
+	}
+
+	return job, nil
+}
@@ -0,0 +1,496 @@
+package configuration
+
+import(
+	"os"
+	"fmt"
+	"flag"
+	"sort"
+	"slices"
+	"errors"
+	"strings"
+	"strconv"
+	"net/url"
+	"path/filepath"
+	"encoding/json"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/infra/encryption"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// Kind of value an option holds, used to validate it up front
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindBool
+	kindList
+)
+
+// Where a value came from, from the lowest to the highest precedence
+const (
+	OriginDefault	= "default"
+	OriginFile		= "file"
+	OriginDotEnv	= "dotenv"
+	OriginEnv		= "env"
+	OriginFlag		= "flag"
+)
+
+// A settable configuration option
+type option struct {
+	Key			string
+	Default		string
+	Usage		string
+	Kind		kind
+	Required	bool
+	Min			int
+	HasMin		bool	// Min is checked even when 0, for the values that can't be negative
+	Max			int
+	Choices		[]string
+}
+
+// All the options known by the service, the key is the env var name
+var options = []option{
+	{Key: "API_VERSION", Usage: "api version reported by the pod"},
+	{Key: "POD_NAME", Default: "go-onboarding", Usage: "pod name, also used as tracer name"},
+	{Key: "ENV", Usage: "environment name (dev, hml, prd)"},
+	{Key: "SETPOD_AZ", Default: "true", Kind: kindBool, Usage: "lookup the availability zone over imds"},
+
+	{Key: "LOG_LEVEL", Default: "info", Choices: logging.Levels, Usage: "log level, it can be changed at runtime in /admin/log"},
+	{Key: "LOG_FORMAT", Default: "json", Choices: logging.Formats, Usage: "log format (json, console)"},
+	{Key: "LOG_MASK_FIELDS", Default: "name,tax_id,email,phone,birth_date,street,number,postal_code", Kind: kindList, Usage: "comma separated json fields masked in the logs"},
+
+	{Key: "PORT", Kind: kindInt, Required: true, Min: 1, Max: 65535, Usage: "http server port"},
+	{Key: "SERVER_READ_TIMEOUT", Default: "120", Kind: kindInt, Min: 1, Usage: "http server read timeout in seconds"},
+	{Key: "SERVER_WRITE_TIMEOUT", Default: "120", Kind: kindInt, Min: 1, Usage: "http server write timeout in seconds"},
+	{Key: "SERVER_IDLE_TIMEOUT", Default: "120", Kind: kindInt, Min: 1, Usage: "http server idle timeout in seconds"},
+	{Key: "CTX_TIMEOUT", Default: "5", Kind: kindInt, Min: 1, Usage: "request context timeout in seconds"},
+	{Key: "EXPORT_TIMEOUT", Default: "300", Kind: kindInt, Min: 1, Usage: "seconds to write the archive of a person export, it is streamed after the status"},
+	{Key: "SERVER_PRESTOP_DELAY", Default: "5", Kind: kindInt, HasMin: true, Usage: "seconds failing readiness before draining the http server"},
+	{Key: "SERVER_SHUTDOWN_TIMEOUT", Default: "30", Kind: kindInt, Min: 1, Usage: "seconds to drain the in-flight requests"},
+
+	{Key: "GRPC_PORT", Default: "0", Kind: kindInt, HasMin: true, Max: 65535, Usage: "grpc server port for the internal services, 0 disables it"},
+	{Key: "GRPC_TOKEN_FILE", Usage: "json file of the grpc client ids and their bearer tokens, empty takes the x-client-id metadata"},
+	{Key: "ADMIN_TOKEN_FILE", Usage: "json file of the admin ids and their bearer tokens of the /admin routes, empty disables the /admin routes"},
+	{Key: "TRUSTED_PROXIES", Kind: kindList, Usage: "comma separated cidrs of the proxies in front of the service, their X-Forwarded-For hops are skipped to find the client ip. Empty ignores X-Forwarded-For"},
+
+	{Key: "SERVER_WITH_TLS", Default: "false", Kind: kindBool, Usage: "serve https using the pod certs"},
+	{Key: "TLS_CERT_FILE", Default: "/var/pod/cert/tls.crt", Usage: "base64 encoded cert (full chain) file"},
+	{Key: "TLS_KEY_FILE", Default: "/var/pod/cert/tls.key", Usage: "base64 encoded private key file"},
+
+	{Key: "DB_HOST", Required: true, Usage: "database host"},
+	{Key: "DB_PORT", Default: "5432", Kind: kindInt, Min: 1, Max: 65535, Usage: "database port"},
+	{Key: "DB_NAME", Default: "postgres", Usage: "database name"},
+	{Key: "DB_MAX_CONNECTION", Default: "5", Kind: kindInt, Min: 1, Usage: "database pool max connections"},
+	{Key: "DB_AUTH_MODE", Default: "secret", Choices: []string{"secret", "iam"}, Usage: "database credentials from the secret files or a rds iam token"},
+	{Key: "DB_SECRET_USER_FILE", Default: "/var/pod/secret/username", Usage: "database username secret file"},
+	{Key: "DB_SECRET_PASSWORD_FILE", Default: "/var/pod/secret/password", Usage: "database password secret file"},
+	{Key: "DB_SECRET_REFRESH_INTERVAL", Default: "30", Kind: kindInt, Min: 1, Usage: "interval in seconds to check the secret files for rotation"},
+	{Key: "DB_IAM_USER", Usage: "database user for the rds iam auth"},
+	{Key: "DB_RETRY_ATTEMPTS", Default: "3", Kind: kindInt, Min: 1, Usage: "attempts of a transaction failing with a retryable error (serialization failure, failover...)"},
+	{Key: "DB_RETRY_BASE_DELAY_MS", Default: "50", Kind: kindInt, Min: 1, Usage: "base backoff in milliseconds between the transaction attempts"},
+	{Key: "DB_RETRY_MAX_DELAY_MS", Default: "1000", Kind: kindInt, Min: 1, Usage: "max backoff in milliseconds between the transaction attempts"},
+	{Key: "DB_CONNECT_ATTEMPTS", Default: "5", Kind: kindInt, Min: 1, Usage: "attempts to connect the database at startup"},
+	{Key: "DB_CONNECT_BASE_DELAY_MS", Default: "1000", Kind: kindInt, Min: 1, Usage: "base backoff in milliseconds between the connect attempts"},
+	{Key: "DB_CONNECT_MAX_DELAY_MS", Default: "10000", Kind: kindInt, Min: 1, Usage: "max backoff in milliseconds between the connect attempts"},
+	{Key: "DB_READER_HOST", Usage: "reader endpoint (replica) used by the get and list person, all in the primary when empty"},
+	{Key: "DB_READER_PORT", Default: "5432", Kind: kindInt, Min: 1, Max: 65535, Usage: "reader endpoint port"},
+	{Key: "DB_READER_MAX_CONNECTION", Default: "5", Kind: kindInt, Min: 1, Usage: "reader pool max connections"},
+	{Key: "DB_READ_YOUR_WRITES", Default: "5", Kind: kindInt, HasMin: true, Usage: "seconds the reads of a client stay in the primary after its writes (0 disables)"},
+	{Key: "DB_READER_CHECK_INTERVAL", Default: "5", Kind: kindInt, Min: 1, Usage: "interval in seconds the reader is pinged, reads fall back to the primary while it fails"},
+
+	{Key: "RATE_LIMIT_ENABLED", Default: "false", Kind: kindBool, Usage: "rate limit the person and upload routes"},
+	{Key: "RATE_LIMIT_STORE", Default: "memory", Choices: []string{"memory", "redis"}, Usage: "token buckets per replica (memory) or shared (redis)"},
+	{Key: "RATE_LIMIT_REDIS_URL", Usage: "redis://[:password@]host:port/db used by the redis store"},
+	{Key: "RATE_LIMIT_READ_RPS", Default: "50", Kind: kindInt, HasMin: true, Usage: "read requests per second per client (0 disables)"},
+	{Key: "RATE_LIMIT_READ_BURST", Default: "100", Kind: kindInt, Min: 1, Usage: "read requests burst per client"},
+	{Key: "RATE_LIMIT_WRITE_RPS", Default: "10", Kind: kindInt, HasMin: true, Usage: "write requests per second per client (0 disables)"},
+	{Key: "RATE_LIMIT_WRITE_BURST", Default: "20", Kind: kindInt, Min: 1, Usage: "write requests burst per client"},
+	{Key: "RATE_LIMIT_UPLOAD_RPS", Default: "1", Kind: kindInt, HasMin: true, Usage: "uploads per second per client (0 disables)"},
+	{Key: "RATE_LIMIT_UPLOAD_BURST", Default: "5", Kind: kindInt, Min: 1, Usage: "uploads burst per client"},
+
+	{Key: "LOAD_SHED_ENABLED", Default: "false", Kind: kindBool, Usage: "shed the person and upload requests above the adaptive concurrency limit"},
+	{Key: "LOAD_SHED_INITIAL_LIMIT", Default: "20", Kind: kindInt, Min: 1, Usage: "concurrent requests admitted at startup"},
+	{Key: "LOAD_SHED_MIN_LIMIT", Default: "2", Kind: kindInt, Min: 1, Usage: "lowest concurrency limit"},
+	{Key: "LOAD_SHED_MAX_LIMIT", Default: "200", Kind: kindInt, Min: 1, Usage: "highest concurrency limit"},
+	{Key: "LOAD_SHED_LATENCY_TOLERANCE_PERCENT", Default: "200", Kind: kindInt, Min: 100, Usage: "latency accepted, in percent of the lowest latency, before the limit shrinks"},
+	{Key: "LOAD_SHED_WRITE_SHARE_PERCENT", Default: "70", Kind: kindInt, Min: 1, Max: 100, Usage: "percent of the limit usable by the writes and uploads, the rest is kept for the reads"},
+	{Key: "LOAD_SHED_WINDOW_MS", Default: "1000", Kind: kindInt, Min: 10, Usage: "window in milliseconds the latency is sampled before the limit is updated"},
+
+	{Key: "CACHE_ENABLED", Default: "false", Kind: kindBool, Usage: "cache the get person responses"},
+	{Key: "CACHE_SIZE", Default: "10000", Kind: kindInt, Min: 1, Usage: "max entries of the in-process lru, not used with a shared backend"},
+	{Key: "CACHE_TTL", Default: "30", Kind: kindInt, Min: 1, Usage: "seconds a person is cached, without a shared backend other replicas may serve it stale up to it after a update"},
+	{Key: "CACHE_NEGATIVE_TTL", Default: "5", Kind: kindInt, HasMin: true, Usage: "seconds a not found person is cached (0 disables)"},
+	{Key: "CACHE_SHARED_BACKEND", Choices: []string{"redis"}, Usage: "shared cache backend used instead of the lru, so a update is seen by all the replicas, none when empty"},
+	{Key: "CACHE_REDIS_URL", Usage: "redis://[:password@]host:port/db used by the redis backend"},
+
+	{Key: "ENCRYPTION_ENABLED", Default: "false", Kind: kindBool, Usage: "encrypt the person PII (name, email, phone, tax_id, address street and document number) at rest"},
+	{Key: "ENCRYPTION_KEY_PROVIDER", Default: "local", Choices: encryption.Providers, Usage: "provider of the master key wrapping the data keys, local is a key file and kms a aws kms key"},
+	{Key: "ENCRYPTION_KEY_FILE", Usage: "json key file of the local provider, {\"active\": id, \"keys\": {id: base64 of 32 bytes}}"},
+	{Key: "ENCRYPTION_KMS_KEY_ID", Usage: "key id, arn or alias of the aws kms key of the kms provider"},
+	{Key: "ENCRYPTION_RELOAD_INTERVAL", Default: "60", Kind: kindInt, Min: 1, Usage: "interval in seconds the data keys are reloaded, the rotations of other replicas are seen"},
+	{Key: "ENCRYPTION_REENCRYPT_INTERVAL", Default: "60", Kind: kindInt, HasMin: true, Usage: "interval in seconds the persons of older keys are encrypted again (0 disables)"},
+	{Key: "ENCRYPTION_REENCRYPT_BATCH", Default: "100", Kind: kindInt, Min: 1, Usage: "persons encrypted again per transaction"},
+
+	{Key: "DEDUP_ENABLED", Default: "false", Kind: kindBool, Usage: "look for duplicates of the persons onboarded"},
+	{Key: "DEDUP_MATCH_PERCENT", Default: "90", Kind: kindInt, Min: 1, Max: 100, Usage: "score in percent a person is refused as a duplicate from"},
+	{Key: "DEDUP_REVIEW_PERCENT", Default: "60", Kind: kindInt, Min: 1, Max: 100, Usage: "score in percent a person is flagged for review from"},
+
+	{Key: "OPENAPI_VALIDATE_REQUEST", Default: "false", Kind: kindBool, Usage: "refuse the requests not matching the openapi document"},
+	{Key: "OPENAPI_VALIDATE_RESPONSE", Default: "false", Kind: kindBool, Usage: "log the json responses not matching the openapi document"},
+
+	{Key: "WEBHOOK_ENABLED", Default: "false", Kind: kindBool, Usage: "deliver the onboarding events to the webhook subscriptions"},
+	{Key: "WEBHOOK_INTERVAL", Default: "5", Kind: kindInt, Min: 1, Usage: "interval in seconds the pending deliveries are looked for"},
+	{Key: "WEBHOOK_BATCH", Default: "50", Kind: kindInt, Min: 1, Usage: "deliveries claimed at a time"},
+	{Key: "WEBHOOK_CONCURRENCY", Default: "8", Kind: kindInt, Min: 1, Usage: "deliveries sent at the same time"},
+	{Key: "WEBHOOK_TIMEOUT", Default: "10", Kind: kindInt, Min: 1, Usage: "timeout in seconds of a delivery"},
+	{Key: "WEBHOOK_MAX_ATTEMPTS", Default: "10", Kind: kindInt, Min: 1, Usage: "attempts of a delivery before it is dead"},
+	{Key: "WEBHOOK_BASE_DELAY", Default: "30", Kind: kindInt, Min: 1, Usage: "base delay in seconds of the exponential backoff of a failed delivery"},
+	{Key: "WEBHOOK_MAX_DELAY", Default: "3600", Kind: kindInt, Min: 1, Usage: "max delay in seconds between the attempts of a delivery"},
+	{Key: "WEBHOOK_ALLOW_HTTP", Default: "false", Kind: kindBool, Usage: "accept plain http endpoints on private addresses, for the development only"},
+
+	{Key: "JOB_ENABLED", Default: "false", Kind: kindBool, Usage: "run the asynchronous jobs (imports, exports, re-encryption) in background"},
+	{Key: "JOB_CONCURRENCY", Default: "4", Kind: kindInt, Min: 1, Usage: "jobs run at the same time by the replica"},
+	{Key: "JOB_POLL_INTERVAL", Default: "2", Kind: kindInt, Min: 1, Usage: "interval in seconds the queued jobs are looked for"},
+	{Key: "JOB_LEASE", Default: "60", Kind: kindInt, Min: 3, Usage: "seconds a running job is held by a replica without a heartbeat, then it is run again"},
+	{Key: "JOB_MAX_ATTEMPTS", Default: "5", Kind: kindInt, Min: 1, Usage: "attempts of a job before it is failed"},
+	{Key: "JOB_BASE_DELAY", Default: "10", Kind: kindInt, Min: 1, Usage: "base delay in seconds of the exponential backoff of a failed job"},
+	{Key: "JOB_MAX_DELAY", Default: "600", Kind: kindInt, Min: 1, Usage: "max delay in seconds between the attempts of a job"},
+
+	{Key: "HEALTH_CHECK_TIMEOUT", Default: "2", Kind: kindInt, Min: 1, Usage: "timeout in seconds of each health check"},
+	{Key: "HEALTH_CACHE_TTL", Default: "5", Kind: kindInt, HasMin: true, Usage: "seconds a health check result is reused"},
+	{Key: "HEALTH_POOL_SATURATION_PERCENT", Default: "90", Kind: kindInt, Min: 1, Max: 100, Usage: "percent of acquired connections reported as saturated"},
+	{Key: "HEALTH_DISK_PATH", Usage: "path checked for free space, the temp dir used by uploads when empty"},
+	{Key: "HEALTH_DISK_MIN_FREE_MB", Default: "100", Kind: kindInt, HasMin: true, Usage: "min free space in MB of the disk path"},
+
+	{Key: "METRICS_TENANTS", Kind: kindList, Usage: "comma separated tenants labelled in the business metrics, the others are counted as \"other\""},
+
+	{Key: "OTEL_EXPORTER_OTLP_ENDPOINT", Usage: "otlp collector endpoint"},
+	{Key: "USE_STDOUT_TRACER_EXPORTER", Default: "false", Kind: kindBool, Usage: "export traces to stdout"},
+	{Key: "USE_OTLP_COLLECTOR", Default: "false", Kind: kindBool, Usage: "export traces to the otlp collector"},
+	{Key: "AWS_CLOUDWATCH_LOG_GROUP", Usage: "comma separated cloudwatch log groups"},
+	{Key: "OTEL_PROPAGATORS", Default: "tracecontext,baggage", Kind: kindList, Choices: tracing.Propagators, Usage: "comma separated trace propagators (tracecontext, baggage, xray, b3, b3multi)"},
+
+	{Key: "AWS_REGION", Required: true, Usage: "aws region"},
+	{Key: "BUCKET_NAME", Usage: "bucket used by the upload file"},
+	{Key: "FILE_PATH", Usage: "prefix (path) used by the upload file"},
+}
+
+// Values holds the effective configuration after all the layers were applied
+type Values struct {
+	values	map[string]string
+	origin	map[string]string
+	errs	[]error
+}
+
+// About get a string value
+func (v *Values) String(key string) string {
+	return v.values[key]
+}
+
+// About get a int value, options were already validated by Load
+func (v *Values) Int(key string) int {
+	intVar, err := strconv.Atoi(v.values[key])
+	if err != nil {
+		v.addError(fmt.Errorf("%s: invalid integer %q", key, v.values[key]))
+	}
+	return intVar
+}
+
+// About get a bool value
+func (v *Values) Bool(key string) bool {
+	boolVar, err := strconv.ParseBool(v.values[key])
+	if err != nil {
+		v.addError(fmt.Errorf("%s: invalid boolean %q", key, v.values[key]))
+	}
+	return boolVar
+}
+
+// About get a comma separated list
+func (v *Values) Strings(key string) []string {
+	if v.values[key] == "" {
+		return nil
+	}
+	return strings.Split(v.values[key], ",")
+}
+
+// About get where a value came from
+func (v *Values) Origin(key string) string {
+	return v.origin[key]
+}
+
+// About get a value to be shown, the password of a url (e.g. redis://:password@host) is hidden
+func (v *Values) Redacted(key string) string {
+	value := v.values[key]
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return value
+}
+
+// About get all keys with a value, sorted
+func (v *Values) Keys() []string {
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// About record a configuration error, all of them are reported at once
+func (v *Values) addError(err error) {
+	v.errs = append(v.errs, err)
+}
+
+// About return all the errors found so far
+func (v *Values) Err() error {
+	return errors.Join(v.errs...)
+}
+
+// About set a layer of values
+func (v *Values) set(layer map[string]string, origin string) {
+	for key, value := range layer {
+		v.values[key] = value
+		v.origin[key] = origin
+	}
+}
+
+// About the flag name of an option, DB_HOST becomes -db-host
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// About flatten a config file, nested keys are joined by "_" (db.host becomes DB_HOST)
+func flatten(prefix string, in map[string]any, out map[string]string) {
+	for key, value := range in {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(name, value, out)
+		case []any:
+			list := make([]string, 0, len(value))
+			for _, item := range value {
+				list = append(list, fmt.Sprint(item))
+			}
+			out[name] = strings.Join(list, ",")
+		case nil:
+			out[name] = ""
+		default:
+			out[name] = fmt.Sprint(value)
+		}
+	}
+}
+
+// About read a yaml or json config file
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		err = fmt.Errorf("unsupported config file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]string{}
+	flatten("", raw, res)
+	return res, nil
+}
+
+// About validate a single option
+func (o option) validate(value string) error {
+	if value == "" {
+		if o.Required {
+			return fmt.Errorf("%s: is required", o.Key)
+		}
+		return nil
+	}
+	switch o.Kind {
+	case kindInt:
+		intVar, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", o.Key, value)
+		}
+		if (o.Min != 0 || o.HasMin) && intVar < o.Min {
+			return fmt.Errorf("%s: %d is lower than %d", o.Key, intVar, o.Min)
+		}
+		if o.Max != 0 && intVar > o.Max {
+			return fmt.Errorf("%s: %d is greater than %d", o.Key, intVar, o.Max)
+		}
+	case kindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: invalid boolean %q", o.Key, value)
+		}
+	}
+	items := []string{value}
+	if o.Kind == kindList {
+		items = strings.Split(value, ",")
+	}
+	for _, item := range items {
+		if len(o.Choices) > 0 && !slices.Contains(o.Choices, item) {
+			return fmt.Errorf("%s: %q must be one of %s", o.Key, item, strings.Join(o.Choices, ", "))
+		}
+	}
+	return nil
+}
+
+// About build the effective configuration values.
+// The layers are applied in order: defaults, config file (-config or CONFIG_FILE),
+// .env file (-env-file or ENV_FILE), environment variables and flags.
+func LoadValues(args []string) (*Values, error) {
+	childLogger.Info().Str("func","LoadValues").Send()
+
+	values := &Values{	values: map[string]string{},
+						origin: map[string]string{},
+	}
+
+	// flags are parsed first, they may point to the config file
+	flags := map[string]string{}
+	fs := flag.NewFlagSet("go-onboarding", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "yaml or json config file")
+	envFile := fs.String("env-file", os.Getenv("ENV_FILE"), "dotenv file (default .env)")
+	for _, o := range options {
+		key := o.Key
+		fs.Func(flagName(key), o.Usage, func(s string) error {
+			flags[key] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	defaults := map[string]string{}
+	for _, o := range options {
+		if o.Default != "" {
+			defaults[o.Key] = o.Default
+		}
+	}
+	values.set(defaults, OriginDefault)
+
+	if *configFile != "" {
+		fileValues, err := readConfigFile(*configFile)
+		if err != nil {
+			values.addError(fmt.Errorf("config file %s: %w", *configFile, err))
+		}
+		values.set(fileValues, OriginFile)
+	}
+
+	dotEnv := ".env"
+	if *envFile != "" {
+		dotEnv = *envFile
+	}
+	dotEnvValues, err := godotenv.Read(dotEnv)
+	if err != nil {
+		if *envFile != "" || !errors.Is(err, os.ErrNotExist) {
+			values.addError(fmt.Errorf("env file %s: %w", dotEnv, err))
+		}
+	}
+	values.set(dotEnvValues, OriginDotEnv)
+
+	env := map[string]string{}
+	for _, o := range options {
+		if value := os.Getenv(o.Key); value != "" {
+			env[o.Key] = value
+		}
+	}
+	values.set(env, OriginEnv)
+	values.set(flags, OriginFlag)
+
+	// validate everything up front
+	known := map[string]bool{}
+	for _, o := range options {
+		known[o.Key] = true
+		if err := o.validate(values.values[o.Key]); err != nil {
+			values.addError(err)
+		}
+	}
+	for key, origin := range values.origin {
+		if !known[key] && origin == OriginFile {
+			values.addError(fmt.Errorf("%s: unknown option in config file", key))
+		}
+	}
+
+	return values, values.Err()
+}
+
+// About load and validate the whole app configuration
+func Load(args []string) (model.AppServer, error) {
+	childLogger.Info().Str("func","Load").Send()
+
+	var appServer model.AppServer
+
+	values, err := LoadValues(args)
+	if err != nil {
+		return appServer, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	infoPod, server, errInfoPod := GetInfoPod(values)
+	configOTEL := GetOtelEnv(values)
+	traceConfig := GetTraceEnv(values)
+	databaseConfig, databaseAuth, errDatabase := GetDatabaseEnv(values)
+	databaseReader := GetDatabaseReaderEnv(values)
+	databaseRetry := GetDatabaseRetryEnv(values)
+	certsTls, errCert := GetCertEnv(values)
+	awsService := GetAwsServiceEnv(values)
+	healthCheck := GetHealthCheckEnv(values)
+	logConfig := GetLogEnv(values)
+	rateLimit, errRateLimit := GetRateLimitEnv(values)
+	loadShed, errLoadShed := GetLoadShedEnv(values)
+	cache, errCache := GetCacheEnv(values)
+	encryption, errEncryption := GetEncryptionEnv(values)
+	dedup, errDedup := GetDedupEnv(values)
+	openAPI := GetOpenAPIEnv(values)
+	webhook, errWebhook := GetWebhookEnv(values)
+	job, errJob := GetJobEnv(values)
+	metricsConfig := GetMetricsEnv(values)
+
+	err = errors.Join(values.Err(), errInfoPod, errDatabase, errCert, errRateLimit, errLoadShed, errCache, errEncryption, errDedup, errWebhook, errJob)
+	if err != nil {
+		return appServer, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	appServer.InfoPod = &infoPod
+	appServer.Server = &server
+	appServer.ConfigOTEL = &configOTEL
+	appServer.TraceConfig = &traceConfig
+	appServer.AwsService = &awsService
+	appServer.Cert = &certsTls
+	appServer.DatabaseConfig = &databaseConfig
+	appServer.DatabaseAuth = &databaseAuth
+	appServer.DatabaseReader = &databaseReader
+	appServer.DatabaseRetry = &databaseRetry
+	appServer.HealthCheck = &healthCheck
+	appServer.Log = &logConfig
+	appServer.RateLimit = &rateLimit
+	appServer.LoadShed = &loadShed
+	appServer.Cache = &cache
+	appServer.Encryption = &encryption
+	appServer.Dedup = &dedup
+	appServer.OpenAPI = &openAPI
+	appServer.Webhook = &webhook
+	appServer.Job = &job
+	appServer.Metrics = &metricsConfig
+
+	return appServer, nil
+}
@@ -0,0 +1,31 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the load shedding env var
+func GetLoadShedEnv(values *Values) (model.LoadShed, error) {
+	childLogger.Info().Str("func","GetLoadShedEnv").Send()
+
+	var loadShed	model.LoadShed
+
+	loadShed.IsEnabled = values.Bool("LOAD_SHED_ENABLED")
+	loadShed.InitialLimit = values.Int("LOAD_SHED_INITIAL_LIMIT")
+	loadShed.MinLimit = values.Int("LOAD_SHED_MIN_LIMIT")
+	loadShed.MaxLimit = values.Int("LOAD_SHED_MAX_LIMIT")
+	loadShed.LatencyTolerancePercent = values.Int("LOAD_SHED_LATENCY_TOLERANCE_PERCENT")
+	loadShed.WriteSharePercent = values.Int("LOAD_SHED_WRITE_SHARE_PERCENT")
+	loadShed.Window = values.Int("LOAD_SHED_WINDOW_MS")
+
+	if loadShed.MinLimit > loadShed.MaxLimit {
+		return loadShed, errors.New("LOAD_SHED_MIN_LIMIT: must not be greater than LOAD_SHED_MAX_LIMIT")
+	}
+	if loadShed.InitialLimit < loadShed.MinLimit || loadShed.InitialLimit > loadShed.MaxLimit {
+		return loadShed, errors.New("LOAD_SHED_INITIAL_LIMIT: must be between LOAD_SHED_MIN_LIMIT and LOAD_SHED_MAX_LIMIT")
+	}
+
+	return loadShed, nil
+}
@@ -0,0 +1,18 @@
+package configuration
+
+import(
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the log env var
+func GetLogEnv(values *Values) model.Log {
+	childLogger.Info().Str("func","GetLogEnv").Send()
+
+	var logConfig	model.Log
+
+	logConfig.Level = values.String("LOG_LEVEL")
+	logConfig.Format = values.String("LOG_FORMAT")
+	logConfig.MaskFields = values.Strings("LOG_MASK_FIELDS")
+
+	return logConfig
+}
@@ -0,0 +1,16 @@
+package configuration
+
+import(
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the metrics env var
+func GetMetricsEnv(values *Values) model.Metrics {
+	childLogger.Info().Str("func","GetMetricsEnv").Send()
+
+	var metrics	model.Metrics
+
+	metrics.Tenants = values.Strings("METRICS_TENANTS")
+
+	return metrics
+}
@@ -0,0 +1,17 @@
+package configuration
+
+import(
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the openapi validation env var
+func GetOpenAPIEnv(values *Values) model.OpenAPI {
+	childLogger.Info().Str("func","GetOpenAPIEnv").Send()
+
+	var openAPI	model.OpenAPI
+
+	openAPI.ValidateRequest = values.Bool("OPENAPI_VALIDATE_REQUEST")
+	openAPI.ValidateResponse = values.Bool("OPENAPI_VALIDATE_RESPONSE")
+
+	return openAPI
+}
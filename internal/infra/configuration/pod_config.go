@@ -1,95 +1,85 @@
-package configuration
-
-import(
-	"os"
-	"strconv"
-	"net"
-	"context"
-
-	"github.com/joho/godotenv"
-	"github.com/rs/zerolog/log"
-	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
-	"github.com/aws/aws-sdk-go-v2/config"
-
-	"github.com/go-onboarding/internal/core/model"
-)
-
-var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.configuration").Logger()
-
-// Load the Pod configuration
-func GetInfoPod() (	model.InfoPod, model.Server) {
-	childLogger.Info().Str("func","GetInfoPod").Send()
-
-	err := godotenv.Load(".env")
-	if err != nil {
-		childLogger.Info().Err(err).Send()
-	}
-
-	var infoPod 	model.InfoPod
-	var server		model.Server
-
-	if os.Getenv("API_VERSION") !=  "" {
-		infoPod.ApiVersion = os.Getenv("API_VERSION")
-	}
-	if os.Getenv("POD_NAME") !=  "" {
-		infoPod.PodName = os.Getenv("POD_NAME")
-	}
-	if os.Getenv("SETPOD_AZ") == "false" {	
-		infoPod.IsAZ = false
-	} else {
-		infoPod.IsAZ = true
-	}
-	if os.Getenv("ENV") !=  "" {	
-		infoPod.Env = os.Getenv("ENV")
-	}
-	
-	// Get IP
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		log.Error().Err(err).Send()
-		os.Exit(3)
-	}
-	for _, a := range addrs {
-		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				infoPod.IPAddress = ipnet.IP.String()
-			}
-		}
-	}
-	infoPod.OSPID = strconv.Itoa(os.Getpid())
-
-	// Get AZ only if localtest is true
-	if (infoPod.IsAZ) {
-		cfg, err := config.LoadDefaultConfig(context.TODO())
-		if err != nil {
-			childLogger.Error().Err(err).Send()
-			os.Exit(3)
-		}
-		client := imds.NewFromConfig(cfg)
-		response, err := client.GetInstanceIdentityDocument(context.TODO(), &imds.GetInstanceIdentityDocumentInput{})
-		if err != nil {
-			childLogger.Error().Err(err).Send()
-			os.Exit(3)
-		}
-		infoPod.AvailabilityZone = response.AvailabilityZone	
-	} else {
-		infoPod.AvailabilityZone = "-"
-	}
-
-	if os.Getenv("PORT") !=  "" {
-		intVar, _ := strconv.Atoi(os.Getenv("PORT"))
-		server.Port = intVar
-	}
-
-	server.ReadTimeout = 120
-	server.WriteTimeout = 120
-	server.IdleTimeout = 120
-	server.CtxTimeout = 5
-
-	if os.Getenv("CTX_TIMEOUT") !=  "" {
-		intVar, _ := strconv.Atoi(os.Getenv("CTX_TIMEOUT"))
-		server.CtxTimeout = intVar
-	}
-
-	return infoPod, server
-}
+package configuration
+
+import(
+	"os"
+	"fmt"
+	"strconv"
+	"net"
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/infra/logging"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.configuration").Logger()
+
+// Load the Pod configuration
+func GetInfoPod(values *Values) (	model.InfoPod, model.Server, error) {
+	childLogger.Info().Str("func","GetInfoPod").Send()
+
+	var infoPod 	model.InfoPod
+	var server		model.Server
+
+	infoPod.ApiVersion = values.String("API_VERSION")
+	infoPod.PodName = values.String("POD_NAME")
+	infoPod.IsAZ = values.Bool("SETPOD_AZ")
+	infoPod.Env = values.String("ENV")
+
+	// Get IP
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return infoPod, server, fmt.Errorf("interface addrs: %w", err)
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				infoPod.IPAddress = ipnet.IP.String()
+			}
+		}
+	}
+	infoPod.OSPID = strconv.Itoa(os.Getpid())
+
+	// Get AZ only if localtest is true
+	if (infoPod.IsAZ) {
+		cfg, err := config.LoadDefaultConfig(context.TODO())
+		if err != nil {
+			return infoPod, server, fmt.Errorf("aws config: %w", err)
+		}
+		client := imds.NewFromConfig(cfg)
+		response, err := client.GetInstanceIdentityDocument(context.TODO(), &imds.GetInstanceIdentityDocumentInput{})
+		if err != nil {
+			return infoPod, server, fmt.Errorf("imds instance identity: %w", err)
+		}
+		infoPod.AvailabilityZone = response.AvailabilityZone
+	} else {
+		infoPod.AvailabilityZone = "-"
+	}
+
+	server.Port = values.Int("PORT")
+	server.ReadTimeout = values.Int("SERVER_READ_TIMEOUT")
+	server.WriteTimeout = values.Int("SERVER_WRITE_TIMEOUT")
+	server.IdleTimeout = values.Int("SERVER_IDLE_TIMEOUT")
+	server.CtxTimeout = values.Int("CTX_TIMEOUT")
+	server.ExportTimeout = values.Int("EXPORT_TIMEOUT")
+	server.PreStopDelay = values.Int("SERVER_PRESTOP_DELAY")
+	server.ShutdownTimeout = values.Int("SERVER_SHUTDOWN_TIMEOUT")
+	server.GrpcPort = values.Int("GRPC_PORT")
+	server.GrpcTokenFile = values.String("GRPC_TOKEN_FILE")
+	server.AdminTokenFile = values.String("ADMIN_TOKEN_FILE")
+	server.TrustedProxies = values.Strings("TRUSTED_PROXIES")
+
+	if server.GrpcPort < 0 || (server.GrpcPort > 0 && server.GrpcPort == server.Port) {
+		return infoPod, server, fmt.Errorf("GRPC_PORT: must be 0 or a port other than PORT")
+	}
+	for _, proxy := range server.TrustedProxies {
+		if _, err := logging.ParseProxy(proxy); err != nil {
+			return infoPod, server, fmt.Errorf("TRUSTED_PROXIES: %q is not a ip or cidr", proxy)
+		}
+	}
+
+	return infoPod, server, nil
+}
@@ -0,0 +1,30 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the rate limit env var
+func GetRateLimitEnv(values *Values) (model.RateLimit, error) {
+	childLogger.Info().Str("func","GetRateLimitEnv").Send()
+
+	var rateLimit	model.RateLimit
+
+	rateLimit.IsEnabled = values.Bool("RATE_LIMIT_ENABLED")
+	rateLimit.Store = values.String("RATE_LIMIT_STORE")
+	rateLimit.RedisURL = values.String("RATE_LIMIT_REDIS_URL")
+	rateLimit.ReadRate = values.Int("RATE_LIMIT_READ_RPS")
+	rateLimit.ReadBurst = values.Int("RATE_LIMIT_READ_BURST")
+	rateLimit.WriteRate = values.Int("RATE_LIMIT_WRITE_RPS")
+	rateLimit.WriteBurst = values.Int("RATE_LIMIT_WRITE_BURST")
+	rateLimit.UploadRate = values.Int("RATE_LIMIT_UPLOAD_RPS")
+	rateLimit.UploadBurst = values.Int("RATE_LIMIT_UPLOAD_BURST")
+
+	if rateLimit.IsEnabled && rateLimit.Store == "redis" && rateLimit.RedisURL == "" {
+		return rateLimit, errors.New("RATE_LIMIT_REDIS_URL: is required when RATE_LIMIT_STORE is redis")
+	}
+
+	return rateLimit, nil
+}
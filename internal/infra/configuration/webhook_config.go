@@ -0,0 +1,30 @@
+package configuration
+
+import(
+	"errors"
+
+	"github.com/go-onboarding/internal/core/model"
+)
+
+// About get the webhook delivery env var
+func GetWebhookEnv(values *Values) (model.Webhook, error) {
+	childLogger.Info().Str("func","GetWebhookEnv").Send()
+
+	var webhook	model.Webhook
+
+	webhook.IsEnabled = values.Bool("WEBHOOK_ENABLED")
+	webhook.Interval = values.Int("WEBHOOK_INTERVAL")
+	webhook.Batch = values.Int("WEBHOOK_BATCH")
+	webhook.Concurrency = values.Int("WEBHOOK_CONCURRENCY")
+	webhook.Timeout = values.Int("WEBHOOK_TIMEOUT")
+	webhook.MaxAttempts = values.Int("WEBHOOK_MAX_ATTEMPTS")
+	webhook.BaseDelay = values.Int("WEBHOOK_BASE_DELAY")
+	webhook.MaxDelay = values.Int("WEBHOOK_MAX_DELAY")
+	webhook.AllowHTTP = values.Bool("WEBHOOK_ALLOW_HTTP")
+
+	if webhook.IsEnabled && webhook.BaseDelay > webhook.MaxDelay {
+		return webhook, errors.New("WEBHOOK_BASE_DELAY: must not be above WEBHOOK_MAX_DELAY")
+	}
+
+	return webhook, nil
+}
@@ -0,0 +1,152 @@
+package credential
+
+import(
+	"os"
+	"fmt"
+	"sync"
+	"time"
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+
+	"github.com/go-onboarding/internal/infra/logging"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.credential").Logger()
+
+// Database credentials used when a new connection is opened
+type Credentials struct {
+	User		string
+	Password	string
+}
+
+// Provider returns the credentials to be used by the next connection
+type Provider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// FileProvider reads the credentials from the secret files mounted in the pod
+// and reloads them when the files change (e.g. ExternalSecret rotation)
+type FileProvider struct {
+	userFile		string
+	passwordFile	string
+	mu				sync.RWMutex
+	credentials		Credentials
+	raw				[]byte
+}
+
+// About create a file provider, the files are read right away
+func NewFileProvider(userFile string, passwordFile string) (*FileProvider, error) {
+	childLogger.Info().Str("func","NewFileProvider").Send()
+
+	p := &FileProvider{	userFile: userFile,
+						passwordFile: passwordFile,
+	}
+	if _, err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// About get the current credentials
+func (p *FileProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.credentials, nil
+}
+
+// About reload the secret files, returns true when the credentials changed
+func (p *FileProvider) Reload() (bool, error) {
+	file_user, err := os.ReadFile(p.userFile)
+	if err != nil {
+		return false, err
+	}
+	file_pass, err := os.ReadFile(p.passwordFile)
+	if err != nil {
+		return false, err
+	}
+
+	raw := append(append([]byte{}, file_user...), file_pass...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.raw != nil && bytes.Equal(raw, p.raw) {
+		return false, nil
+	}
+
+	credentials := Credentials{	User: strings.TrimSpace(string(file_user)),
+								Password: strings.TrimSpace(string(file_pass)),
+	}
+	if credentials.User == "" || credentials.Password == "" {
+		return false, fmt.Errorf("empty database credentials in %s or %s", p.userFile, p.passwordFile)
+	}
+
+	p.raw = raw
+	p.credentials = credentials
+
+	return true, nil
+}
+
+// About watch the secret files until the context is done.
+// Kubernetes swaps the whole secret volume on rotation, so the files are polled
+// instead of relying on inotify events of the symlinked files.
+func (p *FileProvider) Watch(ctx context.Context, interval time.Duration) {
+	childLogger.Info().Str("func","Watch").Str("interval", interval.String()).Send()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := p.Reload()
+			if err != nil {
+				logging.Ctx(ctx, childLogger).Error().Err(err).Msg("error reload database credentials, keeping the previous ones")
+				continue
+			}
+			if changed {
+				logging.Ctx(ctx, childLogger).Info().Msg("database credentials rotated, new connections will use them")
+			}
+		}
+	}
+}
+
+// IAMProvider generates a RDS IAM auth token for every new connection
+type IAMProvider struct {
+	endpoint	string
+	region		string
+	user		string
+	awsCredentials aws.CredentialsProvider
+}
+
+// About create a iam provider, endpoint is host:port of the database (or rds proxy)
+func NewIAMProvider(endpoint string,
+					region string,
+					user string,
+					awsCredentials aws.CredentialsProvider) *IAMProvider {
+	childLogger.Info().Str("func","NewIAMProvider").Send()
+
+	return &IAMProvider{
+		endpoint: endpoint,
+		region: region,
+		user: user,
+		awsCredentials: awsCredentials,
+	}
+}
+
+// About build a fresh auth token, it is valid for 15 minutes
+func (p *IAMProvider) Credentials(ctx context.Context) (Credentials, error) {
+	token, err := auth.BuildAuthToken(ctx, p.endpoint, p.region, p.user, p.awsCredentials)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{User: p.user, Password: token}, nil
+}
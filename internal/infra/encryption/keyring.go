@@ -0,0 +1,342 @@
+package encryption
+
+import(
+	"fmt"
+	"sync"
+	"time"
+	"errors"
+	"context"
+	"strings"
+	"strconv"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/base64"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/internal/infra/logging"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.encryption").Logger()
+
+// Purposes of the stored keys
+const (
+	PurposeData		= "data"	// encrypts the field values, versioned and rotated
+	PurposeIndex	= "index"	// computes the blind indexes, never rotated as it would change them all
+)
+
+// The encrypted values are "enc:v<version>:<base64 of nonce and ciphertext>",
+// a value without the prefix is a plaintext not encrypted yet
+const valuePrefix = "enc:v"
+
+var ErrUnknownKey = errors.New("data key version not found")
+
+// StoredKey is a data key wrapped by the master key of the provider
+type StoredKey struct {
+	Purpose		string
+	Version		int
+	MasterKeyID	string
+	WrappedKey	[]byte
+	CreatedAt	time.Time
+}
+
+// KeyStore keeps the wrapped data keys, e.g. a database table shared by the replicas
+type KeyStore interface {
+	ListKeys(ctx context.Context) ([]StoredKey, error)
+	// add a key, failing when the purpose and version already exist
+	AddKey(ctx context.Context, key StoredKey) error
+	// replace the wrapped key and master key id of a key
+	UpdateKey(ctx context.Context, key StoredKey) error
+}
+
+// Keyring encrypts the PII fields with envelope encryption: the values are
+// encrypted (AES-256-GCM) by data keys, the data keys are stored wrapped by the
+// master key of the provider and unwrapped once in memory.
+// The newest data key version encrypts, the older ones only decrypt until
+// the re-encryption moves their values to the newest.
+// The field name is bound to the ciphertext so a value can not be moved to another field.
+type Keyring struct {
+	provider	KeyProvider
+	store		KeyStore
+
+	mu			sync.RWMutex
+	data		map[int]cipher.AEAD
+	active		int
+	index		[]byte
+}
+
+// About load the keys, the first data and index keys are created on a empty store
+func NewKeyring(ctx context.Context, provider KeyProvider, store KeyStore) (*Keyring, error) {
+	childLogger.Info().Str("func","NewKeyring").Send()
+
+	k := &Keyring{
+		provider: provider,
+		store: store,
+		data: map[int]cipher.AEAD{},
+	}
+	if err := k.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if k.index == nil {
+		if err := k.addKey(ctx, PurposeIndex, 1); err != nil {
+			return nil, err
+		}
+	}
+	if k.active == 0 {
+		if err := k.addKey(ctx, PurposeData, 1); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// About load the keys of the store, the versions already in memory are not unwrapped again
+func (k *Keyring) Reload(ctx context.Context) error {
+	keys, err := k.store.ListKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	k.mu.RLock()
+	loaded := make(map[int]bool, len(k.data))
+	for version := range k.data {
+		loaded[version] = true
+	}
+	hasIndex := k.index != nil
+	k.mu.RUnlock()
+
+	data := map[int]cipher.AEAD{}
+	var index []byte
+	for _, key := range keys {
+		if (key.Purpose == PurposeData && loaded[key.Version]) || (key.Purpose == PurposeIndex && hasIndex) {
+			continue
+		}
+		plaintext, err := k.provider.Unwrap(ctx, key.MasterKeyID, key.WrappedKey)
+		if err != nil {
+			return fmt.Errorf("unwrap %s key v%d: %w", key.Purpose, key.Version, err)
+		}
+		switch key.Purpose {
+		case PurposeData:
+			if data[key.Version], err = newAEAD(plaintext); err != nil {
+				return err
+			}
+		case PurposeIndex:
+			index = plaintext
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for version, aead := range data {
+		k.data[version] = aead
+		if version > k.active {
+			k.active = version
+		}
+	}
+	if index != nil && k.index == nil {
+		k.index = index
+	}
+	return nil
+}
+
+// About reload the keys until the context is done, so the rotations made by other replicas are seen
+func (k *Keyring) Watch(ctx context.Context, interval time.Duration) {
+	childLogger.Info().Str("func","Watch").Str("interval", interval.String()).Send()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(ctx); err != nil {
+				logging.Ctx(ctx, childLogger).Error().Err(err).Msg("error reload encryption keys")
+			}
+		}
+	}
+}
+
+// About create a key, a key created meanwhile by another replica is loaded instead
+func (k *Keyring) addKey(ctx context.Context, purpose string, version int) error {
+	plaintext := make([]byte, 32)
+	if _, err := rand.Read(plaintext); err != nil {
+		return err
+	}
+	masterKeyID, wrapped, err := k.provider.Wrap(ctx, plaintext)
+	if err != nil {
+		return err
+	}
+
+	errAdd := k.store.AddKey(ctx, StoredKey{	Purpose: purpose,
+												Version: version,
+												MasterKeyID: masterKeyID,
+												WrappedKey: wrapped,
+												CreatedAt: time.Now(),
+	})
+	if err := k.Reload(ctx); err != nil {
+		return err
+	}
+	if errAdd != nil && !k.has(purpose, version) {
+		return errAdd
+	}
+	return nil
+}
+
+// About a key was loaded
+func (k *Keyring) has(purpose string, version int) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if purpose == PurposeIndex {
+		return k.index != nil
+	}
+	_, ok := k.data[version]
+	return ok
+}
+
+// About create a new data key version, the new values are encrypted with it
+// and the re-encryption moves the old ones
+func (k *Keyring) Rotate(ctx context.Context) (int, error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","Rotate").Send()
+
+	if err := k.Reload(ctx); err != nil {
+		return 0, err
+	}
+	version := k.ActiveVersion() + 1
+	if err := k.addKey(ctx, PurposeData, version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// About wrap all the stored keys again with the current master key of the provider,
+// after it is rotated the old master key can be retired
+func (k *Keyring) Rewrap(ctx context.Context) (int, error) {
+	logging.Ctx(ctx, childLogger).Info().Str("func","Rewrap").Send()
+
+	keys, err := k.store.ListKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		plaintext, err := k.provider.Unwrap(ctx, key.MasterKeyID, key.WrappedKey)
+		if err != nil {
+			return i, fmt.Errorf("unwrap %s key v%d: %w", key.Purpose, key.Version, err)
+		}
+		if key.MasterKeyID, key.WrappedKey, err = k.provider.Wrap(ctx, plaintext); err != nil {
+			return i, err
+		}
+		if err := k.store.UpdateKey(ctx, key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+// About the version of the data key encrypting the new values
+func (k *Keyring) ActiveVersion() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// About the prefix of the values encrypted with the active data key
+func (k *Keyring) ActivePrefix() string {
+	return valuePrefix + strconv.Itoa(k.ActiveVersion()) + ":"
+}
+
+// About the data key versions loaded
+func (k *Keyring) Versions() []int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	versions := make([]int, 0, len(k.data))
+	for version := 1; version <= k.active; version++ {
+		if _, ok := k.data[version]; ok {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// About encrypt the value of a field with the active data key.
+// A nil keyring or a empty value returns the value as is.
+func (k *Keyring) Encrypt(field string, value string) (string, error) {
+	if k == nil || value == "" {
+		return value, nil
+	}
+
+	k.mu.RLock()
+	version := k.active
+	aead := k.data[version]
+	k.mu.RUnlock()
+
+	sealed, err := seal(aead, []byte(value), []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return valuePrefix + strconv.Itoa(version) + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// About decrypt the value of a field, the plaintext values (not encrypted yet) are returned as is.
+// A version not loaded reloads the keys, it may have been created by another replica.
+func (k *Keyring) Decrypt(ctx context.Context, field string, value string) (string, error) {
+	if k == nil || !strings.HasPrefix(value, valuePrefix) {
+		return value, nil
+	}
+
+	versionStr, encoded, ok := strings.Cut(strings.TrimPrefix(value, valuePrefix), ":")
+	version, err := strconv.Atoi(versionStr)
+	if !ok || err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	if !k.has(PurposeData, version) {
+		if err := k.Reload(ctx); err != nil {
+			return "", err
+		}
+	}
+	k.mu.RLock()
+	aead, ok := k.data[version]
+	k.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: v%d", ErrUnknownKey, version)
+	}
+
+	plaintext, err := open(aead, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+// About the blind index of a field value, a keyed hash (HMAC-SHA256) allowing
+// the exact match lookups of a encrypted field. The value must be normalized
+// the same way when stored and when searched. A nil keyring or a empty value gives "".
+func (k *Keyring) BlindIndex(field string, value string) string {
+	if k == nil || value == "" {
+		return ""
+	}
+
+	k.mu.RLock()
+	mac := hmac.New(sha256.New, k.index)
+	k.mu.RUnlock()
+
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	// 128 bits, enough to avoid collisions without giving a longer hash to attack
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,230 @@
+package encryption
+
+import (
+	"os"
+	"sync"
+	"errors"
+	"context"
+	"strings"
+	"testing"
+	"crypto/rand"
+	"encoding/json"
+	"path/filepath"
+	"encoding/base64"
+)
+
+// the keys of a table shared by the replicas, kept in memory
+type memoryKeyStore struct {
+	mu		sync.Mutex
+	keys	[]StoredKey
+}
+
+func (s *memoryKeyStore) ListKeys(ctx context.Context) ([]StoredKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StoredKey(nil), s.keys...), nil
+}
+
+func (s *memoryKeyStore) AddKey(ctx context.Context, key StoredKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stored := range s.keys {
+		if stored.Purpose == key.Purpose && stored.Version == key.Version {
+			return errors.New("key already exists")
+		}
+	}
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStore) UpdateKey(ctx context.Context, key StoredKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, stored := range s.keys {
+		if stored.Purpose == key.Purpose && stored.Version == key.Version {
+			s.keys[i] = key
+			return nil
+		}
+	}
+	return errors.New("key not found")
+}
+
+// About a local provider over a key file of the master keys
+func newLocalProvider(t *testing.T, masterKeys map[string][]byte, active string) *LocalKeyProvider {
+	t.Helper()
+
+	file := localKeyFile{Active: active, Keys: map[string]string{}}
+	for id, key := range masterKeys {
+		file.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatalf("local key provider: %v", err)
+	}
+	return provider
+}
+
+func newMasterKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newTestKeyring(t *testing.T, provider KeyProvider, store KeyStore) *Keyring {
+	t.Helper()
+
+	keyring, err := NewKeyring(context.Background(), provider, store)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	return keyring
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	provider := newLocalProvider(t, map[string][]byte{"m1": newMasterKey(t)}, "m1")
+	keyring := newTestKeyring(t, provider, &memoryKeyStore{})
+
+	encrypted, err := keyring.Encrypt("person.tax_id", "52998224725")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, "enc:v1:") || strings.Contains(encrypted, "52998224725") {
+		t.Errorf("encrypted %q, want a enc:v1: value without the plaintext", encrypted)
+	}
+	if decrypted, err := keyring.Decrypt(ctx, "person.tax_id", encrypted); err != nil || decrypted != "52998224725" {
+		t.Errorf("decrypt: %q, %v", decrypted, err)
+	}
+
+	// the plaintexts not encrypted yet and the empty values pass as they are
+	for _, value := range []string{"52998224725", ""} {
+		if decrypted, err := keyring.Decrypt(ctx, "person.tax_id", value); err != nil || decrypted != value {
+			t.Errorf("decrypt of %q: %q, %v", value, decrypted, err)
+		}
+	}
+	if encrypted, _ := keyring.Encrypt("person.tax_id", ""); encrypted != "" {
+		t.Errorf("encrypt of a empty value: %q", encrypted)
+	}
+
+	// a nil keyring, the encryption is disabled
+	var disabled *Keyring
+	if encrypted, _ := disabled.Encrypt("person.tax_id", "52998224725"); encrypted != "52998224725" {
+		t.Errorf("encrypt of a nil keyring: %q", encrypted)
+	}
+}
+
+// a value can not be moved to another field, nor its blind index
+func TestKeyringFieldBinding(t *testing.T) {
+	ctx := context.Background()
+	provider := newLocalProvider(t, map[string][]byte{"m1": newMasterKey(t)}, "m1")
+	keyring := newTestKeyring(t, provider, &memoryKeyStore{})
+
+	encrypted, err := keyring.Encrypt("person.tax_id", "52998224725")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if _, err := keyring.Decrypt(ctx, "person.email", encrypted); err == nil {
+		t.Errorf("decrypt of the tax id as the email succeeded")
+	}
+
+	if keyring.BlindIndex("person.tax_id", "52998224725") != keyring.BlindIndex("person.tax_id", "52998224725") {
+		t.Errorf("blind index of the same value differs")
+	}
+	if keyring.BlindIndex("person.tax_id", "52998224725") == keyring.BlindIndex("person.email", "52998224725") {
+		t.Errorf("blind index of the same value in two fields is the same")
+	}
+}
+
+// a rotation made by a replica is seen by the others, the old values still decrypt
+func TestKeyringRotateReload(t *testing.T) {
+	ctx := context.Background()
+	provider := newLocalProvider(t, map[string][]byte{"m1": newMasterKey(t)}, "m1")
+	store := &memoryKeyStore{}
+	replica1 := newTestKeyring(t, provider, store)
+	replica2 := newTestKeyring(t, provider, store)
+
+	old, err := replica1.Encrypt("person.name", "Ana Silva")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	version, err := replica1.Rotate(ctx)
+	if err != nil || version != 2 || replica1.ActiveVersion() != 2 {
+		t.Fatalf("rotate: version %d active %d, %v", version, replica1.ActiveVersion(), err)
+	}
+	rotated, err := replica1.Encrypt("person.name", "Ana Silva")
+	if err != nil || !strings.HasPrefix(rotated, "enc:v2:") {
+		t.Fatalf("encrypt after the rotation: %q, %v", rotated, err)
+	}
+
+	// the version not loaded yet is reloaded from the store
+	if replica2.ActiveVersion() != 1 {
+		t.Errorf("replica 2 active %d before the reload, want 1", replica2.ActiveVersion())
+	}
+	if decrypted, err := replica2.Decrypt(ctx, "person.name", rotated); err != nil || decrypted != "Ana Silva" {
+		t.Errorf("decrypt of v2 by replica 2: %q, %v", decrypted, err)
+	}
+	if replica2.ActiveVersion() != 2 {
+		t.Errorf("replica 2 active %d after the reload, want 2", replica2.ActiveVersion())
+	}
+	if decrypted, err := replica2.Decrypt(ctx, "person.name", old); err != nil || decrypted != "Ana Silva" {
+		t.Errorf("decrypt of v1 by replica 2: %q, %v", decrypted, err)
+	}
+
+	// the index key is never rotated
+	if replica1.BlindIndex("person.name", "Ana Silva") != replica2.BlindIndex("person.name", "Ana Silva") {
+		t.Errorf("blind index differs between the replicas")
+	}
+
+	if _, err := replica2.Decrypt(ctx, "person.name", "enc:v9:" + strings.SplitN(rotated, ":", 3)[2]); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("decrypt of a unknown version: %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+// after a rewrap the old master key can be retired
+func TestKeyringRewrap(t *testing.T) {
+	ctx := context.Background()
+	m1, m2 := newMasterKey(t), newMasterKey(t)
+	store := &memoryKeyStore{}
+	keyring := newTestKeyring(t, newLocalProvider(t, map[string][]byte{"m1": m1}, "m1"), store)
+	if _, err := keyring.Rotate(ctx); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	encrypted, err := keyring.Encrypt("person.email", "ana@example.com")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	index := keyring.BlindIndex("person.email", "ana@example.com")
+
+	// the master key is rotated, the old one stays until the rewrap
+	rotated := newTestKeyring(t, newLocalProvider(t, map[string][]byte{"m1": m1, "m2": m2}, "m2"), store)
+	count, err := rotated.Rewrap(ctx)
+	if err != nil || count != 3 {
+		t.Fatalf("rewrap: %d keys, %v, want 3", count, err)
+	}
+	keys, _ := store.ListKeys(ctx)
+	for _, key := range keys {
+		if key.MasterKeyID != "m2" {
+			t.Errorf("%s key v%d wrapped by %s, want m2", key.Purpose, key.Version, key.MasterKeyID)
+		}
+	}
+
+	retired := newTestKeyring(t, newLocalProvider(t, map[string][]byte{"m2": m2}, "m2"), store)
+	if decrypted, err := retired.Decrypt(ctx, "person.email", encrypted); err != nil || decrypted != "ana@example.com" {
+		t.Errorf("decrypt without the old master key: %q, %v", decrypted, err)
+	}
+	if retired.BlindIndex("person.email", "ana@example.com") != index {
+		t.Errorf("blind index changed by the rewrap")
+	}
+}
@@ -0,0 +1,46 @@
+package encryption
+
+import(
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// the wrapped data keys are bound to this encryption context, as wrapAAD for the local keys
+var kmsContext = map[string]string{"purpose": string(wrapAAD)}
+
+// AWSKMSClient is the KMSClient over the aws kms client
+type AWSKMSClient struct {
+	client	*kms.Client
+}
+
+// About create a kms client from the aws config
+func NewAWSKMSClient(awsConfig aws.Config) *AWSKMSClient {
+	childLogger.Info().Str("func","NewAWSKMSClient").Send()
+
+	return &AWSKMSClient{client: kms.NewFromConfig(awsConfig)}
+}
+
+func (c *AWSKMSClient) Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	res, err := c.client.Encrypt(ctx, &kms.EncryptInput{	KeyId: aws.String(keyID),
+															Plaintext: plaintext,
+															EncryptionContext: kmsContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.CiphertextBlob, nil
+}
+
+// the key is not given, a symmetric ciphertext names its key: the data keys wrapped
+// through a alias are still opened once the alias points to a new key
+func (c *AWSKMSClient) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	res, err := c.client.Decrypt(ctx, &kms.DecryptInput{	CiphertextBlob: ciphertext,
+															EncryptionContext: kmsContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Plaintext, nil
+}
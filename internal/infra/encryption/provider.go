@@ -0,0 +1,164 @@
+package encryption
+
+import(
+	"os"
+	"fmt"
+	"errors"
+	"context"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/cipher"
+	"encoding/json"
+	"encoding/base64"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// KeyProvider protects the data keys with a master key that never leaves it,
+// the local key file in dev or a KMS in prod
+type KeyProvider interface {
+	// wrap a data key with the current master key, returning the id of the master key used
+	Wrap(ctx context.Context, plaintext []byte) (string, []byte, error)
+	// unwrap a data key wrapped by the master key of the id
+	Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error)
+}
+
+// the wrapped data keys are bound to this, a wrapped key is not a valid field value
+var wrapAAD = []byte("go-onboarding:data-key")
+
+// Providers of the master key
+var Providers = []string{"local", "kms"}
+
+// Key provider options
+type ProviderConfig struct {
+	Provider	string	// local or kms
+	KeyFile		string	// json key file of the local provider
+	KMSKeyID	string	// key id, arn or alias of the kms provider
+}
+
+// About create the key provider of the config, the aws config is used by the kms one
+func NewKeyProvider(config ProviderConfig, awsConfig *aws.Config) (KeyProvider, error) {
+	switch config.Provider {
+	case "local":
+		return NewLocalKeyProvider(config.KeyFile)
+	case "kms":
+		if awsConfig == nil {
+			return nil, errors.New("kms key provider: no aws config")
+		}
+		return NewKMSProvider(NewAWSKMSClient(*awsConfig), config.KMSKeyID), nil
+	}
+	return nil, fmt.Errorf("unknown key provider %q", config.Provider)
+}
+
+// LocalKeyProvider keeps the master keys in a json file:
+//
+//	{"active": "2024-01", "keys": {"2024-01": "<base64 of 32 bytes>"}}
+//
+// The master key is rotated adding a key and making it the active one,
+// the old keys must stay in the file until the data keys are rewrapped.
+type LocalKeyProvider struct {
+	active	string
+	keys	map[string]cipher.AEAD
+}
+
+type localKeyFile struct {
+	Active	string				`json:"active"`
+	Keys	map[string]string	`json:"keys"`
+}
+
+// About load the master keys of the file
+func NewLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	childLogger.Info().Str("func","NewLocalKeyProvider").Str("path", path).Send()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file localKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+
+	p := &LocalKeyProvider{active: file.Active, keys: map[string]cipher.AEAD{}}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("key file %s: key %s is not 32 bytes in base64", path, id)
+		}
+		if p.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := p.keys[p.active]; !ok {
+		return nil, fmt.Errorf("key file %s: active key %q not found", path, p.active)
+	}
+	return p, nil
+}
+
+func (p *LocalKeyProvider) Wrap(ctx context.Context, plaintext []byte) (string, []byte, error) {
+	sealed, err := seal(p.keys[p.active], plaintext, wrapAAD)
+	return p.active, sealed, err
+}
+
+func (p *LocalKeyProvider) Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %q not found in the key file", masterKeyID)
+	}
+	return open(aead, wrapped, wrapAAD)
+}
+
+// KMSClient is the part of a KMS used by the provider, AWSKMSClient over the aws kms client
+type KMSClient interface {
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// KMSProvider wraps the data keys with a KMS key, the key material never leaves the KMS.
+// The master key is rotated by the KMS (or by creating a new key and changing keyID).
+type KMSProvider struct {
+	client	KMSClient
+	keyID	string
+}
+
+// About create a provider over a KMS key
+func NewKMSProvider(client KMSClient, keyID string) *KMSProvider {
+	childLogger.Info().Str("func","NewKMSProvider").Str("key_id", keyID).Send()
+
+	return &KMSProvider{client: client, keyID: keyID}
+}
+
+func (p *KMSProvider) Wrap(ctx context.Context, plaintext []byte) (string, []byte, error) {
+	wrapped, err := p.client.Encrypt(ctx, p.keyID, plaintext)
+	return p.keyID, wrapped, err
+}
+
+func (p *KMSProvider) Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	return p.client.Decrypt(ctx, masterKeyID, wrapped)
+}
+
+// About a AES-256-GCM cipher
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// About encrypt with a random nonce, returned ahead of the ciphertext
+func seal(aead cipher.AEAD, plaintext []byte, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize() + len(plaintext) + aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// About decrypt a value of seal
+func open(aead cipher.AEAD, sealed []byte, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+}
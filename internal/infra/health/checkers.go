@@ -0,0 +1,56 @@
+package health
+
+import(
+	"fmt"
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// About check the database answers a ping
+func PingCheck(ping func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) error {
+		return ping(ctx)
+	}
+}
+
+// About check the pool is not saturated, maxPercent of the connections acquired
+func PoolSaturationCheck(stat func() *pgxpool.Stat, maxPercent int) CheckFunc {
+	return func(ctx context.Context) error {
+		stats := stat()
+		if stats.MaxConns() == 0 {
+			return nil
+		}
+		percent := int(stats.AcquiredConns() * 100 / stats.MaxConns())
+		if percent >= maxPercent {
+			return fmt.Errorf("pool saturated: %d of %d connections acquired", stats.AcquiredConns(), stats.MaxConns())
+		}
+		return nil
+	}
+}
+
+// About check the bucket is reachable
+func HeadBucketCheck(client *s3.Client, bucketName string) CheckFunc {
+	bucketName = strings.TrimSuffix(bucketName, "/")
+	return func(ctx context.Context) error {
+		_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+		return err
+	}
+}
+
+// About check there is free space in the path used by the temporary uploads
+func DiskFreeCheck(path string, minFreeBytes uint64) CheckFunc {
+	return func(ctx context.Context) error {
+		free, err := diskFree(path)
+		if err != nil {
+			return err
+		}
+		if free < minFreeBytes {
+			return fmt.Errorf("low disk space in %s: %d bytes free, min %d", path, free, minFreeBytes)
+		}
+		return nil
+	}
+}
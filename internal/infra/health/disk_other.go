@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package health
+
+import(
+	"errors"
+)
+
+// About get the free bytes, not supported in this platform
+func diskFree(path string) (uint64, error) {
+	return 0, errors.New("disk free check not supported")
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package health
+
+import(
+	"syscall"
+)
+
+// About get the free bytes available to the user in the path filesystem
+func diskFree(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
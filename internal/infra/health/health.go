@@ -0,0 +1,186 @@
+package health
+
+import(
+	"sync"
+	"time"
+	"context"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/internal/infra/logging"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.health").Logger()
+
+// Probe identifies which kubernetes probe is asking
+type Probe string
+
+const (
+	ProbeLive		Probe = "live"
+	ProbeReady		Probe = "ready"
+	ProbeStartup	Probe = "startup"
+)
+
+const (
+	StatusUp	= "up"
+	StatusDown	= "down"
+)
+
+// CheckFunc returns nil when the dependency is healthy
+type CheckFunc func(ctx context.Context) error
+
+// A dependency check.
+// Critical checks fail the probe, the others are only reported.
+type Check struct {
+	Name		string
+	Probes		[]Probe
+	Critical	bool
+	Timeout		time.Duration
+	CacheTTL	time.Duration
+	Check		CheckFunc
+}
+
+// Result of a check
+type Result struct {
+	Name		string		`json:"name"`
+	Status		string		`json:"status"`
+	Critical	bool		`json:"critical"`
+	Error		string		`json:"error,omitempty"`
+	Duration	string		`json:"duration"`
+	CheckedAt	time.Time	`json:"checked_at"`
+	Cached		bool		`json:"cached"`
+}
+
+// Report of a probe
+type Report struct {
+	Probe		Probe		`json:"probe"`
+	Status		string		`json:"status"`
+	Checks		[]Result	`json:"checks,omitempty"`
+}
+
+type registeredCheck struct {
+	check		Check
+	mu			sync.Mutex
+	last		*Result
+}
+
+type Health struct {
+	mu			sync.RWMutex
+	checks		[]*registeredCheck
+	ready		atomic.Bool
+	started		atomic.Bool
+}
+
+// About create a health
+func NewHealth() *Health {
+	childLogger.Info().Str("func","NewHealth").Send()
+
+	return &Health{}
+}
+
+// About register a check
+func (h *Health) Register(check Check) {
+	childLogger.Info().Str("func","Register").Str("check", check.Name).Send()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, &registeredCheck{check: check})
+}
+
+// About set the readiness, it is turned off when the shutdown begins
+func (h *Health) SetReady(ready bool) {
+	childLogger.Info().Str("func","SetReady").Bool("ready", ready).Send()
+
+	h.ready.Store(ready)
+}
+
+// About run all the checks of a probe.
+// Live never runs dependency checks, a broken database must not restart the pod.
+// Startup latches once it succeeded.
+func (h *Health) Run(ctx context.Context, probe Probe) Report {
+	report := Report{Probe: probe, Status: StatusUp}
+
+	switch probe {
+	case ProbeLive:
+		return report
+	case ProbeReady:
+		if !h.ready.Load() {
+			report.Status = StatusDown
+		}
+	case ProbeStartup:
+		if h.started.Load() {
+			return report
+		}
+	}
+
+	h.mu.RLock()
+	checks := make([]*registeredCheck, 0, len(h.checks))
+	for _, c := range h.checks {
+		for _, p := range c.check.Probes {
+			if p == probe {
+				checks = append(checks, c)
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *registeredCheck) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Critical && result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	report.Checks = results
+
+	if probe == ProbeStartup && report.Status == StatusUp {
+		h.started.Store(true)
+	}
+
+	return report
+}
+
+// About run a check with its timeout, the result is reused until the cache ttl expires
+func (c *registeredCheck) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.check.CacheTTL {
+		cached := *c.last
+		cached.Cached = true
+		return cached
+	}
+
+	ctxCheck, cancel := context.WithTimeout(ctx, c.check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check.Check(ctxCheck)
+
+	result := Result{	Name: c.check.Name,
+						Status: StatusUp,
+						Critical: c.check.Critical,
+						Duration: time.Since(start).String(),
+						CheckedAt: start,
+	}
+	if err != nil {
+		logging.Ctx(ctx, childLogger).Error().Err(err).Str("check", c.check.Name).Msg("health check failed")
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	c.last = &result
+
+	return result
+}
@@ -0,0 +1,411 @@
+package jobs
+
+import(
+	"os"
+	"fmt"
+	"sync"
+	"time"
+	"errors"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/retry"
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.jobs").Logger()
+
+// The status of a job
+const (
+	StatusQueued	= "queued"
+	StatusRunning	= "running"
+	StatusSucceeded	= "succeeded"
+	StatusFailed	= "failed"
+	StatusCanceled	= "canceled"
+)
+
+// the outcome is saved with a context of its own, the one of the job may be canceled
+const finishTimeout = 10 * time.Second
+
+var (
+	// the job is not held by the worker anymore, its lease expired and another worker claimed it
+	ErrLost			= errors.New("job lease lost")
+	ErrCanceled		= errors.New("job canceled")
+	ErrShutdown		= errors.New("job interrupted by the shutdown")
+)
+
+// Store keeps the jobs, e.g. a database table shared by the replicas
+type Store interface {
+	// claim a queued job due, or a running one whose lease is over, of the kinds given.
+	// The attempts are counted by the claim, nil when there is no job to run.
+	Claim(ctx context.Context, kinds []string, worker string, lease time.Duration) (*model.AsyncJob, error)
+	// renew the lease of a job held by the worker and save its progress, telling
+	// whether its cancel was requested. ErrLost when it is not held by the worker.
+	Heartbeat(ctx context.Context, id int64, worker string, progress model.JobProgress, lease time.Duration) (bool, error)
+	// save the outcome of a job held by the worker (status, attempts, progress, result,
+	// error and run_at) and release its lease. ErrLost when it is not held by the worker.
+	Finish(ctx context.Context, job *model.AsyncJob, worker string) error
+}
+
+// Progress reports the work done of a job, saved with the next heartbeat
+type Progress func(done int, total int)
+
+// Handler runs a job, the result is saved as json. The context is canceled when the
+// cancel of the job is requested, its lease is lost or the shutdown does not wait anymore.
+type Handler func(ctx context.Context, job *model.AsyncJob, progress Progress) (any, error)
+
+type permanentError struct {
+	err	error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// About mark a error as not worth a retry, the job fails at once (e.g. a invalid payload)
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// About tell a error marked by Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// Config of a pool, the Retry MaxAttempts is not used, each job has its own
+type Config struct {
+	Concurrency		int
+	PollInterval	time.Duration
+	Lease			time.Duration
+	Retry			retry.Policy
+}
+
+// Pool runs the jobs claimed from the store by a fixed number of workers.
+// A running job renews its lease with a heartbeat, a job of a replica gone is
+// claimed again once its lease is over. A failed job is queued again with a
+// exponential backoff until its attempts are over.
+type Pool struct {
+	store		Store
+	config		Config
+	worker		string
+	handlers	map[string]Handler
+	kinds		[]string
+
+	ctx			context.Context
+	cancel		context.CancelCauseFunc
+	slots		chan struct{}
+	stop		chan struct{}
+
+	mu			sync.Mutex
+	stopped		bool
+	wg			sync.WaitGroup
+}
+
+// About create a pool, the worker id tells the jobs held by this replica
+func NewPool(store Store, config Config) *Pool {
+	childLogger.Info().Str("func","NewPool").Int("concurrency", config.Concurrency).Send()
+
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	return &Pool{
+		store: store,
+		config: config,
+		worker: fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(suffix)),
+		handlers: map[string]Handler{},
+		slots: make(chan struct{}, config.Concurrency),
+		stop: make(chan struct{}),
+	}
+}
+
+// About register the handler of a kind of job, before the start
+func (p *Pool) Register(kind string, handler Handler) {
+	p.handlers[kind] = handler
+	p.kinds = append(p.kinds, kind)
+}
+
+// About the kinds of job registered
+func (p *Pool) Kinds() []string {
+	return p.kinds
+}
+
+// About start claiming the jobs. The jobs run out of the context given, only the
+// shutdown cancels them.
+func (p *Pool) Start(ctx context.Context) {
+	childLogger.Info().Str("func","Start").Str("worker", p.worker).Strs("kinds", p.kinds).Send()
+
+	p.ctx, p.cancel = context.WithCancelCause(context.WithoutCancel(ctx))
+	go p.poll(ctx)
+}
+
+// About stop claiming and wait the running jobs. When the context is done first the
+// jobs are canceled and queued again, as they were not run.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	childLogger.Info().Str("func","Shutdown").Str("worker", p.worker).Send()
+
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.stop)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	if p.cancel != nil {
+		p.cancel(ErrShutdown)
+	}
+	// the handlers return once canceled, their jobs are queued again
+	select {
+	case <-done:
+	case <-time.After(finishTimeout):
+	}
+	return fmt.Errorf("running jobs interrupted: %w", ctx.Err())
+}
+
+// the loop claiming the jobs while there are free workers
+func (p *Pool) poll(ctx context.Context) {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.fill(ctx)
+		}
+	}
+}
+
+// claim jobs until there is no free worker or no job to run
+func (p *Pool) fill(ctx context.Context) {
+	for {
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			return
+		}
+
+		job, err := p.store.Claim(ctx, p.kinds, p.worker, p.config.Lease)
+		if err != nil || job == nil {
+			<-p.slots
+			if err != nil {
+				childLogger.Error().Err(err).Msg("error claim job")
+			}
+			return
+		}
+
+		p.mu.Lock()
+		if p.stopped {
+			p.mu.Unlock()
+			<-p.slots
+			p.release(job)
+			return
+		}
+		p.wg.Add(1)
+		p.mu.Unlock()
+
+		go func() {
+			defer p.wg.Done()
+			defer func() { <-p.slots }()
+			p.run(job)
+		}()
+	}
+}
+
+// queue again a job claimed during the shutdown, as it was not run
+func (p *Pool) release(job *model.AsyncJob) {
+	now := time.Now()
+	job.Status = StatusQueued
+	job.Attempts--
+	job.RunAt = &now
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(p.ctx), finishTimeout)
+	defer cancel()
+	if err := p.store.Finish(ctx, job, p.worker); err != nil {
+		childLogger.Error().Err(err).Int64("job_id", job.ID).Msg("error release job")
+	}
+}
+
+// the progress of a running job, reported by the handler and saved by the heartbeat
+type state struct {
+	mu			sync.Mutex
+	progress	model.JobProgress
+}
+
+func (s *state) set(done int, total int) {
+	s.mu.Lock()
+	s.progress = model.JobProgress{Done: done, Total: total}
+	s.mu.Unlock()
+}
+
+func (s *state) get() model.JobProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progress
+}
+
+// run a claimed job and save its outcome
+func (p *Pool) run(job *model.AsyncJob) {
+	start := time.Now()
+
+	ctx := logging.WithCorrelation(p.ctx, &logging.Correlation{	TraceID: job.TraceID,
+																Route: "job:" + job.Kind,
+																Tenant: job.TenantID,
+	})
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	logging.Ctx(ctx, childLogger).Info().Str("func","run").Int64("job_id", job.ID).Int("attempt", job.Attempts).Send()
+
+	progress := &state{progress: job.Progress}
+
+	// a job claimed again after too many leases lost (e.g. it crashes the replica)
+	var result any
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		err = Permanent(fmt.Errorf("attempts over (%d)", job.MaxAttempts))
+	} else {
+		heartbeatDone := make(chan struct{})
+		go p.heartbeat(ctx, cancel, job, progress, heartbeatDone)
+
+		result, err = p.call(ctx, job, progress)
+
+		cancel(nil)
+		<-heartbeatDone
+	}
+
+	cause := context.Cause(ctx)
+	job.Progress = progress.get()
+	job.Result = nil
+	job.Error = ""
+
+	outcome := ""
+	switch {
+	case errors.Is(cause, ErrLost):
+		logging.Ctx(ctx, childLogger).Warn().Int64("job_id", job.ID).Msg("job lease lost, its outcome is dropped")
+		metrics.ObserveJob(job.Kind, "lost", time.Since(start))
+		return
+	case errors.Is(cause, ErrShutdown):
+		// not counted as a attempt, the job did not fail
+		outcome = "interrupted"
+		job.Status = StatusQueued
+		job.Attempts--
+		job.RunAt = &start
+		job.Error = ErrShutdown.Error()
+	case errors.Is(cause, ErrCanceled):
+		outcome = StatusCanceled
+		job.Status = StatusCanceled
+		job.Error = ErrCanceled.Error()
+	case err == nil:
+		outcome = StatusSucceeded
+		job.Status = StatusSucceeded
+		if result != nil {
+			if job.Result, err = json.Marshal(result); err != nil {
+				outcome = StatusFailed
+				job.Status = StatusFailed
+				job.Error = err.Error()
+			}
+		}
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		outcome = StatusFailed
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	default:
+		outcome = "retry"
+		runAt := time.Now().Add(p.config.Retry.Backoff(job.Attempts))
+		job.Status = StatusQueued
+		job.RunAt = &runAt
+		job.Error = err.Error()
+	}
+
+	if outcome != StatusSucceeded {
+		logging.Ctx(ctx, childLogger).Warn().Int64("job_id", job.ID).Str("outcome", outcome).Str("error", job.Error).Send()
+	}
+	metrics.ObserveJob(job.Kind, outcome, time.Since(start))
+
+	finishCtx, finishCancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer finishCancel()
+	if err := p.store.Finish(finishCtx, job, p.worker); err != nil {
+		// the lease expires and the job runs again
+		logging.Ctx(ctx, childLogger).Error().Err(err).Int64("job_id", job.ID).Msg("error finish job")
+	}
+}
+
+// call the handler of the job, a panic fails the attempt
+func (p *Pool) call(ctx context.Context, job *model.AsyncJob, progress *state) (result any, err error) {
+	ctx, span := tracing.Start(ctx, "jobs.run",	attribute.String("job.kind", job.Kind),
+												attribute.Int64("job.id", job.ID),
+												attribute.Int("job.attempt", job.Attempts))
+	defer func() { tracing.End(span, err) }()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panic: %v", r)
+		}
+	}()
+
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		return nil, Permanent(fmt.Errorf("no handler of the kind %s", job.Kind))
+	}
+	return handler(ctx, job, progress.set)
+}
+
+// renew the lease of a running job every third of it, saving its progress, until done.
+// The job is canceled when its cancel is requested or its lease is lost.
+func (p *Pool) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, job *model.AsyncJob, progress *state, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(p.config.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		beatCtx, beatCancel := context.WithTimeout(ctx, p.config.Lease / 3)
+		canceled, err := p.store.Heartbeat(beatCtx, job.ID, p.worker, progress.get(), p.config.Lease)
+		beatCancel()
+
+		switch {
+		case errors.Is(err, ErrLost):
+			cancel(ErrLost)
+			return
+		case err != nil:
+			// tried again on the next tick, the lease is still valid
+			logging.Ctx(ctx, childLogger).Error().Err(err).Int64("job_id", job.ID).Msg("error heartbeat job")
+		case canceled:
+			cancel(ErrCanceled)
+			return
+		}
+	}
+}
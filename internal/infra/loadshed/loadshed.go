@@ -0,0 +1,242 @@
+package loadshed
+
+import(
+	"fmt"
+	"math"
+	"context"
+	"sync"
+	"time"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/eliezerraj/go-core/coreJson"
+
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.loadshed").Logger()
+
+var core_json coreJson.CoreJson
+var core_apiError coreJson.APIError
+
+// Priority of a route, the lower priorities are shed first.
+// The probes are never wrapped by the limiter so they are always served.
+type Priority string
+
+const (
+	PriorityRead	Priority = "read"
+	PriorityWrite	Priority = "write"
+)
+
+// Reasons a request is shed
+const (
+	ReasonLimit		= "limit"
+	ReasonPool		= "pool_saturated"
+)
+
+// Limits of the adaptive concurrency limiter
+type Config struct {
+	InitialLimit		int
+	MinLimit			int
+	MaxLimit			int
+	LatencyTolerance	float64		// latency / min latency ratio accepted before the limit shrinks
+	WriteShare			float64		// share of the limit usable by the writes
+	Window				time.Duration
+}
+
+// Limiter is a gradient concurrency limiter.
+// The limit grows while the latency stays close to the lowest latency seen and
+// shrinks as soon as the requests get slower (e.g. waiting in the pool Acquire)
+// or the database pool is saturated, so the excess work is rejected right away
+// instead of queueing until the context timeout.
+type Limiter struct {
+	config		Config
+	stat		func() *pgxpool.Stat
+
+	mu				sync.Mutex
+	limit			float64
+	inFlight		int
+	maxInFlight		int
+	minLatency		time.Duration
+	windowStart		time.Time
+	windowCount		int
+	windowSum		time.Duration
+	windows			int
+	lastEmptyAcquire int64
+	poolSaturated	bool
+}
+
+// the lowest latency is forgotten after some windows, it follows the database moving
+const minLatencyResetWindows = 60
+
+// About create a limiter, stat gives the pool stats and may be nil
+func NewLimiter(config Config, stat func() *pgxpool.Stat) *Limiter {
+	childLogger.Info().Str("func","NewLimiter").Interface("config", config).Send()
+
+	l := &Limiter{
+		config: config,
+		stat: stat,
+		limit: float64(config.InitialLimit),
+		windowStart: time.Now(),
+	}
+	metrics.ConcurrencyLimit.Set(l.limit)
+
+	return l
+}
+
+// About try to admit a request, returns the reason when it is shed
+func (l *Limiter) acquire(priority Priority) (bool, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.roll(time.Now())
+
+	limit := l.limit
+	if priority == PriorityWrite {
+		if l.poolSaturated {
+			return false, ReasonPool
+		}
+		limit = math.Max(1, limit * l.config.WriteShare)
+	}
+	if float64(l.inFlight) >= limit {
+		return false, ReasonLimit
+	}
+
+	l.inFlight++
+	if l.inFlight > l.maxInFlight {
+		l.maxInFlight = l.inFlight
+	}
+	metrics.ConcurrencyInFlight.Set(float64(l.inFlight))
+
+	return true, ""
+}
+
+// About release a request and sample its latency
+func (l *Limiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	metrics.ConcurrencyInFlight.Set(float64(l.inFlight))
+
+	l.windowCount++
+	l.windowSum += latency
+
+	l.roll(time.Now())
+}
+
+// About close the window when it is over, checked by acquire too so a
+// pool stuck without releases is still seen as saturated
+func (l *Limiter) roll(now time.Time) {
+	if now.Sub(l.windowStart) < l.config.Window {
+		return
+	}
+	l.update()
+	l.windowStart = now
+	l.windowCount = 0
+	l.windowSum = 0
+	l.maxInFlight = l.inFlight
+}
+
+// About compute the new limit at the end of a window
+func (l *Limiter) update() {
+	l.poolSaturated = l.isPoolSaturated()
+	if l.windowCount == 0 {
+		if l.poolSaturated {
+			l.setLimit(l.limit * 0.9)
+		}
+		return
+	}
+	latency := l.windowSum / time.Duration(l.windowCount)
+
+	l.windows++
+	if l.minLatency == 0 || latency < l.minLatency || l.windows % minLatencyResetWindows == 0 {
+		l.minLatency = latency
+	}
+
+	// 1 while the latency is inside the tolerance, down to 0.5 when it degrades
+	gradient := math.Max(0.5, math.Min(1, l.config.LatencyTolerance * float64(l.minLatency) / float64(latency)))
+
+	newLimit := l.limit * gradient
+	// grow only when the limit was really used, an idle pod keeps its limit
+	if gradient == 1 && float64(l.maxInFlight) >= l.limit / 2 {
+		newLimit += math.Sqrt(l.limit)
+	}
+
+	if l.poolSaturated {
+		newLimit = math.Min(newLimit, l.limit * 0.9)
+	}
+	l.setLimit(newLimit)
+
+	childLogger.Debug().Str("func","update").
+						Float64("limit", l.limit).
+						Str("latency", latency.String()).
+						Str("min_latency", l.minLatency.String()).
+						Bool("pool_saturated", l.poolSaturated).Send()
+}
+
+// About set the limit inside the min and max
+func (l *Limiter) setLimit(limit float64) {
+	l.limit = math.Max(float64(l.config.MinLimit), math.Min(float64(l.config.MaxLimit), limit))
+	metrics.ConcurrencyLimit.Set(l.limit)
+}
+
+// About the pool is saturated when all connections are acquired and
+// requests had to wait for one since the last window
+func (l *Limiter) isPoolSaturated() bool {
+	if l.stat == nil {
+		return false
+	}
+	stat := l.stat()
+
+	emptyAcquire := stat.EmptyAcquireCount()
+	waited := emptyAcquire > l.lastEmptyAcquire
+	l.lastEmptyAcquire = emptyAcquire
+
+	return waited && stat.AcquiredConns() >= stat.MaxConns()
+}
+
+// About the load shedding middleware of a route priority, a nil limiter lets everything pass
+func (l *Limiter) Middleware(priority Priority) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			release := l.Admit(req.Context(), priority)
+			if release == nil {
+				trace_id := fmt.Sprintf("%v", req.Context().Value("trace-request-id"))
+				rw.Header().Set("Retry-After", "1")
+				apiError := core_apiError.NewAPIError(erro.ErrOverloaded, trace_id, http.StatusServiceUnavailable)
+				core_json.WriteJSON(rw, http.StatusServiceUnavailable, apiError)
+				return
+			}
+			defer release()
+
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// About admit a request of a priority, also used by the grpc server. The release must
+// be called once the request is done, it is nil when the request is shed.
+// A nil limiter admits everything.
+func (l *Limiter) Admit(ctx context.Context, priority Priority) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	ok, reason := l.acquire(priority)
+	if !ok {
+		metrics.ShedRequests.WithLabelValues(string(priority), reason).Inc()
+		logging.Ctx(ctx, childLogger).Warn().Str("priority", string(priority)).Str("reason", reason).Msg("request shed")
+		return nil
+	}
+
+	start := time.Now()
+	return func() { l.release(time.Since(start)) }
+}
@@ -0,0 +1,393 @@
+package logging
+
+import(
+	"os"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+	"context"
+	"strings"
+	"net"
+	"net/http"
+	"net/netip"
+	"encoding/json"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Writer shared by all the loggers, so the format can be switched after the
+// package loggers were created. Every package with a logger must import this
+// package (directly or not) so this init runs before their loggers are built.
+var output = &switchWriter{out: os.Stderr}
+
+func init() {
+	log.Logger = log.Output(output)
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		SetFormat(format)
+	}
+}
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.logging").Logger()
+
+// Levels accepted by SetLevel
+var Levels = []string{"trace", "debug", "info", "warn", "error", "fatal", "panic", "disabled"}
+
+// Formats accepted by SetFormat
+var Formats = []string{"json", "console"}
+
+type switchWriter struct {
+	mu		sync.RWMutex
+	out		io.Writer
+	format	string
+}
+
+func (w *switchWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.out.Write(p)
+}
+
+// About set the global level and the output format
+func Setup(level string, format string) error {
+	childLogger.Info().Str("func","Setup").Str("level", level).Str("format", format).Send()
+
+	if err := SetFormat(format); err != nil {
+		return err
+	}
+	return SetLevel(level)
+}
+
+// About change the output format at runtime
+func SetFormat(format string) error {
+	var out io.Writer
+	switch format {
+	case "json":
+		out = os.Stderr
+	case "console":
+		out = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	output.mu.Lock()
+	defer output.mu.Unlock()
+
+	output.out = out
+	output.format = format
+	return nil
+}
+
+// About get the output format
+func Format() string {
+	output.mu.RLock()
+	defer output.mu.RUnlock()
+
+	if output.format == "" {
+		return "json"
+	}
+	return output.format
+}
+
+// About change the global level at runtime
+func SetLevel(level string) error {
+	logLevel, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(logLevel)
+	return nil
+}
+
+// About get the global level
+func Level() string {
+	return zerolog.GlobalLevel().String()
+}
+
+// Correlation fields shared by all the log lines of a request
+type Correlation struct {
+	TraceID		string
+	Route		string
+	Tenant		string
+	Client		string	// X-Client-Id or the client ip, not logged
+	CallerTenant	string	// tenant the caller is bound to (X-Tenant-Id), SetTenant does not change it
+	Admin			bool	// the caller is authenticated by a token, it may reach all the tenants
+}
+
+type correlationKey struct{}
+
+// About get the correlation of the request, nil outside a request
+func CorrelationFrom(ctx context.Context) *Correlation {
+	correlation, _ := ctx.Value(correlationKey{}).(*Correlation)
+	return correlation
+}
+
+// About put the correlation of a request in the context, for the servers other than the http one
+func WithCorrelation(ctx context.Context, correlation *Correlation) context.Context {
+	return context.WithValue(ctx, correlationKey{}, correlation)
+}
+
+// About the tenant the caller is bound to, "" when not bound to one (or outside a request)
+func CallerTenant(ctx context.Context) string {
+	if correlation := CorrelationFrom(ctx); correlation != nil {
+		return correlation.CallerTenant
+	}
+	return ""
+}
+
+// About whether the caller is authenticated by a token (admin or grpc client), it may
+// then call without a tenant to reach all of them
+func CallerAdmin(ctx context.Context) bool {
+	if correlation := CorrelationFrom(ctx); correlation != nil {
+		return correlation.Admin
+	}
+	return false
+}
+
+// About set the tenant once it is known (e.g. after decoding the body)
+func SetTenant(ctx context.Context, tenant string) {
+	if correlation := CorrelationFrom(ctx); correlation != nil && tenant != "" {
+		correlation.Tenant = tenant
+	}
+}
+
+// About a logger with the correlation fields of the request
+func Ctx(ctx context.Context, logger zerolog.Logger) *zerolog.Logger {
+	correlation := CorrelationFrom(ctx)
+	if correlation == nil {
+		if traceID, ok := ctx.Value("trace-request-id").(string); ok {
+			logger = logger.With().Str("trace_id", traceID).Logger()
+		}
+		return &logger
+	}
+
+	logCtx := logger.With().Str("trace_id", correlation.TraceID).Str("route", correlation.Route)
+	if correlation.Tenant != "" {
+		logCtx = logCtx.Str("tenant", correlation.Tenant)
+	}
+	logger = logCtx.Logger()
+	return &logger
+}
+
+// ResponseWriter keeping the status code and the bytes written
+type accessWriter struct {
+	http.ResponseWriter
+	status	int
+	bytes	int
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// About the wrapped writer, for the http.ResponseController (e.g. a longer write deadline)
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+var (
+	proxyMu			sync.RWMutex
+	trustedProxies	[]netip.Prefix
+)
+
+// About parse a trusted proxy, a cidr or a single ip
+func ParseProxy(proxy string) (netip.Prefix, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// About set the proxies in front of the service (ingress, load balancer), their
+// X-Forwarded-For hops are skipped to find the client ip
+func SetTrustedProxies(proxies []string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		prefix, err := ParseProxy(proxy)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+	trustedProxies = prefixes
+	return nil
+}
+
+// About whether a ip is one of the trusted proxies
+func trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	proxyMu.RLock()
+	defer proxyMu.RUnlock()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// About the client ip. When the peer is a trusted proxy the X-Forwarded-For hops are read
+// from the right, the first one that is not a trusted proxy is the client. Otherwise the
+// header is ignored, the client writes what it wants in it.
+func ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if !trustedProxy(host) {
+		return host
+	}
+
+	hops := []string{}
+	for _, forwarded := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(forwarded, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !trustedProxy(hops[i]) {
+			return hops[i]
+		}
+	}
+	// every hop is a proxy, the farthest one is the closest to the client
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return host
+}
+
+// About the access log middleware, it also puts the correlation in the context.
+// It must run after the trace request id middleware.
+func MiddleWareAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		correlation := &Correlation{	TraceID: fmt.Sprintf("%v", req.Context().Value("trace-request-id")),
+										Route: req.URL.Path,
+										Tenant: req.Header.Get("X-Tenant-Id"),
+										Client: req.Header.Get("X-Client-Id"),
+										CallerTenant: req.Header.Get("X-Tenant-Id"),
+		}
+		if correlation.Client == "" {
+			correlation.Client = ClientIP(req)
+		}
+		if current := mux.CurrentRoute(req); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				correlation.Route = template
+			}
+		}
+		req = req.WithContext(WithCorrelation(req.Context(), correlation))
+
+		start := time.Now()
+		aw := &accessWriter{ResponseWriter: rw, status: http.StatusOK}
+		next.ServeHTTP(aw, req)
+
+		Ctx(req.Context(), childLogger).Info().
+			Str("method", req.Method).
+			Int("status", aw.status).
+			Int64("latency_ms", time.Since(start).Milliseconds()).
+			Int("bytes", aw.bytes).
+			Msg("access")
+	})
+}
+
+var (
+	maskMu		sync.RWMutex
+	maskFields	= map[string]bool{}
+)
+
+// About set the json fields masked by Mask
+func SetMaskFields(fields []string) {
+	maskMu.Lock()
+	defer maskMu.Unlock()
+
+	maskFields = map[string]bool{}
+	for _, field := range fields {
+		maskFields[strings.ToLower(strings.TrimSpace(field))] = true
+	}
+}
+
+// About get the masked fields
+func MaskFields() []string {
+	maskMu.RLock()
+	defer maskMu.RUnlock()
+
+	fields := make([]string, 0, len(maskFields))
+	for field := range maskFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// About mask the PII fields of a value before logging it.
+// The value is walked through its json form, the masked fields keep only the first rune.
+func Mask(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "masking error"
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return "masking error"
+	}
+
+	maskMu.RLock()
+	defer maskMu.RUnlock()
+
+	return maskValue(raw)
+}
+
+func maskValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if maskFields[strings.ToLower(key)] {
+				v[key] = maskString(value)
+				continue
+			}
+			v[key] = maskValue(value)
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = maskValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func maskString(v any) string {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return "***"
+	}
+	return string([]rune(s)[0]) + "***"
+}
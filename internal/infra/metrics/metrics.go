@@ -0,0 +1,327 @@
+package metrics
+
+import(
+	"time"
+	"strconv"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.metrics").Logger()
+
+const namespace = "onboarding"
+
+// Registry used by the /metrics endpoint
+var Registry = prometheus.NewRegistry()
+
+// the tenants labelled in the business metrics, the tenant ids come from the requests
+// so the others are counted together to bound the cardinality
+var tenants atomic.Pointer[map[string]bool]
+
+var (
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "http_requests_total",
+		Help: "http requests by route template, method and status code",
+	}, []string{"route", "method", "code"})
+
+	HttpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name: "http_request_duration_seconds",
+		Help: "http request latency by route template and method",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	HttpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name: "http_requests_in_flight",
+		Help: "http requests being served",
+	})
+
+	GrpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "grpc_requests_total",
+		Help: "grpc calls by full method and status code",
+	}, []string{"method", "code"})
+
+	GrpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name: "grpc_request_duration_seconds",
+		Help: "grpc call latency by full method, the whole stream for the streaming ones",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	DbTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "db_transactions_total",
+		Help: "database transactions by operation and result (commit, rollback)",
+	}, []string{"operation", "result"})
+
+	UploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "upload_bytes_total",
+		Help: "bytes uploaded to the bucket",
+	})
+
+	UploadSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name: "upload_size_bytes",
+		Help: "size of the uploaded files",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 9), // 1KB .. 64MB
+	})
+
+	OnboardingEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "onboarding_events_total",
+		Help: "onboarding business events by tenant (METRICS_TENANTS, else other) and event (person_added, person_updated, file_uploaded)",
+	}, []string{"tenant", "event"})
+
+	ConcurrencyLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name: "concurrency_limit",
+		Help: "current adaptive concurrency limit of the load shedder",
+	})
+
+	ConcurrencyInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name: "concurrency_in_flight",
+		Help: "requests admitted by the load shedder being served",
+	})
+
+	ShedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "shed_requests_total",
+		Help: "requests rejected by the load shedder by priority and reason (limit, pool_saturated)",
+	}, []string{"priority", "reason"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "cache_requests_total",
+		Help: "cache lookups by cache, layer (local, shared) and result (hit, negative_hit, miss)",
+	}, []string{"cache", "layer", "result"})
+
+	DbReads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "db_reads_total",
+		Help: "database reads by pool (primary, reader) and routing reason",
+	}, []string{"pool", "reason"})
+
+	Retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "retries_total",
+		Help: "attempts retried after a retryable error by operation",
+	}, []string{"operation"})
+
+	Reencrypted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "persons_reencrypted_total",
+		Help: "persons encrypted again with the active data key",
+	})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "webhook_deliveries_total",
+		Help: "webhook delivery attempts by event and result (delivered, failed, dead)",
+	}, []string{"event", "result"})
+
+	WebhookDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name: "webhook_delivery_duration_seconds",
+		Help: "webhook delivery attempt latency by event",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"event"})
+
+	JobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name: "job_runs_total",
+		Help: "asynchronous job runs by kind and outcome (succeeded, retry, failed, canceled, interrupted)",
+	}, []string{"kind", "outcome"})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name: "job_run_duration_seconds",
+		Help: "asynchronous job run latency by kind",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600},
+	}, []string{"kind"})
+)
+
+func init() {
+	Registry.MustRegister(	collectors.NewGoCollector(),
+							collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+							HttpRequests,
+							HttpDuration,
+							HttpInFlight,
+							GrpcRequests,
+							GrpcDuration,
+							DbTransactions,
+							UploadBytes,
+							UploadSize,
+							OnboardingEvents,
+							ConcurrencyLimit,
+							ConcurrencyInFlight,
+							ShedRequests,
+							CacheRequests,
+							DbReads,
+							Retries,
+							Reencrypted,
+							WebhookDeliveries,
+							WebhookDuration,
+							JobRuns,
+							JobDuration,
+	)
+}
+
+// About the /metrics handler
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// About count a transaction result
+func ObserveTx(operation string, err error) {
+	if err != nil {
+		DbTransactions.WithLabelValues(operation, "rollback").Inc()
+		return
+	}
+	DbTransactions.WithLabelValues(operation, "commit").Inc()
+}
+
+// About set the tenants labelled in the business metrics, the others are "other"
+func SetTenants(list []string) {
+	allowed := make(map[string]bool, len(list))
+	for _, tenant := range list {
+		allowed[tenant] = true
+	}
+	tenants.Store(&allowed)
+}
+
+// About count a business event, empty tenants are reported as "unknown" and the
+// tenants not set as "other"
+func ObserveEvent(tenant string, event string) {
+	if tenant == "" {
+		tenant = "unknown"
+	} else if allowed := tenants.Load(); allowed == nil || !(*allowed)[tenant] {
+		tenant = "other"
+	}
+	OnboardingEvents.WithLabelValues(tenant, event).Inc()
+}
+
+// About observe a grpc call
+func ObserveGrpc(method string, code string, elapsed time.Duration) {
+	GrpcDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+	GrpcRequests.WithLabelValues(method, code).Inc()
+}
+
+// About observe a webhook delivery attempt
+func ObserveWebhook(event string, result string, elapsed time.Duration) {
+	WebhookDuration.WithLabelValues(event).Observe(elapsed.Seconds())
+	WebhookDeliveries.WithLabelValues(event, result).Inc()
+}
+
+// About observe a run of a asynchronous job
+func ObserveJob(kind string, outcome string, elapsed time.Duration) {
+	JobDuration.WithLabelValues(kind).Observe(elapsed.Seconds())
+	JobRuns.WithLabelValues(kind, outcome).Inc()
+}
+
+// About observe a uploaded file
+func ObserveUpload(size int) {
+	UploadBytes.Add(float64(size))
+	UploadSize.Observe(float64(size))
+}
+
+// ResponseWriter keeping the status code
+type statusWriter struct {
+	http.ResponseWriter
+	status	int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// About the RED metrics middleware, labelled by the mux route template
+// (e.g. /person/{id}) so the raw paths do not explode the cardinality
+func MiddleWareMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		route := "unmatched"
+		if current := mux.CurrentRoute(req); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		HttpInFlight.Inc()
+		defer HttpInFlight.Dec()
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: rw, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+
+		HttpDuration.WithLabelValues(route, req.Method).Observe(time.Since(start).Seconds())
+		HttpRequests.WithLabelValues(route, req.Method, strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// pgx pool stats exposed as gauges at scrape time
+type poolCollector struct {
+	stat				func() *pgxpool.Stat
+	acquiredConns		*prometheus.Desc
+	idleConns			*prometheus.Desc
+	totalConns			*prometheus.Desc
+	maxConns			*prometheus.Desc
+	constructingConns	*prometheus.Desc
+	acquireCount		*prometheus.Desc
+	emptyAcquireCount	*prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	acquireDuration		*prometheus.Desc
+}
+
+// About register the pool gauges
+func RegisterPool(name string, stat func() *pgxpool.Stat) {
+	childLogger.Info().Str("func","RegisterPool").Str("pool", name).Send()
+
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", metric), help, nil, labels)
+	}
+
+	Registry.MustRegister(&poolCollector{
+		stat: stat,
+		acquiredConns: desc("acquired_conns", "connections currently acquired"),
+		idleConns: desc("idle_conns", "idle connections"),
+		totalConns: desc("total_conns", "total connections"),
+		maxConns: desc("max_conns", "max connections"),
+		constructingConns: desc("constructing_conns", "connections being opened"),
+		acquireCount: desc("acquire_total", "successful acquires"),
+		emptyAcquireCount: desc("empty_acquire_total", "acquires that waited for a connection"),
+		canceledAcquireCount: desc("canceled_acquire_total", "acquires canceled by the context"),
+		acquireDuration: desc("acquire_duration_seconds_total", "time spent acquiring connections"),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stats.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stats.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stats.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stats.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stats.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stats.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stats.AcquireDuration().Seconds())
+}
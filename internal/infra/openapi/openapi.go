@@ -0,0 +1,287 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"bytes"
+	"errors"
+	"strings"
+	"net/http"
+	"encoding/json"
+	_ "embed"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+
+	"github.com/eliezerraj/go-core/coreJson"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.openapi").Logger()
+
+// The contract of the http routes, every route registered by the http server must be in it
+//go:embed openapi.yaml
+var spec []byte
+
+// the responses bigger than it are not validated
+const maxResponseBody = 1 << 20
+
+func init() {
+	// the errors returned to the clients have the reason only, not the schema
+	openapi3.SchemaErrorDetailsDisabled = true
+}
+
+// Validator of the requests and responses against the openapi document. The requests not
+// matching it are refused with a bad request, the responses not matching it are logged.
+type Validator struct {
+	doc					*openapi3.T
+	docJSON				[]byte
+	validateRequest		bool
+	validateResponse	bool
+}
+
+// About load the openapi document, it fails when the document is not valid
+func NewValidator(validateRequest bool, validateResponse bool) (*Validator, error) {
+	childLogger.Info().Str("func","NewValidator").Bool("validate_request", validateRequest).Bool("validate_response", validateResponse).Send()
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("openapi document: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("openapi document: %w", err)
+	}
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validator{	doc: doc,
+						docJSON: docJSON,
+						validateRequest: validateRequest,
+						validateResponse: validateResponse,
+	}, nil
+}
+
+// About serve the openapi document as json
+func (v *Validator) ServeSpec(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(v.docJSON)
+}
+
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>go-onboarding</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>`
+
+// About serve the swagger ui page of the openapi document
+func (v *Validator) ServeSwaggerUI(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Write([]byte(swaggerPage))
+}
+
+// About the route of the document of a request, by the template of the mux route
+// matched, nil when the route is not documented
+func (v *Validator) route(req *http.Request) (*routers.Route, map[string]string) {
+	current := mux.CurrentRoute(req)
+	if current == nil {
+		return nil, nil
+	}
+	template, err := current.GetPathTemplate()
+	if err != nil {
+		return nil, nil
+	}
+	pathItem := v.doc.Paths.Find(template)
+	if pathItem == nil || pathItem.GetOperation(req.Method) == nil {
+		return nil, nil
+	}
+	return &routers.Route{	Spec: v.doc,
+							Path: template,
+							PathItem: pathItem,
+							Method: req.Method,
+							Operation: pathItem.GetOperation(req.Method),
+	}, mux.Vars(req)
+}
+
+// ResponseWriter keeping a copy of the json responses to be validated
+type captureWriter struct {
+	http.ResponseWriter
+	status	int
+	body	bytes.Buffer
+	skip	bool
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if !w.skip {
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || w.body.Len() + len(b) > maxResponseBody {
+			w.skip = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// About the validation middleware, it must run after the correlation middlewares.
+// The routes not documented and the preflight requests are not validated, a nil
+// validator validates nothing.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	if v == nil || (!v.validateRequest && !v.validateResponse) {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		route, pathParams := v.route(req)
+		if route == nil || req.Method == http.MethodOptions {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{	Request: req,
+															PathParams: pathParams,
+															Route: route,
+															Options: &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		}
+		if v.validateRequest {
+			// the body read is put back in the request
+			if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+				v.writeError(rw, req, err)
+				return
+			}
+		}
+		if !v.validateResponse {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		cw := &captureWriter{ResponseWriter: rw, status: http.StatusOK}
+		next.ServeHTTP(cw, req)
+		if cw.skip {
+			return
+		}
+
+		output := &openapi3filter.ResponseValidationInput{	RequestValidationInput: input,
+															Status: cw.status,
+															Header: cw.Header(),
+															Options: &openapi3filter.Options{IncludeResponseStatus: true},
+		}
+		output.SetBodyBytes(cw.body.Bytes())
+		if err := openapi3filter.ValidateResponse(req.Context(), output); err != nil {
+			logging.Ctx(req.Context(), childLogger).Warn().Str("reason", describe(err)).Int("status", cw.status).Msg("response not matching the openapi document")
+		}
+	})
+}
+
+// About refuse a request not matching the document, as the ErrorHandler of the routes
+func (v *Validator) writeError(rw http.ResponseWriter, req *http.Request, err error) {
+	trace_id := fmt.Sprintf("%v", req.Context().Value("trace-request-id"))
+	logging.Ctx(req.Context(), childLogger).Info().Str("reason", describe(err)).Msg("request not matching the openapi document")
+
+	var apiError coreJson.APIError
+	apiError = apiError.NewAPIError(fmt.Errorf("%w: %s", erro.ErrInvalid, describe(err)), trace_id, http.StatusBadRequest)
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(rw).Encode(apiError)
+}
+
+// About the reason of a validation error and the field it is about, in the format
+// of the validation errors of the service (person.name: reason)
+func describe(err error) string {
+	reason := err.Error()
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		reason = schemaErr.Reason
+		if field := strings.Join(schemaErr.JSONPointer(), "."); field != "" {
+			reason = field + ": " + reason
+		}
+	}
+
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) {
+		if requestErr.Parameter != nil {
+			return requestErr.Parameter.Name + ": " + strings.TrimPrefix(reason, requestErr.Parameter.Name + ": ")
+		}
+		if schemaErr == nil {
+			return requestErr.Reason
+		}
+	}
+	return reason
+}
+
+// the methods a route is checked for
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// About the methods a route serves, told by matching a request of each method: the
+// method matchers of the subrouters add up. A route of any method is a GET one.
+func routeMethods(route *mux.Route) []string {
+	names, _ := route.GetVarNames()
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, name, "1")
+	}
+	url, err := route.URLPath(pairs...)
+	if err != nil {
+		return nil
+	}
+
+	served := []string{}
+	for _, method := range methods {
+		req, err := http.NewRequest(method, url.String(), nil)
+		if err != nil {
+			continue
+		}
+		var match mux.RouteMatch
+		if route.Match(req, &match) && match.MatchErr == nil {
+			served = append(served, method)
+		}
+	}
+	if len(served) == len(methods) {
+		return []string{http.MethodGet}
+	}
+	return served
+}
+
+// About the routes of the router missing in the document, as "METHOD /template"
+func (v *Validator) Undocumented(router *mux.Router) []string {
+	missing := []string{}
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			// a subrouter, its routes are walked
+			return nil
+		}
+
+		pathItem := v.doc.Paths.Find(template)
+		for _, method := range routeMethods(route) {
+			if pathItem == nil || pathItem.GetOperation(method) == nil {
+				missing = append(missing, method + " " + template)
+			}
+		}
+		return nil
+	})
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,138 @@
+package ratelimit
+
+import(
+	"fmt"
+	"context"
+	"math"
+	"strconv"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/eliezerraj/go-core/coreJson"
+
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.ratelimit").Logger()
+
+var core_json coreJson.CoreJson
+var core_apiError coreJson.APIError
+
+// Class of route, each one has its own limit
+type Class string
+
+const (
+	ClassRead	Class = "read"
+	ClassWrite	Class = "write"
+	ClassUpload	Class = "upload"
+)
+
+type Limiter struct {
+	store		Store
+	limits		map[Class]Limit
+}
+
+// About create a limiter
+func NewLimiter(store Store, limits map[Class]Limit) *Limiter {
+	childLogger.Info().Str("func","NewLimiter").Interface("limits", limits).Send()
+
+	return &Limiter{
+		store: store,
+		limits: limits,
+	}
+}
+
+// About the client key of a request, see Key
+func (l *Limiter) key(req *http.Request) string {
+	return Key(req.Context(), logging.ClientIP(req))
+}
+
+// About the client key: the client authenticated by a token, else the client ip seen by
+// the service (the peer or the hop before the trusted proxies). The X-Tenant-Id and
+// X-Client-Id headers are set by the caller, a key on them would be bypassed by changing
+// them: they are only logged.
+func Key(ctx context.Context, ip string) string {
+	if correlation := logging.CorrelationFrom(ctx); correlation != nil && correlation.Admin && correlation.Client != "" {
+		return "client:" + correlation.Client
+	}
+	return "ip:" + ip
+}
+
+// About seconds rounded up, as used by the headers
+func seconds(f float64) string {
+	return strconv.Itoa(int(math.Ceil(f)))
+}
+
+// About take a token of a route class for a client key, for the servers other than the
+// http one. It returns whether the call is allowed and, when not, the seconds to wait.
+// A nil limiter, a class without limit or a store error let everything pass.
+func (l *Limiter) Allow(ctx context.Context, class Class, clientKey string) (bool, string) {
+	if l == nil {
+		return true, ""
+	}
+	limit, ok := l.limits[class]
+	if !ok || limit.Rate <= 0 {
+		return true, ""
+	}
+
+	key := string(class) + ":" + clientKey
+	result, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		// fail open, the limiter must not take the service down
+		logging.Ctx(ctx, childLogger).Error().Err(err).Str("key", key).Msg("rate limit store error")
+		return true, ""
+	}
+	if !result.Allowed {
+		logging.Ctx(ctx, childLogger).Warn().Str("key", key).Msg("rate limit exceeded")
+		return false, seconds(result.RetryAfter.Seconds())
+	}
+	return true, ""
+}
+
+// About the rate limit middleware of a route class.
+// A nil limiter or a class without limit lets everything pass.
+func (l *Limiter) Middleware(class Class) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		limit, ok := l.limits[class]
+		if !ok || limit.Rate <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			key := string(class) + ":" + l.key(req)
+
+			result, err := l.store.Take(req.Context(), key, limit)
+			if err != nil {
+				// fail open, the limiter must not take the service down
+				logging.Ctx(req.Context(), childLogger).Error().Err(err).Str("key", key).Msg("rate limit store error")
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			rw.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			rw.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			rw.Header().Set("RateLimit-Reset", seconds(result.Reset.Seconds()))
+			rw.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Burst, seconds(float64(limit.Burst) / limit.Rate)))
+
+			if !result.Allowed {
+				logging.Ctx(req.Context(), childLogger).Warn().
+					Str("key", key).
+					Str("tenant", req.Header.Get("X-Tenant-Id")).
+					Str("client", req.Header.Get("X-Client-Id")).
+					Msg("rate limit exceeded")
+
+				trace_id := fmt.Sprintf("%v", req.Context().Value("trace-request-id"))
+				rw.Header().Set("Retry-After", seconds(result.RetryAfter.Seconds()))
+				apiError := core_apiError.NewAPIError(erro.ErrTooManyRequests, trace_id, http.StatusTooManyRequests)
+				core_json.WriteJSON(rw, http.StatusTooManyRequests, apiError)
+				return
+			}
+
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
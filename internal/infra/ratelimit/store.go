@@ -0,0 +1,158 @@
+package ratelimit
+
+import(
+	"math"
+	"sync"
+	"time"
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Token bucket: Rate tokens per second up to Burst tokens
+type Limit struct {
+	Rate	float64
+	Burst	int
+}
+
+// Result of taking a token
+type Result struct {
+	Allowed		bool
+	Limit		int
+	Remaining	int
+	Reset		time.Duration	// until the bucket is full again
+	RetryAfter	time.Duration	// until the next token, when not allowed
+}
+
+// Store keeps the buckets, a shared store makes the limits global across replicas
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// About build the result from the tokens left in the bucket
+func newResult(allowed bool, tokens float64, limit Limit) Result {
+	result := Result{	Allowed: allowed,
+						Limit: limit.Burst,
+						Remaining: int(math.Floor(tokens)),
+						Reset: time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	}
+	return result
+}
+
+type bucket struct {
+	tokens	float64
+	last	time.Time
+}
+
+// MemoryStore keeps the buckets in the process, limits are per replica
+type MemoryStore struct {
+	mu			sync.Mutex
+	buckets		map[string]*bucket
+	lastSweep	time.Time
+}
+
+// About create a memory store
+func NewMemoryStore() *MemoryStore {
+	childLogger.Info().Str("func","NewMemoryStore").Send()
+
+	return &MemoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+// About take a token
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens + now.Sub(b.last).Seconds() * limit.Rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+// About drop the buckets idle for a while, a full bucket is the same as no bucket
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > 10 * time.Minute {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Token bucket in a redis hash, the redis clock is used so all replicas agree
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in redis (or any server speaking its protocol
+// with lua support, e.g. valkey, elasticache), limits are shared by the replicas
+type RedisStore struct {
+	client	redis.UniversalClient
+	prefix	string
+}
+
+// About create a redis store from a redis:// url
+func NewRedisStore(url string) (*RedisStore, error) {
+	childLogger.Info().Str("func","NewRedisStore").Send()
+
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: redis.NewClient(options), prefix: "go-onboarding:ratelimit:"}, nil
+}
+
+// About take a token
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	res, err := takeScript.Run(ctx, s.client, []string{s.prefix + key}, limit.Rate, limit.Burst).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(allowed == 1, tokens, limit), nil
+}
+
+// About close the redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
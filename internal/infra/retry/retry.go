@@ -0,0 +1,68 @@
+package retry
+
+import(
+	"time"
+	"context"
+	"math/rand/v2"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.retry").Logger()
+
+// Policy of exponential backoff with full jitter, the wait before the attempt n+1
+// is random between 0 and min(MaxDelay, BaseDelay * 2^n)
+type Policy struct {
+	MaxAttempts	int
+	BaseDelay	time.Duration
+	MaxDelay	time.Duration
+}
+
+// About the wait after a failed attempt, attempt starts at 1
+func (p Policy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 {
+		if exp := p.BaseDelay << (attempt - 1); exp > 0 && exp < p.MaxDelay {
+			delay = exp
+		}
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay)
+}
+
+// About run fn until it succeeds, the error is not retryable, the attempts are
+// over or the context is done. The last error is returned.
+func Do(ctx context.Context,
+		policy Policy,
+		operation string,
+		retryable func(error) bool,
+		fn func(ctx context.Context) error) error {
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := policy.Backoff(attempt)
+		logging.Ctx(ctx, childLogger).Warn().Err(err).
+									Str("operation", operation).
+									Int("attempt", attempt).
+									Str("delay", delay.String()).
+									Msg("retryable error, trying again")
+		metrics.Retries.WithLabelValues(operation).Inc()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
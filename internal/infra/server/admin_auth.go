@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"net/http"
+	"crypto/subtle"
+
+	"github.com/eliezerraj/go-core/coreJson"
+
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/logging"
+)
+
+var core_json coreJson.CoreJson
+var core_apiError coreJson.APIError
+
+// About read the admin tokens of the /admin routes, without a token file they are disabled
+func (h *HttpServer) LoadAdminTokens() error {
+	if h.httpServer.AdminTokenFile == "" {
+		return nil
+	}
+	tokens, err := readTokenFile(h.httpServer.AdminTokenFile)
+	if err != nil {
+		return err
+	}
+	h.adminTokens = tokens
+	return nil
+}
+
+// About the admin id of the bearer token of a request, "" without a valid one
+func (h *HttpServer) adminID(req *http.Request) string {
+	admin := ""
+	if token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); found && token != "" {
+		for known, id := range h.adminTokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+				admin = id
+			}
+		}
+	}
+	return admin
+}
+
+// About mark the caller as admin in the correlation, the admin id becomes its client
+func markAdmin(req *http.Request, admin string) {
+	if correlation := logging.CorrelationFrom(req.Context()); correlation != nil {
+		correlation.Client = admin
+		correlation.Admin = true
+	}
+}
+
+// About the identity middleware of the other routes, a caller sending the bearer token
+// of a admin may call them without X-Tenant-Id to reach all the tenants. A request
+// without a valid token goes on as it is, bound to its X-Tenant-Id.
+func (h *HttpServer) adminIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if admin := h.adminID(req); admin != "" {
+			markAdmin(req, admin)
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// About the auth middleware of the /admin and /job routes, the caller must send the bearer token
+// of a admin of the token file. The admin id becomes the client of the correlation.
+func (h *HttpServer) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		trace_id := fmt.Sprintf("%v", req.Context().Value("trace-request-id"))
+
+		if len(h.adminTokens) == 0 {
+			apiError := core_apiError.NewAPIError(erro.ErrHTTPForbiden, trace_id, http.StatusForbidden)
+			core_json.WriteJSON(rw, http.StatusForbidden, apiError)
+			return
+		}
+
+		admin := h.adminID(req)
+		if admin == "" {
+			logging.Ctx(req.Context(), childLogger).Warn().Str("route", req.URL.Path).Msg("admin route without a valid bearer token")
+
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			apiError := core_apiError.NewAPIError(erro.ErrUnauthorized, trace_id, http.StatusUnauthorized)
+			core_json.WriteJSON(rw, http.StatusUnauthorized, apiError)
+			return
+		}
+
+		markAdmin(req, admin)
+		logging.Ctx(req.Context(), childLogger).Info().Str("admin", admin).Str("method", req.Method).Str("route", req.URL.Path).Msg("admin call")
+
+		next.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/service"
+	"github.com/go-onboarding/internal/adapter/api"
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/database/pgtest"
+	"github.com/go-onboarding/internal/infra/health"
+
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// a caller reaches the persons of its tenant, a admin all of them, the others none
+func TestPersonCallerScope(t *testing.T) {
+	fake, err := pgtest.NewServer(personHandler)
+	if err != nil {
+		t.Fatalf("fake database: %v", err)
+	}
+	defer fake.Close()
+
+	host, port := fake.HostPort()
+	databasePGServer, err := database.NewDatabasePGServer(context.Background(),
+														go_core_pg.DatabaseConfig{Host: host, Port: port, DatabaseName: "onboarding", DbMax_Connection: 2},
+														staticCredentials{})
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	defer databasePGServer.CloseConnection()
+
+	workerService := service.NewWorkerService(database.NewWorkerRepository(databasePGServer), nil, &model.AwsService{})
+	httpRouters := api.NewHttpRouters(workerService, 5, health.NewHealth())
+
+	h := NewHttpAppServer(&model.Server{})
+	h.adminTokens = map[string]string{"admin-token": "ops"}
+	router := h.Router(&httpRouters, &model.AppServer{})
+
+	cases := []struct {
+		name			string
+		tenant			string
+		authorization	string
+		want			int
+	}{
+		{name: "bound to the tenant", tenant: "T-1", want: http.StatusOK},
+		{name: "not bound", want: http.StatusUnauthorized},
+		{name: "not bound with a invalid token", authorization: "Bearer other", want: http.StatusUnauthorized},
+		{name: "admin not bound", authorization: "Bearer admin-token", want: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/person/P-1", nil)
+			if tc.tenant != "" {
+				req.Header.Set("X-Tenant-Id", tc.tenant)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("GET /person/P-1: status %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+// the job routes reach every tenant, they are only for the admins
+func TestJobAdminAuth(t *testing.T) {
+	httpRouters := api.NewHttpRouters(&service.WorkerService{}, 5, health.NewHealth())
+
+	h := NewHttpAppServer(&model.Server{})
+	h.adminTokens = map[string]string{"admin-token": "ops"}
+	router := h.Router(&httpRouters, &model.AppServer{})
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/job"},
+		{http.MethodGet, "/job/J-1"},
+		{http.MethodPost, "/job"},
+		{http.MethodPost, "/job/J-1/cancel"},
+	} {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("X-Tenant-Id", "T-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without the admin token: status %d, want %d", route.method, route.path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+// a caller bound to a tenant may not ask for the persons of another one
+func TestPersonOtherTenant(t *testing.T) {
+	httpRouters := api.NewHttpRouters(&service.WorkerService{}, 5, health.NewHealth())
+	router := NewHttpAppServer(&model.Server{}).Router(&httpRouters, &model.AppServer{})
+
+	for _, path := range []string{
+		"/person/lookup?tenant_id=T-2&tax_id=52998224725",
+		"/person/search?tenant_id=T-2&q=maria",
+		"/person/duplicates?tenant_id=T-2",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Tenant-Id", "T-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("GET %s bound to T-1: status %d, want %d (%s)", path, rec.Code, http.StatusForbidden, rec.Body.String())
+		}
+	}
+}
+
+// the child collections of a person of another tenant are not found, nor changed
+func TestAddressOtherTenant(t *testing.T) {
+	fake, err := pgtest.NewServer(func(sql string, args []*string) (pgtest.Result, error) {
+		if strings.Contains(sql, "FOR UPDATE") {
+			return pgtest.Result{	Columns: []pgtest.Column{{Name: "id", OID: pgtype.Int4OID}, {Name: "tenant_id", OID: pgtype.TextOID}},
+									Rows: [][]any{{int32(1), "T-1"}},
+			}, nil
+		}
+		return pgtest.Result{}, nil
+	})
+	if err != nil {
+		t.Fatalf("fake database: %v", err)
+	}
+	defer fake.Close()
+
+	host, port := fake.HostPort()
+	databasePGServer, err := database.NewDatabasePGServer(context.Background(),
+														go_core_pg.DatabaseConfig{Host: host, Port: port, DatabaseName: "onboarding", DbMax_Connection: 2},
+														staticCredentials{})
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	defer databasePGServer.CloseConnection()
+
+	workerService := service.NewWorkerService(database.NewWorkerRepository(databasePGServer), nil, &model.AwsService{})
+	httpRouters := api.NewHttpRouters(workerService, 5, health.NewHealth())
+	router := NewHttpAppServer(&model.Server{}).Router(&httpRouters, &model.AppServer{})
+
+	req := httptest.NewRequest(http.MethodDelete, "/person/P-1/address/7", nil)
+	req.Header.Set("X-Tenant-Id", "T-2")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE /person/P-1/address/7 bound to T-2: status %d, want %d (%s)", rec.Code, http.StatusNotFound, rec.Body.String())
+	}
+	for _, query := range fake.Queries() {
+		if strings.Contains(query.SQL, "person_address") || strings.Contains(query.SQL, "person_audit") {
+			t.Errorf("query of the other tenant ran: %s", query.SQL)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net"
+	"time"
+	"strings"
+	"context"
+	"crypto/subtle"
+
+	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/loadshed"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/ratelimit"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// About the methods called without a token: the probes and the reflection of the tools
+func authExempt(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+// About the first value of a incoming metadata key
+func metadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// About authenticate the caller and put the correlation in the context, shared by the
+// unary and stream calls. With a token file the caller is the client of the bearer token,
+// else the x-client-id metadata or the peer address. The x-tenant-id metadata binds the
+// call to a tenant, a client of the token file may call without it to reach all of them.
+func (g *GrpcServer) correlate(ctx context.Context, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	correlation := &logging.Correlation{	Route: fullMethod,
+											Tenant: metadataValue(md, "x-tenant-id"),
+											Client: metadataValue(md, "x-client-id"),
+											CallerTenant: metadataValue(md, "x-tenant-id"),
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		correlation.TraceID = spanContext.TraceID().String()
+		ctx = context.WithValue(ctx, "trace-request-id", correlation.TraceID)
+		grpc.SetHeader(ctx, metadata.Pairs("x-trace-id", correlation.TraceID))
+	}
+
+	if g.tokens != nil && !authExempt(fullMethod) {
+		token, found := strings.CutPrefix(metadataValue(md, "authorization"), "Bearer ")
+		if !found || token == "" {
+			return ctx, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		client := ""
+		for known, id := range g.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+				client = id
+			}
+		}
+		if client == "" {
+			return ctx, status.Error(codes.Unauthenticated, "invalid bearer token")
+		}
+		correlation.Client = client
+		correlation.Admin = true
+	}
+	if correlation.Client == "" {
+		if p, ok := peer.FromContext(ctx); ok {
+			correlation.Client = p.Addr.String()
+		}
+	}
+
+	return logging.WithCorrelation(ctx, correlation), nil
+}
+
+// About the rate limit class and the load shedding priority of a method, the reads are
+// shed before the writes as on the http routes
+func methodClass(fullMethod string) (ratelimit.Class, loadshed.Priority) {
+	name := fullMethod[strings.LastIndex(fullMethod, "/") + 1:]
+	for _, read := range []string{"Get", "List", "Lookup", "Search"} {
+		if strings.HasPrefix(name, read) {
+			return ratelimit.ClassRead, loadshed.PriorityRead
+		}
+	}
+	return ratelimit.ClassWrite, loadshed.PriorityWrite
+}
+
+// About admit a call as the http routes are: the rate limit of the client (the one of
+// the token, else the peer ip) and then the load shedding. The release is called once
+// the call is done. The probes and the reflection are always admitted.
+func (g *GrpcServer) admit(ctx context.Context, fullMethod string) (func(), error) {
+	if authExempt(fullMethod) {
+		return func() {}, nil
+	}
+	class, priority := methodClass(fullMethod)
+
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if allowed, retryAfter := g.rateLimiter.Allow(ctx, class, ratelimit.Key(ctx, ip)); !allowed {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+		return nil, status.Error(codes.ResourceExhausted, erro.ErrTooManyRequests.Error())
+	}
+
+	release := g.loadShedder.Admit(ctx, priority)
+	if release == nil {
+		return nil, status.Error(codes.Unavailable, erro.ErrOverloaded.Error())
+	}
+	return release, nil
+}
+
+// About the access log and the metrics of a call
+func observeCall(ctx context.Context, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+	metrics.ObserveGrpc(fullMethod, code.String(), time.Since(start))
+
+	if authExempt(fullMethod) {
+		return
+	}
+	logging.Ctx(ctx, childLogger).Info().
+		Str("code", code.String()).
+		Int64("latency_ms", time.Since(start).Milliseconds()).
+		Msg("access")
+}
+
+func (g *GrpcServer) unaryInterceptor(	ctx context.Context,
+										req any,
+										info *grpc.UnaryServerInfo,
+										handler grpc.UnaryHandler) (resp any, err error) {
+	start := time.Now()
+	ctx, err = g.correlate(ctx, info.FullMethod)
+	if err == nil {
+		var release func()
+		if release, err = g.admit(ctx, info.FullMethod); err == nil {
+			resp, err = handler(ctx, req)
+			release()
+		}
+	}
+	observeCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// ServerStream with the context of the correlation
+type correlatedStream struct {
+	grpc.ServerStream
+	ctx	context.Context
+}
+
+func (s *correlatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (g *GrpcServer) streamInterceptor(	srv any,
+										stream grpc.ServerStream,
+										info *grpc.StreamServerInfo,
+										handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	ctx, err := g.correlate(stream.Context(), info.FullMethod)
+	if err == nil {
+		var release func()
+		if release, err = g.admit(ctx, info.FullMethod); err == nil {
+			err = handler(srv, &correlatedStream{ServerStream: stream, ctx: ctx})
+			release()
+		}
+	}
+	observeCall(ctx, info.FullMethod, start, err)
+	return err
+}
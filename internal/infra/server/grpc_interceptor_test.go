@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net"
+	"context"
+	"testing"
+
+	"github.com/go-onboarding/internal/infra/ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// a grpc client over its limit is refused as a http one is, the probes never are
+func TestGrpcRateLimit(t *testing.T) {
+	g := &GrpcServer{}
+	g.SetRateLimiter(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassRead: {Rate: 0.001, Burst: 1},
+	}))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	cases := []struct {
+		name	string
+		method	string
+		want	codes.Code
+	}{
+		{name: "first read", method: "/onboarding.Onboarding/GetPerson", want: codes.OK},
+		{name: "read over the limit", method: "/onboarding.Onboarding/ListPerson", want: codes.ResourceExhausted},
+		{name: "write without limit", method: "/onboarding.Onboarding/AddPerson", want: codes.OK},
+		{name: "probe", method: "/grpc.health.v1.Health/Check", want: codes.OK},
+	}
+	for _, tc := range cases {
+		_, err := g.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+		if code := status.Code(err); code != tc.want {
+			t.Errorf("%s: code %v, want %v (%v)", tc.name, code, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"os"
+	"net"
+	"time"
+	"strconv"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/adapter/rpc"
+	"github.com/go-onboarding/internal/infra/health"
+	"github.com/go-onboarding/internal/infra/loadshed"
+	"github.com/go-onboarding/internal/infra/ratelimit"
+	onboardingv1 "github.com/go-onboarding/proto/onboarding/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+	grpc_health "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+)
+
+// interval the readiness is polled to set the status of the grpc health service
+const grpcHealthInterval = 5 * time.Second
+
+type GrpcServer struct {
+	grpcServer	*model.Server
+	tokens		map[string]string	// bearer token -> client id
+	rateLimiter	*ratelimit.Limiter
+	loadShedder	*loadshed.Limiter
+	server		*grpc.Server
+	health		*grpc_health.Server
+	stop		chan struct{}
+}
+
+// about create a grpc server, the tokens of the clients are read from the token file
+func NewGrpcAppServer(grpcServer *model.Server) (*GrpcServer, error) {
+	childLogger.Info().Str("func","NewGrpcAppServer").Send()
+
+	g := GrpcServer{grpcServer: grpcServer,
+					health: grpc_health.NewServer(),
+					stop: make(chan struct{}),
+	}
+	if grpcServer.GrpcTokenFile != "" {
+		tokens, err := readTokenFile(grpcServer.GrpcTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		g.tokens = tokens
+	}
+	return &g, nil
+}
+
+// About set the rate limiter of the calls, nil disables it
+func (g *GrpcServer) SetRateLimiter(rateLimiter *ratelimit.Limiter) {
+	g.rateLimiter = rateLimiter
+}
+
+// About set the load shedder of the calls, nil disables it
+func (g *GrpcServer) SetLoadShedder(loadShedder *loadshed.Limiter) {
+	g.loadShedder = loadShedder
+}
+
+// About read the json file of the client ids and their tokens, {"client-id": "token"}
+func readTokenFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	clients := map[string]string{}
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("token file %s: %w", path, err)
+	}
+
+	tokens := map[string]string{}
+	for client, token := range clients {
+		if token == "" {
+			return nil, fmt.Errorf("token file %s: empty token of client %q", path, client)
+		}
+		tokens[token] = client
+	}
+	return tokens, nil
+}
+
+// About start the grpc server, it serves in background until the Shutdown.
+// The readiness of the health checks is the status of the grpc health service.
+func (g *GrpcServer) StartGrpcAppServer(ctx context.Context,
+										grpcRouters *rpc.GrpcRouters,
+										healthCheck *health.Health,
+										appServer *model.AppServer) error {
+	childLogger.Info().Str("func","StartGrpcAppServer").Send()
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(g.unaryInterceptor),
+		grpc.ChainStreamInterceptor(g.streamInterceptor),
+	}
+	if appServer.Cert.IsTLS {
+		serverTLSConf, err := SetTLSOn(appServer.Cert.CertPEM, appServer.Cert.CertPrivKeyPEM)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(serverTLSConf)))
+	}
+
+	g.server = grpc.NewServer(opts...)
+	onboardingv1.RegisterOnboardingServiceServer(g.server, grpcRouters)
+	healthpb.RegisterHealthServer(g.server, g.health)
+	reflection.Register(g.server)
+
+	listener, err := net.Listen("tcp", ":" + strconv.Itoa(g.grpcServer.GrpcPort))
+	if err != nil {
+		return err
+	}
+
+	childLogger.Info().Str("Grpc Port", strconv.Itoa(g.grpcServer.GrpcPort)).Send()
+
+	go func() {
+		if err := g.server.Serve(listener); err != nil {
+			childLogger.Error().Err(err).Msg("canceling grpc server !!!")
+		}
+	}()
+	go g.watchHealth(ctx, healthCheck)
+
+	return nil
+}
+
+// About set the status of the grpc health service from the readiness
+func (g *GrpcServer) watchHealth(ctx context.Context, healthCheck *health.Health) {
+	ticker := time.NewTicker(grpcHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if healthCheck.Run(ctx, health.ProbeReady).Status != health.StatusUp {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		g.health.SetServingStatus("", status)
+		g.health.SetServingStatus(onboardingv1.OnboardingService_ServiceDesc.ServiceName, status)
+
+		select {
+		case <-ticker.C:
+		case <-g.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// About stop the grpc server, the in-flight calls are drained until the ctx is done
+func (g *GrpcServer) Shutdown(ctx context.Context) error {
+	childLogger.Info().Str("func","Shutdown").Send()
+
+	if g.server == nil {
+		return nil
+	}
+	close(g.stop)
+	g.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		childLogger.Error().Msg("warning dirty grpc shutdown !!!")
+		g.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/adapter/api"
+	"github.com/go-onboarding/internal/infra/health"
+	"github.com/go-onboarding/internal/infra/openapi"
+)
+
+// the contract must cover every route of the router
+func TestRouterDocumented(t *testing.T) {
+	validator, err := openapi.NewValidator(false, false)
+	if err != nil {
+		t.Fatalf("openapi: %v", err)
+	}
+
+	h := NewHttpAppServer(&model.Server{})
+	h.SetOpenAPI(validator)
+	httpRouters := api.NewHttpRouters(nil, 5, health.NewHealth())
+
+	if missing := validator.Undocumented(h.Router(&httpRouters, &model.AppServer{})); len(missing) > 0 {
+		t.Errorf("routes not documented in the openapi document: %s", strings.Join(missing, ", "))
+	}
+}
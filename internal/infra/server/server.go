@@ -1,6 +1,8 @@
 package server
 
 import (
+	"fmt"
+	"sync"
 	"time"
 	"encoding/json"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"context"
 	"encoding/pem"
 	"crypto/tls"
+	"errors"
 
 	"github.com/go-onboarding/internal/core/model"
 	go_core_observ "github.com/eliezerraj/go-core/observability"  
@@ -21,11 +24,16 @@ import (
 
 	"github.com/eliezerraj/go-core/middleware"
 	"github.com/go-onboarding/internal/core/erro"
+	"github.com/go-onboarding/internal/infra/loadshed"
+	"github.com/go-onboarding/internal/infra/logging"
+	"github.com/go-onboarding/internal/infra/metrics"
+	"github.com/go-onboarding/internal/infra/openapi"
+	"github.com/go-onboarding/internal/infra/ratelimit"
+	"github.com/go-onboarding/internal/infra/tracing"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
-	//"go.opentelemetry.io/contrib/propagators/aws/xray"
-	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
@@ -36,8 +44,36 @@ var tracerProvider go_core_observ.TracerProvider
 var infoTrace go_core_observ.InfoTrace
 var tracer	trace.Tracer
 
+// bounds of the shutdown steps after the drain, each one has its own: a drain using all
+// the shutdown timeout must not leave the resources unreleased and the spans unflushed
+const (
+	stopTimeout		= 5 * time.Second	// the background loops
+	closeTimeout	= 5 * time.Second	// each closer
+	flushTimeout	= 5 * time.Second	// the tracer provider
+)
+
 type HttpServer struct {
 	httpServer	*model.Server
+	drainers	[]closer
+	closers		[]closer
+	workers		[]worker
+	rateLimiter	*ratelimit.Limiter
+	loadShedder	*loadshed.Limiter
+	openAPI		*openapi.Validator
+	adminTokens	map[string]string	// bearer token -> admin id
+}
+
+// A server drained or a resource released in the shutdown
+type closer struct {
+	name	string
+	close	func(ctx context.Context) error
+}
+
+// A background loop of the service, stopped in the shutdown before the resources are released
+type worker struct {
+	name	string
+	cancel	context.CancelFunc
+	done	chan struct{}
 }
 
 // about create a httpserver
@@ -46,9 +82,47 @@ func NewHttpAppServer(httpServer *model.Server) HttpServer {
 	return HttpServer{httpServer: httpServer }
 }
 
-//about set the server tls
-func setTLSOn(certPEM []byte, certPrivKeyPEM []byte) (*tls.Config, error){
-	childLogger.Info().Str("func","setTLSOn").Send()
+// About set the rate limiter of the person and upload routes, nil disables it
+func (h *HttpServer) SetRateLimiter(rateLimiter *ratelimit.Limiter) {
+	h.rateLimiter = rateLimiter
+}
+
+// About set the load shedder of the person and upload routes, nil disables it
+func (h *HttpServer) SetLoadShedder(loadShedder *loadshed.Limiter) {
+	h.loadShedder = loadShedder
+}
+
+// About set the openapi document served and validated, nil disables both
+func (h *HttpServer) SetOpenAPI(openAPI *openapi.Validator) {
+	h.openAPI = openAPI
+}
+
+// About register a server or a pool drained in the shutdown (e.g. grpc, jobs), concurrently
+// with the http server and within the same shutdown timeout
+func (h *HttpServer) AddDrainer(name string, drain func(ctx context.Context) error) {
+	h.drainers = append(h.drainers, closer{name: name, close: drain})
+}
+
+// About register a resource to be released in the shutdown, in the order they were added
+func (h *HttpServer) AddCloser(name string, close func(ctx context.Context) error) {
+	h.closers = append(h.closers, closer{name: name, close: close})
+}
+
+// About run a background loop until the shutdown: its context is canceled once the
+// http server is drained and it is waited before the closers run
+func (h *HttpServer) Go(ctx context.Context, name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	h.workers = append(h.workers, worker{name: name, cancel: cancel, done: done})
+}
+
+//about set the server tls, also used by the admin cli to verify the cert files
+func SetTLSOn(certPEM []byte, certPrivKeyPEM []byte) (*tls.Config, error){
+	childLogger.Info().Str("func","SetTLSOn").Send()
 
 	block, _ := pem.Decode(certPrivKeyPEM)
 	if block == nil {
@@ -66,7 +140,7 @@ func setTLSOn(certPEM []byte, certPrivKeyPEM []byte) (*tls.Config, error){
 	serverCert, err := tls.X509KeyPair(certPEM, certPrivKeyPEM)
 	if err != nil {
 		childLogger.Error().Err(err).Msg("error X509KeyPair !")
-		panic(err)
+		return nil, fmt.Errorf("%w: %w", erro.ErrCertTls, err)
 	}
 
 	serverTLSConf := &tls.Config{
@@ -79,42 +153,29 @@ func setTLSOn(certPEM []byte, certPrivKeyPEM []byte) (*tls.Config, error){
 }
 
 
-// About start http server
-func (h HttpServer) StartHttpAppServer(	ctx context.Context, 
-										httpRouters *api.HttpRouters,
-										appServer *model.AppServer) {
-	childLogger.Info().Str("func","StartHttpAppServer").Send()
-			
-	// ---------------------- OTEL ---------------
-	infoTrace.PodName = appServer.InfoPod.PodName
-	infoTrace.PodVersion = appServer.InfoPod.ApiVersion
-	infoTrace.ServiceType = "k8-workload"
-	infoTrace.Env = appServer.InfoPod.Env
-	infoTrace.AccountID = appServer.InfoPod.AccountID
-
-	tp := tracerProvider.NewTracerProvider(	ctx, 
-											appServer.ConfigOTEL, 
-											&infoTrace)
-
-	if tp != nil {
-		//otel.SetTextMapPropagator(xray.Propagator{})
-		otel.SetTextMapPropagator(propagation.TraceContext{}) 		
-		otel.SetTracerProvider(tp)
-		tracer = tp.Tracer(appServer.InfoPod.PodName)
+// About skip the traces of the probes and scrapes
+func notProbe(req *http.Request) bool {
+	switch req.URL.Path {
+	case "/health", "/live", "/ready", "/startup", "/metrics":
+		return false
 	}
+	return true
+}
+
+// About the router of all the routes with their middlewares, every route must be
+// in the openapi document (checked by the tests)
+func (h HttpServer) Router(	httpRouters *api.HttpRouters,
+							appServer *model.AppServer) *mux.Router {
+	childLogger.Info().Str("func","Router").Send()
 
-	defer func() { 
-		if tp != nil {
-			err := tp.Shutdown(ctx)
-			if err != nil{
-				childLogger.Error().Err(err).Send()
-			}
-		}
-		childLogger.Info().Msg("stop done !!!")
-	}()
-	
 	myRouter := mux.NewRouter().StrictSlash(true)
+	myRouter.Use(otelmux.Middleware("go-onboarding", otelmux.WithFilter(notProbe)))
 	myRouter.Use(core_middleware.MiddleWareHandlerHeader)
+	myRouter.Use(tracing.MiddleWareTraceRequestID)
+	myRouter.Use(logging.MiddleWareAccessLog)
+	myRouter.Use(h.adminIdentity)
+	myRouter.Use(metrics.MiddleWareMetrics)
+	myRouter.Use(h.openAPI.Middleware)
 
 	myRouter.HandleFunc("/", func(rw http.ResponseWriter, req *http.Request) {
 		childLogger.Debug().Msg("/")
@@ -127,6 +188,12 @@ func (h HttpServer) StartHttpAppServer(	ctx context.Context,
 	live := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
     live.HandleFunc("/live", httpRouters.Live)
 
+	ready := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+    ready.HandleFunc("/ready", httpRouters.Ready)
+
+	startup := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+    startup.HandleFunc("/startup", httpRouters.Startup)
+
 	header := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
     header.HandleFunc("/header", httpRouters.Header)
 
@@ -135,6 +202,26 @@ func (h HttpServer) StartHttpAppServer(	ctx context.Context,
 
 	stat := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
     stat.HandleFunc("/stat", httpRouters.Stat)
+
+	logConfig := myRouter.Methods(http.MethodGet, http.MethodPut).Subrouter()
+	logConfig.HandleFunc("/admin/log", core_middleware.MiddleWareErrorHandler(httpRouters.LogConfig))
+	logConfig.Use(h.adminAuth)
+
+	encryptionKeys := myRouter.Methods(http.MethodGet).Subrouter()
+	encryptionKeys.HandleFunc("/admin/encryption", core_middleware.MiddleWareErrorHandler(httpRouters.EncryptionKeys))
+	encryptionKeys.Use(h.adminAuth)
+	encryptionKeys = myRouter.Methods(http.MethodPost).Subrouter()
+	encryptionKeys.HandleFunc("/admin/encryption/{action}", core_middleware.MiddleWareErrorHandler(httpRouters.EncryptionKeys))
+	encryptionKeys.Use(h.adminAuth)
+
+	metric := myRouter.Methods(http.MethodGet).Subrouter()
+    metric.Handle("/metrics", metrics.Handler())
+
+	if h.openAPI != nil {
+		docs := myRouter.Methods(http.MethodGet).Subrouter()
+		docs.HandleFunc("/openapi.json", h.openAPI.ServeSpec)
+		docs.HandleFunc("/swagger", h.openAPI.ServeSwaggerUI)
+	}
 	
 	myRouter.HandleFunc("/info", func(rw http.ResponseWriter, req *http.Request) {
 		childLogger.Info().Str("HandleFunc","/info").Send()
@@ -145,29 +232,170 @@ func (h HttpServer) StartHttpAppServer(	ctx context.Context,
 	
 	addPerson := myRouter.Methods(http.MethodPost, http.MethodOptions).Subrouter()
 	addPerson.HandleFunc("/person/add", core_middleware.MiddleWareErrorHandler(httpRouters.AddPerson))		
-	addPerson.Use(otelmux.Middleware("go-onboarding"))
+	addPerson.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	addPerson.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	// before /person/{id}, it would match lookup, search and duplicates as a id
+	lookupPerson := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	lookupPerson.HandleFunc("/person/lookup", core_middleware.MiddleWareErrorHandler(httpRouters.LookupPerson))
+	lookupPerson.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	lookupPerson.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	searchPerson := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	searchPerson.HandleFunc("/person/search", core_middleware.MiddleWareErrorHandler(httpRouters.SearchPerson))
+	searchPerson.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	searchPerson.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	listDuplicate := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	listDuplicate.HandleFunc("/person/duplicates", core_middleware.MiddleWareErrorHandler(httpRouters.ListDuplicate))
+	listDuplicate.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	listDuplicate.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	dismissDuplicate := myRouter.Methods(http.MethodPost, http.MethodOptions).Subrouter()
+	dismissDuplicate.HandleFunc("/person/duplicates/{id}/dismiss", core_middleware.MiddleWareErrorHandler(httpRouters.DismissDuplicate))
+	dismissDuplicate.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	dismissDuplicate.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	mergePerson := myRouter.Methods(http.MethodPost, http.MethodOptions).Subrouter()
+	mergePerson.HandleFunc("/person/merge", core_middleware.MiddleWareErrorHandler(httpRouters.MergePerson))
+	mergePerson.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	mergePerson.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
 
 	getPerson := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
 	getPerson.HandleFunc("/person/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.GetPerson))		
-	getPerson.Use(otelmux.Middleware("go-onboarding"))
+	getPerson.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	getPerson.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
 
 	updatePerson := myRouter.Methods(http.MethodPost, http.MethodOptions).Subrouter()
 	updatePerson.HandleFunc("/person/update", core_middleware.MiddleWareErrorHandler(httpRouters.UpdatePerson))		
-	updatePerson.Use(otelmux.Middleware("go-onboarding"))
+	updatePerson.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	updatePerson.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
 
 	listPerson := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
 	listPerson.HandleFunc("/person/list/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.ListPerson))		
-	listPerson.Use(otelmux.Middleware("go-onboarding"))
+	listPerson.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	listPerson.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	listAddress := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	listAddress.HandleFunc("/person/{id}/address", core_middleware.MiddleWareErrorHandler(httpRouters.ListAddress))
+	listAddress.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	listAddress.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	saveAddress := myRouter.Methods(http.MethodPost, http.MethodPut, http.MethodOptions).Subrouter()
+	saveAddress.HandleFunc("/person/{id}/address", core_middleware.MiddleWareErrorHandler(httpRouters.SaveAddress)).Methods(http.MethodPost, http.MethodOptions)
+	saveAddress.HandleFunc("/person/{id}/address/{address_id}", core_middleware.MiddleWareErrorHandler(httpRouters.SaveAddress)).Methods(http.MethodPut, http.MethodOptions)
+	saveAddress.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	saveAddress.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	deleteAddress := myRouter.Methods(http.MethodDelete, http.MethodOptions).Subrouter()
+	deleteAddress.HandleFunc("/person/{id}/address/{address_id}", core_middleware.MiddleWareErrorHandler(httpRouters.DeleteAddress))
+	deleteAddress.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	deleteAddress.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	listDocument := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	listDocument.HandleFunc("/person/{id}/document", core_middleware.MiddleWareErrorHandler(httpRouters.ListDocument))
+	listDocument.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	listDocument.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	saveDocument := myRouter.Methods(http.MethodPost, http.MethodPut, http.MethodOptions).Subrouter()
+	saveDocument.HandleFunc("/person/{id}/document", core_middleware.MiddleWareErrorHandler(httpRouters.SaveDocument)).Methods(http.MethodPost, http.MethodOptions)
+	saveDocument.HandleFunc("/person/{id}/document/{document_id}", core_middleware.MiddleWareErrorHandler(httpRouters.SaveDocument)).Methods(http.MethodPut, http.MethodOptions)
+	saveDocument.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	saveDocument.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	deleteDocument := myRouter.Methods(http.MethodDelete, http.MethodOptions).Subrouter()
+	deleteDocument.HandleFunc("/person/{id}/document/{document_id}", core_middleware.MiddleWareErrorHandler(httpRouters.DeleteDocument))
+	deleteDocument.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	deleteDocument.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	exportPerson := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	exportPerson.HandleFunc("/person/{id}/export", core_middleware.MiddleWareErrorHandler(httpRouters.ExportPerson))
+	exportPerson.Use(h.rateLimiter.Middleware(ratelimit.ClassUpload))
+	exportPerson.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	erasePerson := myRouter.Methods(http.MethodDelete, http.MethodOptions).Subrouter()
+	erasePerson.HandleFunc("/person/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.ErasePerson))
+	erasePerson.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	erasePerson.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
 
 	uploadFile := myRouter.Methods(http.MethodPost, http.MethodOptions).Subrouter()
 	uploadFile.HandleFunc("/uploadFile", core_middleware.MiddleWareErrorHandler(httpRouters.UploadFile))		
-	uploadFile.Use(otelmux.Middleware("go-onboarding"))
+	uploadFile.Use(h.rateLimiter.Middleware(ratelimit.ClassUpload))
+	uploadFile.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	listWebhook := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	listWebhook.HandleFunc("/webhook", core_middleware.MiddleWareErrorHandler(httpRouters.ListWebhook))
+	listWebhook.HandleFunc("/webhook/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.GetWebhook))
+	listWebhook.HandleFunc("/webhook/{id}/delivery", core_middleware.MiddleWareErrorHandler(httpRouters.ListWebhookDelivery))
+	listWebhook.HandleFunc("/webhook/{id}/delivery/{delivery_id}", core_middleware.MiddleWareErrorHandler(httpRouters.GetWebhookDelivery))
+	listWebhook.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	listWebhook.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	saveWebhook := myRouter.Methods(http.MethodPost, http.MethodPut, http.MethodOptions).Subrouter()
+	saveWebhook.HandleFunc("/webhook", core_middleware.MiddleWareErrorHandler(httpRouters.SaveWebhook)).Methods(http.MethodPost, http.MethodOptions)
+	saveWebhook.HandleFunc("/webhook/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.SaveWebhook)).Methods(http.MethodPut, http.MethodOptions)
+	saveWebhook.HandleFunc("/webhook/{id}/delivery/{delivery_id}/retry", core_middleware.MiddleWareErrorHandler(httpRouters.RetryWebhookDelivery)).Methods(http.MethodPost, http.MethodOptions)
+	saveWebhook.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	saveWebhook.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	deleteWebhook := myRouter.Methods(http.MethodDelete, http.MethodOptions).Subrouter()
+	deleteWebhook.HandleFunc("/webhook/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.DeleteWebhook))
+	deleteWebhook.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	deleteWebhook.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	listJob := myRouter.Methods(http.MethodGet, http.MethodOptions).Subrouter()
+	listJob.HandleFunc("/job", core_middleware.MiddleWareErrorHandler(httpRouters.ListJob))
+	listJob.HandleFunc("/job/{id}", core_middleware.MiddleWareErrorHandler(httpRouters.GetJob))
+	listJob.Use(h.adminAuth)
+	listJob.Use(h.rateLimiter.Middleware(ratelimit.ClassRead))
+	listJob.Use(h.loadShedder.Middleware(loadshed.PriorityRead))
+
+	saveJob := myRouter.Methods(http.MethodPost, http.MethodOptions).Subrouter()
+	saveJob.HandleFunc("/job", core_middleware.MiddleWareErrorHandler(httpRouters.EnqueueJob))
+	saveJob.HandleFunc("/job/{id}/cancel", core_middleware.MiddleWareErrorHandler(httpRouters.CancelJob))
+	saveJob.Use(h.adminAuth)
+	saveJob.Use(h.rateLimiter.Middleware(ratelimit.ClassWrite))
+	saveJob.Use(h.loadShedder.Middleware(loadshed.PriorityWrite))
+
+	return myRouter
+
+}
+
+// About start http server, it blocks until a termination signal and returns
+// an error when the shutdown was not clean
+func (h HttpServer) StartHttpAppServer(	ctx context.Context, 
+										httpRouters *api.HttpRouters,
+										appServer *model.AppServer) error {
+	childLogger.Info().Str("func","StartHttpAppServer").Send()
+			
+	// ---------------------- OTEL ---------------
+	infoTrace.PodName = appServer.InfoPod.PodName
+	infoTrace.PodVersion = appServer.InfoPod.ApiVersion
+	infoTrace.ServiceType = "k8-workload"
+	infoTrace.Env = appServer.InfoPod.Env
+	infoTrace.AccountID = appServer.InfoPod.AccountID
+
+	tp := tracerProvider.NewTracerProvider(	ctx, 
+											appServer.ConfigOTEL, 
+											&infoTrace)
+
+	propagator, err := tracing.NewPropagator(appServer.TraceConfig.Propagators)
+	if err != nil {
+		return err
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	if tp != nil {
+		otel.SetTracerProvider(tp)
+		tracer = tp.Tracer(appServer.InfoPod.PodName)
+	}
+
+	myRouter := h.Router(httpRouters, appServer)
 
 	// set TLS on
 	var serverTLSConf *tls.Config
-	var err error
 	if appServer.Cert.IsTLS {
-		serverTLSConf, err = setTLSOn(appServer.Cert.CertPEM, appServer.Cert.CertPrivKeyPEM)
+		serverTLSConf, err = SetTLSOn(appServer.Cert.CertPEM, appServer.Cert.CertPrivKeyPEM)
 		if err != nil {
 			childLogger.Error().Err(err).Msg("Error set server with TLS")
 		} 
@@ -184,8 +412,9 @@ func (h HttpServer) StartHttpAppServer(	ctx context.Context,
 
 	childLogger.Info().Str("Service Port", strconv.Itoa(h.httpServer.Port)).Send()
 
+	serverErr := make(chan error, 1)
 	go func() {
-
+		var err error
 		// spinup a server TLS on / off
 		if appServer.Cert.IsTLS {
 			err = srv.ListenAndServeTLS("","")
@@ -193,31 +422,121 @@ func (h HttpServer) StartHttpAppServer(	ctx context.Context,
 			err = srv.ListenAndServe()
 		} 
 
-		if err != nil {
+		if err != nil && err != http.ErrServerClosed {
 			childLogger.Error().Err(err).Msg("canceling http mux server !!!")
+			serverErr <- err
 		}
 	}()
+	httpRouters.SetReady(true)
 
 	// Get SIGNALS
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(ch)
 
+	var errShutdown error
+	wait:
 	for {
-		sig := <-ch
-
-		switch sig {
-		case syscall.SIGHUP:
-			childLogger.Info().Msg("Received SIGHUP: reloading configuration...")
-		case syscall.SIGINT, syscall.SIGTERM:
-			childLogger.Info().Msg("Received SIGINT/SIGTERM termination signal. Exiting")
-			return
-		default:
-			childLogger.Info().Interface("Received signal:", sig).Send()
+		select {
+		case err := <-serverErr:
+			errShutdown = err
+			break wait
+		case sig := <-ch:
+			switch sig {
+			case syscall.SIGHUP:
+				childLogger.Info().Msg("Received SIGHUP: reloading configuration...")
+			case syscall.SIGINT, syscall.SIGTERM:
+				childLogger.Info().Msg("Received SIGINT/SIGTERM termination signal. Exiting")
+				break wait
+			default:
+				childLogger.Info().Interface("Received signal:", sig).Send()
+			}
 		}
 	}
 
-	if err := srv.Shutdown(ctx); err != nil && err != http.ErrServerClosed {
+	return errors.Join(errShutdown, h.shutdown(ctx, &srv, httpRouters, tp))
+}
+
+// About the shutdown sequence
+// 1) readiness fails so the pod leaves the service endpoints
+// 2) wait the pre-stop delay, the endpoints take a while to be updated
+// 3) drain the in-flight requests, the http server and the drainers together, until the shutdown timeout
+// 4) stop the background loops
+// 5) release the resources (database pool...)
+// 6) flush the tracer provider
+// The steps after the drain have their own timeouts.
+func (h HttpServer) shutdown(	ctx context.Context,
+								srv *http.Server,
+								httpRouters *api.HttpRouters,
+								tp *sdktrace.TracerProvider) error {
+	childLogger.Info().Str("func","shutdown").Send()
+
+	var errs []error
+
+	httpRouters.SetReady(false)
+	time.Sleep(time.Duration(h.httpServer.PreStopDelay) * time.Second)
+
+	ctxDrain, cancel := context.WithTimeout(ctx, time.Duration(h.httpServer.ShutdownTimeout) * time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, d := range h.drainers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			childLogger.Info().Str("drainer", d.name).Msg("draining")
+			if err := d.close(ctxDrain); err != nil {
+				childLogger.Error().Err(err).Str("drainer", d.name).Send()
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	if err := srv.Shutdown(ctxDrain); err != nil {
 		childLogger.Error().Err(err).Msg("warning dirty shutdown !!!")
-		return
+		srv.Close()
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	wg.Wait()
+
+	ctxStop, cancelStop := context.WithTimeout(ctx, stopTimeout)
+	defer cancelStop()
+	for _, w := range h.workers {
+		w.cancel()
 	}
-}
\ No newline at end of file
+	for _, w := range h.workers {
+		select {
+		case <-w.done:
+		case <-ctxStop.Done():
+			childLogger.Error().Str("worker", w.name).Msg("warning background loop not stopped !!!")
+			errs = append(errs, fmt.Errorf("background loop %s not stopped: %w", w.name, ctxStop.Err()))
+		}
+	}
+
+	for _, c := range h.closers {
+		childLogger.Info().Str("closer", c.name).Msg("closing")
+		ctxClose, cancelClose := context.WithTimeout(ctx, closeTimeout)
+		if err := c.close(ctxClose); err != nil {
+			childLogger.Error().Err(err).Str("closer", c.name).Send()
+			errs = append(errs, err)
+		}
+		cancelClose()
+	}
+
+	if tp != nil {
+		ctxFlush, cancelFlush := context.WithTimeout(ctx, flushTimeout)
+		defer cancelFlush()
+		if err := tp.Shutdown(ctxFlush); err != nil {
+			childLogger.Error().Err(err).Send()
+			errs = append(errs, err)
+		}
+	}
+
+	childLogger.Info().Msg("stop done !!!")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"io"
+	"sync"
+	"time"
+	"context"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/adapter/api"
+	"github.com/go-onboarding/internal/infra/health"
+)
+
+// the events of a shutdown, in the order they happened
+type events struct {
+	mu		sync.Mutex
+	list	[]string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) index(event string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, item := range e.list {
+		if item == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestShutdownDrainsInFlightRequest(t *testing.T) {
+	var got events
+
+	entered := make(chan struct{})
+	slow := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		close(entered)
+		time.Sleep(300 * time.Millisecond)
+		rw.Write([]byte("done"))
+		got.add("handler")
+	}))
+	slow.Start()
+	defer slow.Close()
+
+	h := NewHttpAppServer(&model.Server{ShutdownTimeout: 5})
+	h.Go(context.Background(), "loop", func(ctx context.Context) {
+		<-ctx.Done()
+		got.add("loop")
+	})
+	h.AddCloser("database", func(ctx context.Context) error {
+		got.add("closer")
+		return nil
+	})
+
+	type result struct {
+		body	string
+		err		error
+	}
+	response := make(chan result, 1)
+	go func() {
+		res, err := http.Get(slow.URL)
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		response <- result{body: string(body), err: err}
+	}()
+
+	<-entered
+	httpRouters := api.NewHttpRouters(nil, 0, health.NewHealth())
+	if err := h.shutdown(context.Background(), slow.Config, &httpRouters, nil); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	res := <-response
+	if res.err != nil || res.body != "done" {
+		t.Fatalf("in-flight request: body %q, error %v", res.body, res.err)
+	}
+	if got.index("handler") < 0 || got.index("handler") > got.index("loop") {
+		t.Errorf("background loop stopped before the request was drained: %v", got.list)
+	}
+	if got.index("loop") > got.index("closer") {
+		t.Errorf("closer run before the background loop stopped: %v", got.list)
+	}
+}
+
+func TestShutdownFailsReadinessBeforeDraining(t *testing.T) {
+	var got events
+
+	healthCheck := health.NewHealth()
+	httpRouters := api.NewHttpRouters(nil, 0, healthCheck)
+	httpRouters.SetReady(true)
+
+	h := NewHttpAppServer(&model.Server{PreStopDelay: 1, ShutdownTimeout: 5})
+	start := time.Now()
+	var drainedAfter time.Duration
+	var readiness string
+	h.AddDrainer("grpc", func(ctx context.Context) error {
+		drainedAfter = time.Since(start)
+		readiness = healthCheck.Run(ctx, health.ProbeReady).Status
+		got.add("drainer")
+		return nil
+	})
+	h.AddCloser("database", func(ctx context.Context) error {
+		got.add("closer")
+		return nil
+	})
+
+	if err := h.shutdown(context.Background(), &http.Server{}, &httpRouters, nil); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if readiness != health.StatusDown {
+		t.Errorf("readiness when the drain began: %v, want %v", readiness, health.StatusDown)
+	}
+	if drainedAfter < time.Second {
+		t.Errorf("drain began after %v, before the pre-stop delay of 1s", drainedAfter)
+	}
+	if got.index("drainer") < 0 || got.index("drainer") > got.index("closer") {
+		t.Errorf("closer run before the drain: %v", got.list)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"time"
+	"context"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/service"
+	"github.com/go-onboarding/internal/adapter/api"
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/database/pgtest"
+	"github.com/go-onboarding/internal/infra/health"
+	"github.com/go-onboarding/internal/infra/credential"
+
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// the credentials of the fake database, it takes any
+type staticCredentials struct{}
+
+func (staticCredentials) Credentials(ctx context.Context) (credential.Credentials, error) {
+	return credential.Credentials{User: "test", Password: "test"}, nil
+}
+
+// About a person row for the select of the person, nothing for the other statements
+func personHandler(sql string, args []*string) (pgtest.Result, error) {
+	if !strings.Contains(sql, "FROM public.person ") {
+		return pgtest.Result{}, nil
+	}
+	text := func(name string) pgtest.Column { return pgtest.Column{Name: name, OID: pgtype.TextOID} }
+	now := time.Now()
+	return pgtest.Result{
+		Columns: []pgtest.Column{	{Name: "id", OID: pgtype.Int4OID},
+									text("person_id"), text("name"), text("person_type"), text("birth_date"),
+									text("email"), text("phone"), text("nationality"), text("tax_id"),
+									{Name: "created_at", OID: pgtype.TimestamptzOID},
+									{Name: "updated_at", OID: pgtype.TimestamptzOID},
+									text("tenant_id"),
+		},
+		Rows: [][]any{{int32(1), "P-1", "Ana", "F", "1990-01-01", "", "", "", "", now, nil, "T-1"}},
+	}, nil
+}
+
+func TestRouterSpanTree(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	fake, err := pgtest.NewServer(personHandler)
+	if err != nil {
+		t.Fatalf("fake database: %v", err)
+	}
+	defer fake.Close()
+
+	host, port := fake.HostPort()
+	databasePGServer, err := database.NewDatabasePGServer(context.Background(),
+														go_core_pg.DatabaseConfig{Host: host, Port: port, DatabaseName: "onboarding", DbMax_Connection: 2},
+														staticCredentials{})
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	defer databasePGServer.CloseConnection()
+
+	workerService := service.NewWorkerService(database.NewWorkerRepository(databasePGServer), nil, &model.AwsService{})
+	httpRouters := api.NewHttpRouters(workerService, 5, health.NewHealth())
+
+	h := NewHttpAppServer(&model.Server{})
+	router := h.Router(&httpRouters, &model.AppServer{})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/person/P-1", nil)
+	req.Header.Set("X-Tenant-Id", "T-1")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /person/P-1: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	spans := recorder.Ended()
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		if _, ok := byName[span.Name()]; !ok {
+			byName[span.Name()] = span
+		}
+	}
+
+	// each span is the child of the one before it
+	chain := []string{"adapter.api.GetPerson", "service.GetPerson", "database.GetPerson", "db.select"}
+	for i, name := range chain {
+		span, ok := byName[name]
+		if !ok {
+			t.Fatalf("span %s not recorded, got %d spans", name, len(spans))
+		}
+		if i == 0 {
+			continue
+		}
+		parent := byName[chain[i-1]]
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of %s", name, chain[i-1])
+		}
+		if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("span %s is not in the trace of %s", name, chain[i-1])
+		}
+	}
+
+	// the person, address and document selects are under the repository span
+	statements := 0
+	for _, span := range spans {
+		if span.Name() != "db.select" {
+			continue
+		}
+		statements++
+		if span.Parent().SpanID() != byName["database.GetPerson"].SpanContext().SpanID() {
+			t.Errorf("statement span outside of database.GetPerson")
+		}
+		attrs := map[string]string{}
+		for _, attr := range span.Attributes() {
+			attrs[string(attr.Key)] = attr.Value.Emit()
+		}
+		if attrs["db.operation"] != "SELECT" {
+			t.Errorf("db.operation %q, want SELECT", attrs["db.operation"])
+		}
+		if !strings.HasPrefix(attrs["db.statement"], "SELECT") || attrs["db.system"] != "postgresql" || attrs["db.name"] != "onboarding" {
+			t.Errorf("statement attributes %v", attrs)
+		}
+	}
+	if statements != 3 {
+		t.Errorf("%d statement spans, want the person, address and document selects", statements)
+	}
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import(
+	"fmt"
+	"net/http"
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/contrib/propagators/b3"
+)
+
+// Propagators supported, the names follow the OTEL_PROPAGATORS spec
+var Propagators = []string{"tracecontext", "baggage", "xray", "b3", "b3multi"}
+
+// About build a composite propagator, extraction is tried in the given order
+// and injection writes all the formats
+func NewPropagator(names []string) (propagation.TextMapPropagator, error) {
+	childLogger.Info().Str("func","NewPropagator").Strs("propagators", names).Send()
+
+	propagators := []propagation.TextMapPropagator{}
+	for _, name := range names {
+		switch name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "xray":
+			propagators = append(propagators, xray.Propagator{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		default:
+			return nil, fmt.Errorf("unknown propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+// About use the incoming trace id as the trace-request-id, so the id returned
+// to the clients by the ErrorHandler matches the trace in the backend.
+// It must run after otelmux, which extracts the remote span context.
+func MiddleWareTraceRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		spanContext := trace.SpanContextFromContext(req.Context())
+		if spanContext.HasTraceID() {
+			traceID := spanContext.TraceID().String()
+			ctx := context.WithValue(req.Context(), "trace-request-id", traceID)
+			rw.Header().Set("X-Trace-Id", traceID)
+			req = req.WithContext(ctx)
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
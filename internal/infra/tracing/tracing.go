@@ -0,0 +1,44 @@
+package tracing
+
+import(
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","internal.infra.tracing").Logger()
+
+const instrumentationName = "github.com/go-onboarding"
+
+// About start a child span of the one in the context.
+// The returned context must be passed down so the layers below nest under this span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// About end a span, recording the error and setting the status
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
+
+// About the tenant and person attributes
+func PersonAttributes(tenantID string, personID string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{}
+	if tenantID != "" {
+		attrs = append(attrs, attribute.String("tenant.id", tenantID))
+	}
+	if personID != "" {
+		attrs = append(attrs, attribute.String("person.id", personID))
+	}
+	return attrs
+}
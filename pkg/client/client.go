@@ -0,0 +1,247 @@
+package client
+
+import (
+	"io"
+	"fmt"
+	"time"
+	"bytes"
+	"errors"
+	"context"
+	"strconv"
+	"net/url"
+	"net/http"
+	"encoding/json"
+	"math/rand/v2"
+
+	"github.com/rs/zerolog/log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+var childLogger = log.With().Str("component","go-onboarding").Str("package","pkg.client").Logger()
+
+// RetryPolicy of exponential backoff with full jitter, the wait before the attempt n+1
+// is random between 0 and min(MaxDelay, BaseDelay * 2^n)
+type RetryPolicy struct {
+	MaxAttempts	int
+	BaseDelay	time.Duration
+	MaxDelay	time.Duration
+}
+
+// About the wait after a failed attempt, attempt starts at 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 {
+		if exp := p.BaseDelay << (attempt - 1); exp > 0 && exp < p.MaxDelay {
+			delay = exp
+		}
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay)
+}
+
+// the error bodies are read up to it
+const maxErrorBody = 64 << 10
+
+// Config of a client, the zero values get the defaults
+type Config struct {
+	HTTPClient	*http.Client	// http.Client with a 30s timeout when nil
+	Retry		RetryPolicy		// 3 attempts from 100ms up to 2s when MaxAttempts is 0, 1 disables the retries
+	TenantID	string			// sent as X-Tenant-Id, the rate limit and the logs of the api are by it
+	ClientID	string			// sent as X-Client-Id, the actor of the audit
+	UserAgent	string
+}
+
+// Client of the onboarding http api.
+// The calls are traced as children of the span in the context, propagated with the
+// otel global propagator. The idempotent calls (GET, PUT and DELETE) are retried on a
+// network error, 429, 502, 503 and 504; the others only on 429 and 503, the api
+// refuses them before doing anything (rate limit and load shedding).
+type Client struct {
+	baseURL		*url.URL
+	httpClient	*http.Client
+	retry		RetryPolicy
+	tenantID	string
+	clientID	string
+	userAgent	string
+}
+
+// About create a client of the api at the base url, e.g. http://go-onboarding:5000
+func New(baseURL string, config Config) (*Client, error) {
+	childLogger.Info().Str("func","New").Str("base_url", baseURL).Send()
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("base url %q: http or https url expected", baseURL)
+	}
+
+	c := &Client{	baseURL: u,
+					httpClient: config.HTTPClient,
+					retry: config.Retry,
+					tenantID: config.TenantID,
+					clientID: config.ClientID,
+					userAgent: config.UserAgent,
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.retry.MaxAttempts == 0 {
+		c.retry = RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+	}
+	if c.userAgent == "" {
+		c.userAgent = "go-onboarding-client"
+	}
+	return c, nil
+}
+
+// a call of the api, the body is kept to be sent again
+type request struct {
+	method		string
+	path		string
+	query		url.Values
+	body		[]byte
+	contentType	string
+}
+
+// About a json request, the body is encoded when not nil
+func jsonRequest(method string, path string, body any) (request, error) {
+	r := request{method: method, path: path}
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return r, err
+		}
+		r.body = encoded
+		r.contentType = "application/json"
+	}
+	return r, nil
+}
+
+func (r request) idempotent() bool {
+	return r.method == http.MethodGet || r.method == http.MethodPut || r.method == http.MethodDelete
+}
+
+// About send a request and decode the json response in out (when not nil)
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	res, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s: %w", r.method, r.path, err)
+	}
+	return nil
+}
+
+// About send a request until a 2xx, a error not retryable or the attempts are over.
+// The body of the 2xx response must be closed by the caller.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := c.attempt(ctx, r)
+		if err == nil && res.StatusCode >= 200 && res.StatusCode <= 299 {
+			return res, nil
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+			err = decodeError(res)
+		}
+		if attempt >= c.retry.MaxAttempts || !r.retryable(ctx, err) {
+			return nil, err
+		}
+
+		delay := max(c.retry.Backoff(attempt), retryAfter)
+		if c.retry.MaxDelay > 0 {
+			delay = min(delay, c.retry.MaxDelay)
+		}
+		childLogger.Warn().Err(err).
+										Str("method", r.method).
+										Str("path", r.path).
+										Int("attempt", attempt).
+										Str("delay", delay.String()).
+										Msg("retryable error, trying again")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// About send a request once
+func (c *Client) attempt(ctx context.Context, r request) (*http.Response, error) {
+	u := c.baseURL.JoinPath(r.path)
+	u.RawQuery = r.query.Encode()
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.tenantID != "" {
+		req.Header.Set("X-Tenant-Id", c.tenantID)
+	}
+	if c.clientID != "" {
+		req.Header.Set("X-Client-Id", c.clientID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return c.httpClient.Do(req)
+}
+
+// About tell if a failed request may be sent again
+func (r request) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		// no response, it may or not have been done
+		return r.idempotent()
+	}
+	switch apiError.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return r.idempotent()
+	}
+	return false
+}
+
+// About the wait asked by the api, in seconds or as a http date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
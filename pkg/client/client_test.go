@@ -0,0 +1,496 @@
+package client
+
+import (
+	"io"
+	"time"
+	"bytes"
+	"errors"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-onboarding/internal/core/model"
+	"github.com/go-onboarding/internal/core/dedup"
+	"github.com/go-onboarding/internal/core/service"
+	"github.com/go-onboarding/internal/adapter/api"
+	"github.com/go-onboarding/internal/adapter/bucket"
+	"github.com/go-onboarding/internal/adapter/database"
+	"github.com/go-onboarding/internal/adapter/database/pgtest"
+	"github.com/go-onboarding/internal/infra/server"
+	"github.com/go-onboarding/internal/infra/health"
+	"github.com/go-onboarding/internal/infra/credential"
+
+	go_core_pg "github.com/eliezerraj/go-core/database/pg"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// the credentials of the fake database, it takes any
+type staticCredentials struct{}
+
+func (staticCredentials) Credentials(ctx context.Context) (credential.Credentials, error) {
+	return credential.Credentials{User: "test", Password: "test"}, nil
+}
+
+// the persons of the fake database, all of the tenant T-1 with the id of their position
+var testPersons = []Person{	{PersonID: "P-1", Name: "Ana Silva", TaxID: "52998224725"},
+							{PersonID: "P-2", Name: "Ana Souza"},
+							{PersonID: "P-3", Name: "Ana Santos"},
+							{PersonID: "P-4", Name: "Ana Sales"},
+							{PersonID: "P-5", Name: "Ana Serra"},
+}
+
+func text(name string) pgtest.Column { return pgtest.Column{Name: name, OID: pgtype.TextOID} }
+
+func integer(name string) pgtest.Column { return pgtest.Column{Name: name, OID: pgtype.Int4OID} }
+
+func timestamp(name string) pgtest.Column { return pgtest.Column{Name: name, OID: pgtype.TimestamptzOID} }
+
+var personColumns = []pgtest.Column{	integer("id"),
+										text("person_id"), text("name"), text("person_type"), text("birth_date"),
+										text("email"), text("phone"), text("nationality"), text("tax_id"),
+										timestamp("created_at"), timestamp("updated_at"), text("tenant_id"),
+}
+
+// About the row of a person, its id is its position in the fixture
+func personRow(i int) []any {
+	person := testPersons[i]
+	return []any{int32(i + 1), person.PersonID, person.Name, "natural", "", "", "", "", person.TaxID, time.Now(), nil, "T-1"}
+}
+
+// About the position of a person in the fixture, -1 when it is not there
+func personIndex(personID *string) int {
+	for i, person := range testPersons {
+		if personID != nil && person.PersonID == *personID {
+			return i
+		}
+	}
+	return -1
+}
+
+// About answer the statements of the routes, as a database holding the persons of the
+// fixture with a address and a document each. The writes affect a row and return the id 10.
+func onboardingHandler(sql string, args []*string) (pgtest.Result, error) {
+	sql = strings.Join(strings.Fields(sql), " ")
+	// the statement is described before it is run, with nil args
+	arg := func(i int) *string {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(sql, "WITH q AS"):
+		// the search, a page of the persons of the fixture
+		result := pgtest.Result{Columns: append(append([]pgtest.Column{}, personColumns...), pgtest.Column{Name: "score", OID: pgtype.Float8OID})}
+		if args == nil {
+			return result, nil
+		}
+		limit, _ := strconv.Atoi(*arg(2))
+		offset, _ := strconv.Atoi(*arg(3))
+		for i := offset; i < min(offset + limit, len(testPersons)); i++ {
+			result.Rows = append(result.Rows, append(personRow(i), float64(1) - float64(i) / 10))
+		}
+		return result, nil
+	case strings.HasPrefix(sql, "SELECT p.person_id"):
+		// the duplicate candidates, by the tax id
+		result := pgtest.Result{Columns: []pgtest.Column{text("person_id")}}
+		if taxID := arg(3); taxID != nil && *taxID == testPersons[0].TaxID {
+			result.Rows = [][]any{{testPersons[0].PersonID}}
+		}
+		return result, nil
+	case strings.HasPrefix(sql, "SELECT id, COALESCE(tenant_id, '') FROM public.person "):
+		result := pgtest.Result{Columns: []pgtest.Column{integer("id"), text("tenant_id")}}
+		if i := personIndex(arg(0)); i >= 0 {
+			result.Rows = [][]any{{int32(i + 1), "T-1"}}
+		}
+		return result, nil
+	case strings.HasPrefix(sql, "SELECT id FROM public.person "):
+		result := pgtest.Result{Columns: []pgtest.Column{integer("id")}}
+		if i := personIndex(arg(0)); i >= 0 {
+			result.Rows = [][]any{{int32(i + 1)}}
+		}
+		return result, nil
+	case strings.Contains(sql, "FROM public.person WHERE person_id =$1"):
+		result := pgtest.Result{Columns: personColumns}
+		if i := personIndex(arg(0)); i >= 0 {
+			result.Rows = [][]any{personRow(i)}
+		}
+		return result, nil
+	case strings.Contains(sql, "FROM public.person WHERE person_id >= $1"):
+		result := pgtest.Result{Columns: personColumns}
+		for i, person := range testPersons {
+			if args != nil && person.PersonID >= *arg(0) {
+				result.Rows = append(result.Rows, personRow(i))
+			}
+		}
+		return result, nil
+	case strings.Contains(sql, "FROM public.person WHERE COALESCE(tenant_id, '') = $1"):
+		// the lookup, by the tax id or the email in clear
+		result := pgtest.Result{Columns: personColumns}
+		if value := arg(2); value != nil && *value == testPersons[0].TaxID {
+			result.Rows = [][]any{personRow(0)}
+		}
+		return result, nil
+	case strings.HasPrefix(sql, "SELECT id, type, street"):
+		return pgtest.Result{
+			Columns: []pgtest.Column{	integer("id"), text("type"), text("street"), text("number"), text("complement"),
+										text("district"), text("city"), text("state"), text("postal_code"), text("country"),
+										{Name: "is_primary", OID: pgtype.BoolOID}, timestamp("created_at"), timestamp("updated_at"),
+			},
+			Rows: [][]any{{int32(1), "home", "Rua A", "10", "", "", "Sao Paulo", "SP", "01000-000", "BR", true, time.Now(), nil}},
+		}, nil
+	case strings.HasPrefix(sql, "SELECT id, type, number"):
+		return pgtest.Result{
+			Columns: []pgtest.Column{	integer("id"), text("type"), text("number"), text("issuing_country"), text("issuing_authority"),
+										text("issue_date"), text("expiry_date"), timestamp("created_at"), timestamp("updated_at"),
+			},
+			Rows: [][]any{{int32(1), "passport", "X123", "BR", "", "", "", time.Now(), nil}},
+		}, nil
+	case strings.HasPrefix(sql, "SELECT id, person_id, candidate_id"):
+		return pgtest.Result{
+			Columns: []pgtest.Column{	{Name: "id", OID: pgtype.Int8OID}, text("person_id"), text("candidate_id"), text("tenant_id"),
+										{Name: "score", OID: pgtype.Float8OID}, {Name: "reasons", OID: pgtype.TextArrayOID},
+										text("status"), timestamp("created_at"), timestamp("resolved_at"),
+			},
+			Rows: [][]any{{int64(4), "P-1", "P-2", "T-1", 0.8, []string{"name"}, "pending", time.Now(), nil}},
+		}, nil
+	case strings.HasPrefix(sql, "UPDATE public.person_duplicate SET status = $2, resolved_at = $3 WHERE id = $1"):
+		return pgtest.Result{
+			Columns: []pgtest.Column{	text("person_id"), text("candidate_id"), text("tenant_id"),
+										{Name: "score", OID: pgtype.Float8OID}, {Name: "reasons", OID: pgtype.TextArrayOID},
+										text("status"), timestamp("created_at"), timestamp("resolved_at"),
+			},
+			Rows: [][]any{{"P-1", "P-2", "T-1", 0.8, []string{"name"}, "dismissed", time.Now(), time.Now()}},
+		}, nil
+	case strings.HasPrefix(sql, "SELECT person_id, COALESCE(tenant_id, ''), COALESCE(actor, '')"):
+		result := pgtest.Result{
+			Columns: []pgtest.Column{	text("person_id"), text("tenant_id"), text("actor"), text("trace_id"),
+										timestamp("erased_at"), integer("objects_deleted"), timestamp("objects_deleted_at"),
+			},
+		}
+		if personID := arg(0); personID != nil {
+			result.Rows = [][]any{{*personID, "T-1", "", "", time.Now(), int32(0), nil}}
+		}
+		return result, nil
+	case strings.HasPrefix(sql, "SELECT merged_person_id"):
+		// no merge yet
+		return pgtest.Result{
+			Columns: []pgtest.Column{	text("merged_person_id"), text("survivor_person_id"), text("tenant_id"), text("actor"),
+										text("trace_id"), timestamp("merged_at"), integer("objects_moved"), timestamp("objects_moved_at"),
+			},
+		}, nil
+	case strings.Contains(sql, "FROM public.person_audit"):
+		return pgtest.Result{
+			Columns: []pgtest.Column{	{Name: "id", OID: pgtype.Int8OID}, text("person_id"), text("tenant_id"), text("action"),
+										text("actor"), text("trace_id"), timestamp("created_at"),
+			},
+		}, nil
+	case strings.HasPrefix(sql, "UPDATE public.person SET name = ''"):
+		return pgtest.Result{Columns: []pgtest.Column{text("tenant_id")}, Rows: [][]any{{"T-1"}}}, nil
+	case strings.Contains(sql, "RETURNING objects_"):
+		return pgtest.Result{Columns: []pgtest.Column{integer("objects")}, Rows: [][]any{{int32(0)}}}, nil
+	case strings.Contains(sql, "RETURNING id"):
+		return pgtest.Result{Columns: []pgtest.Column{integer("id")}, Rows: [][]any{{int32(10)}}}, nil
+	case strings.HasPrefix(strings.ToUpper(sql), "UPDATE"):
+		return pgtest.Result{Tag: "UPDATE 1"}, nil
+	case strings.HasPrefix(sql, "DELETE"):
+		return pgtest.Result{Tag: "DELETE 1"}, nil
+	case strings.HasPrefix(sql, "INSERT"):
+		return pgtest.Result{Tag: "INSERT 0 1"}, nil
+	}
+	// the advisory lock
+	return pgtest.Result{Tag: "SELECT 1"}, nil
+}
+
+// About a bucket holding no file, the listings of the fake s3 are empty
+func newTestBucket(t *testing.T) *bucket.PersonBucket {
+	fake := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/xml")
+		if req.URL.Query().Has("versions") {
+			io.WriteString(rw, `<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><IsTruncated>false</IsTruncated></ListVersionsResult>`)
+			return
+		}
+		io.WriteString(rw, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><IsTruncated>false</IsTruncated><KeyCount>0</KeyCount></ListBucketResult>`)
+	}))
+	t.Cleanup(fake.Close)
+
+	client := s3.New(s3.Options{	Region: "us-east-1",
+									BaseEndpoint: aws.String(fake.URL),
+									UsePathStyle: true,
+									Credentials: aws.AnonymousCredentials{},
+	})
+	return bucket.NewPersonBucket(client, "onboarding", "person")
+}
+
+// About a client of the router of the api, over the fake database and bucket.
+// The router is reached through wrap when given.
+func newTestClient(t *testing.T, wrap func(http.Handler) http.Handler) *Client {
+	fake, err := pgtest.NewServer(onboardingHandler)
+	if err != nil {
+		t.Fatalf("fake database: %v", err)
+	}
+	t.Cleanup(func() { fake.Close() })
+
+	host, port := fake.HostPort()
+	databasePGServer, err := database.NewDatabasePGServer(context.Background(),
+														go_core_pg.DatabaseConfig{Host: host, Port: port, DatabaseName: "onboarding", DbMax_Connection: 4},
+														staticCredentials{})
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	t.Cleanup(databasePGServer.CloseConnection)
+
+	workerService := service.NewWorkerService(database.NewWorkerRepository(databasePGServer), nil, &model.AwsService{})
+	workerService.SetDedupPolicy(dedup.Policy{MatchThreshold: 0.9})
+	workerService.SetPersonBucket(newTestBucket(t))
+	httpRouters := api.NewHttpRouters(workerService, 5, health.NewHealth())
+
+	h := server.NewHttpAppServer(&model.Server{})
+	var handler http.Handler = h.Router(&httpRouters, &model.AppServer{})
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	apiServer := httptest.NewServer(handler)
+	t.Cleanup(apiServer.Close)
+
+	c, err := New(apiServer.URL, Config{	TenantID: "T-1",
+									ClientID: "client-test",
+									Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	return c
+}
+
+// every typed method reaches its route and decodes the answer
+func TestClientMethods(t *testing.T) {
+	c := newTestClient(t, nil)
+	ctx := context.Background()
+
+	check := func(name string, err error, ok bool) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !ok {
+			t.Errorf("%s: unexpected answer", name)
+		}
+	}
+
+	onboarding, err := c.AddPerson(ctx, &Person{PersonID: "P-9", Name: "Bruno Lima", TenantID: "T-1"})
+	check("AddPerson", err, err == nil && onboarding.Person.ID == 10)
+
+	onboarding, err = c.GetPerson(ctx, "P-1")
+	check("GetPerson", err, err == nil && onboarding.Person.Name == "Ana Silva" && len(onboarding.Person.Addresses) == 1 && len(onboarding.Person.Documents) == 1)
+
+	onboarding, err = c.UpdatePerson(ctx, &Person{PersonID: "P-2", Name: "Ana Souza Lima", TenantID: "T-1"})
+	check("UpdatePerson", err, err == nil && onboarding.Person.Name == "Ana Souza Lima")
+
+	persons, err := c.ListPerson(ctx, "P-4")
+	check("ListPerson", err, len(persons) == 2 && persons[0].Person.PersonID == "P-4")
+
+	persons, err = c.LookupPerson(ctx, "T-1", "529.982.247-25", "")
+	check("LookupPerson", err, len(persons) == 1 && persons[0].Person.PersonID == "P-1")
+
+	page, err := c.SearchPerson(ctx, &PersonSearch{Query: "ana", TenantID: "T-1", Limit: 2})
+	check("SearchPerson", err, err == nil && len(page.Items) == 2 && page.NextOffset != nil && *page.NextOffset == 2)
+
+	var archive bytes.Buffer
+	written, err := c.ExportPerson(ctx, "P-1", &archive)
+	check("ExportPerson", err, written > 0 && bytes.HasPrefix(archive.Bytes(), []byte("PK")))
+
+	tombstone, err := c.ErasePerson(ctx, "P-3")
+	check("ErasePerson", err, err == nil && tombstone.PersonID == "P-3" && tombstone.TenantID == "T-1")
+
+	duplicates, err := c.ListDuplicate(ctx, "T-1", "")
+	check("ListDuplicate", err, len(duplicates) == 1 && duplicates[0].Candidate.PersonID == "P-2")
+
+	duplicate, err := c.DismissDuplicate(ctx, 4)
+	check("DismissDuplicate", err, err == nil && duplicate.ID == 4 && duplicate.Status == "dismissed")
+
+	merge, err := c.MergePerson(ctx, &PersonMerge{SurvivorPersonID: "P-1", MergedPersonID: "P-2"})
+	check("MergePerson", err, err == nil && merge.MergedPersonID == "P-2" && merge.TenantID == "T-1")
+
+	addresses, err := c.ListAddress(ctx, "P-1")
+	check("ListAddress", err, len(addresses) == 1 && addresses[0].Street == "Rua A")
+
+	address := &Address{Type: "home", Street: "Rua B", City: "Sao Paulo", PostalCode: "01000-000", Country: "BR"}
+	address, err = c.AddAddress(ctx, "P-1", address)
+	check("AddAddress", err, err == nil && address.ID == 10)
+
+	if err == nil {
+		address.Street = "Rua C"
+		address, err = c.UpdateAddress(ctx, "P-1", address)
+		check("UpdateAddress", err, err == nil && address.Street == "Rua C")
+	}
+
+	check("DeleteAddress", c.DeleteAddress(ctx, "P-1", 10), true)
+
+	documents, err := c.ListDocument(ctx, "P-1")
+	check("ListDocument", err, len(documents) == 1 && documents[0].Number == "X123")
+
+	document := &Document{Type: "passport", Number: "Y456", IssuingCountry: "BR"}
+	document, err = c.AddDocument(ctx, "P-1", document)
+	check("AddDocument", err, err == nil && document.ID == 10)
+
+	if err == nil {
+		document.Number = "Y789"
+		document, err = c.UpdateDocument(ctx, "P-1", document)
+		check("UpdateDocument", err, err == nil && document.Number == "Y789")
+	}
+
+	check("DeleteDocument", c.DeleteDocument(ctx, "P-1", 10), true)
+
+	// the person is checked before the file is stored
+	err = c.UploadFile(ctx, "P-404", "scan.pdf", strings.NewReader("%PDF"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("UploadFile of a unknown person: %v, want %v", err, ErrNotFound)
+	}
+}
+
+// the errors of the api are told apart by errors.Is, a duplicate has its candidates
+func TestClientErrors(t *testing.T) {
+	c := newTestClient(t, nil)
+	ctx := context.Background()
+
+	_, err := c.AddPerson(ctx, &Person{PersonID: "P-9", Name: "Ana Silva", Nationality: "BR", TaxID: "52998224725", TenantID: "T-1"})
+	var apiError *APIError
+	if !errors.Is(err, ErrDuplicatePerson) || !errors.As(err, &apiError) {
+		t.Fatalf("AddPerson of a duplicate: %v, want %v", err, ErrDuplicatePerson)
+	}
+	if apiError.StatusCode != http.StatusConflict || len(apiError.Candidates) != 1 || apiError.Candidates[0].Candidate.PersonID != "P-1" {
+		t.Errorf("duplicate %d with the candidates %+v, want 409 with P-1", apiError.StatusCode, apiError.Candidates)
+	}
+
+	_, err = c.GetPerson(ctx, "P-404")
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiError) || apiError.StatusCode != http.StatusNotFound {
+		t.Errorf("GetPerson of a unknown person: %v, want %v", err, ErrNotFound)
+	}
+
+	_, err = c.AddPerson(ctx, &Person{PersonID: "P-9", TenantID: "T-1"})
+	if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "name") {
+		t.Errorf("AddPerson without a name: %v, want %v", err, ErrInvalid)
+	}
+
+	_, err = c.SearchPerson(ctx, &PersonSearch{Query: "?!", TenantID: "T-1"})
+	if !errors.Is(err, ErrBadRequest) {
+		t.Errorf("SearchPerson without words: %v, want %v", err, ErrBadRequest)
+	}
+
+	// a caller not bound to a tenant reaches none
+	unbound := *c
+	unbound.tenantID = ""
+	_, err = unbound.GetPerson(ctx, "P-1")
+	if !errors.Is(err, ErrUnauthorized) || !errors.As(err, &apiError) || apiError.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GetPerson without a tenant: %v, want %v", err, ErrUnauthorized)
+	}
+}
+
+// About a handler answering a status to the first failures requests, the router after.
+// The requests received are counted.
+func failing(status int, failures int, requests *int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			*requests++
+			if *requests <= failures {
+				http.Error(rw, http.StatusText(status), status)
+				return
+			}
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// the idempotent calls are retried on the gateway errors, all the calls on the load shedding
+func TestClientRetry(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name			string
+		status			int
+		failures		int
+		call			func(c *Client) error
+		wantRequests	int
+		wantErr			error
+	}{
+		{	name: "GET retried on 502", status: http.StatusBadGateway, failures: 1, wantRequests: 2,
+			call: func(c *Client) error { _, err := c.GetPerson(ctx, "P-1"); return err },
+		},
+		{	name: "PUT retried on 504", status: http.StatusGatewayTimeout, failures: 1, wantRequests: 2,
+			call: func(c *Client) error {
+				_, err := c.UpdateAddress(ctx, "P-1", &Address{ID: 1, Type: "home", Street: "Rua A", City: "Sao Paulo", PostalCode: "01000-000", Country: "BR"})
+				return err
+			},
+		},
+		{	name: "DELETE retried on 502", status: http.StatusBadGateway, failures: 2, wantRequests: 3,
+			call: func(c *Client) error { return c.DeleteDocument(ctx, "P-1", 1) },
+		},
+		{	name: "POST not retried on 502", status: http.StatusBadGateway, failures: 1, wantRequests: 1, wantErr: ErrServer,
+			call: func(c *Client) error { _, err := c.UpdatePerson(ctx, &Person{PersonID: "P-2", Name: "Ana Souza"}); return err },
+		},
+		{	name: "POST retried on 503", status: http.StatusServiceUnavailable, failures: 1, wantRequests: 2,
+			call: func(c *Client) error { _, err := c.UpdatePerson(ctx, &Person{PersonID: "P-2", Name: "Ana Souza"}); return err },
+		},
+		{	name: "attempts over", status: http.StatusServiceUnavailable, failures: 3, wantRequests: 3, wantErr: ErrOverloaded,
+			call: func(c *Client) error { _, err := c.GetPerson(ctx, "P-1"); return err },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			c := newTestClient(t, failing(tc.status, tc.failures, &requests))
+
+			err := tc.call(c)
+			if tc.wantErr == nil && err != nil || tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("error %v, want %v", err, tc.wantErr)
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("%d requests, want %d", requests, tc.wantRequests)
+			}
+		})
+	}
+}
+
+// the iteration fetches the pages as it goes, until the last one or the break
+func TestClientSearchPersonIter(t *testing.T) {
+	requests := 0
+	counting := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests++
+			next.ServeHTTP(rw, req)
+		})
+	}
+	c := newTestClient(t, counting)
+	search := PersonSearch{Query: "ana", TenantID: "T-1", Limit: 2}
+
+	personIDs := []string{}
+	for match, err := range c.SearchPersonIter(context.Background(), search) {
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		personIDs = append(personIDs, match.Person.PersonID)
+	}
+	if strings.Join(personIDs, ",") != "P-1,P-2,P-3,P-4,P-5" || requests != 3 {
+		t.Errorf("matches %v in %d pages, want the 5 persons in 3 pages", personIDs, requests)
+	}
+
+	requests = 0
+	personIDs = personIDs[:0]
+	for match := range c.SearchPersonIter(context.Background(), search) {
+		personIDs = append(personIDs, match.Person.PersonID)
+		if len(personIDs) == 3 {
+			break
+		}
+	}
+	if len(personIDs) != 3 || requests != 2 {
+		t.Errorf("%d matches in %d pages, want 3 in 2 pages", len(personIDs), requests)
+	}
+}
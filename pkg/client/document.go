@@ -0,0 +1,103 @@
+package client
+
+import (
+	"io"
+	"bytes"
+	"context"
+	"strconv"
+	"net/url"
+	"net/http"
+	"mime/multipart"
+
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// About list the identity documents of a person
+func (c *Client) ListDocument(ctx context.Context, personID string) (_ []Document, err error) {
+	ctx, span := tracing.Start(ctx, "client.ListDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	res := []Document{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: documentPath(personID, 0)}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// About add a identity document to a person
+func (c *Client) AddDocument(ctx context.Context, personID string, document *Document) (_ *Document, err error) {
+	ctx, span := tracing.Start(ctx, "client.AddDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPost, documentPath(personID, 0), document)
+	if err != nil {
+		return nil, err
+	}
+	res := Document{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About update a identity document of a person, found by its id
+func (c *Client) UpdateDocument(ctx context.Context, personID string, document *Document) (_ *Document, err error) {
+	ctx, span := tracing.Start(ctx, "client.UpdateDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPut, documentPath(personID, document.ID), document)
+	if err != nil {
+		return nil, err
+	}
+	res := Document{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About delete a identity document of a person
+func (c *Client) DeleteDocument(ctx context.Context, personID string, documentID int) (err error) {
+	ctx, span := tracing.Start(ctx, "client.DeleteDocument", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	return c.do(ctx, request{method: http.MethodDelete, path: documentPath(personID, documentID)}, nil)
+}
+
+// About upload a file of a person (e.g. the scan of a document), up to 20Mb.
+// It is read at once, to be sent again when the api asks to retry.
+func (c *Client) UploadFile(ctx context.Context, personID string, fileName string, file io.Reader) (err error) {
+	ctx, span := tracing.Start(ctx, "client.UploadFile", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err = form.WriteField("person_id", personID); err != nil {
+		return err
+	}
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return err
+	}
+	if err = form.Close(); err != nil {
+		return err
+	}
+
+	return c.do(ctx, request{	method: http.MethodPost,
+								path: "/uploadFile",
+								body: body.Bytes(),
+								contentType: form.FormDataContentType(),
+	}, nil)
+}
+
+// About the path of the documents of a person, of one of them when the id is given
+func documentPath(personID string, documentID int) string {
+	path := "/person/" + url.PathEscape(personID) + "/document"
+	if documentID != 0 {
+		path += "/" + strconv.Itoa(documentID)
+	}
+	return path
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"time"
+)
+
+// the bodies of the api, kept apart from the model of the service so the
+// callers outside the module do not depend on it
+
+type Onboarding struct {
+	Person 			*Person 			`json:"person"`
+	Duplicates		[]PersonDuplicate	`json:"duplicates,omitempty"`
+}
+
+type Person struct {
+	ID			int 		`json:"id,omitempty"`
+	PersonID	string		`json:"person_id,omitempty"`
+	Name 		string 		`json:"name,omitempty"`
+	PersonType	string		`json:"person_type,omitempty"`
+	BirthDate	string		`json:"birth_date,omitempty"`
+	Email		string		`json:"email,omitempty"`
+	Phone		string		`json:"phone,omitempty"`
+	Nationality	string		`json:"nationality,omitempty"`
+	TaxID		string		`json:"tax_id,omitempty"`
+	Addresses	[]Address	`json:"addresses,omitempty"`
+	Documents	[]Document	`json:"documents,omitempty"`
+	CreatedAt	time.Time 	`json:"created_at,omitempty"`
+	UpdatedAt	*time.Time 	`json:"updated_at,omitempty"`
+	TenantID	string 		`json:"tenant_id,omitempty"`
+}
+
+type Address struct {
+	ID			int 		`json:"id,omitempty"`
+	Type		string		`json:"type,omitempty"`
+	Street		string		`json:"street,omitempty"`
+	Number		string		`json:"number,omitempty"`
+	Complement	string		`json:"complement,omitempty"`
+	District	string		`json:"district,omitempty"`
+	City		string		`json:"city,omitempty"`
+	State		string		`json:"state,omitempty"`
+	PostalCode	string		`json:"postal_code,omitempty"`
+	Country		string		`json:"country,omitempty"`
+	IsPrimary	bool		`json:"is_primary"`
+	CreatedAt	time.Time 	`json:"created_at,omitempty"`
+	UpdatedAt	*time.Time 	`json:"updated_at,omitempty"`
+}
+
+type Document struct {
+	ID				int 		`json:"id,omitempty"`
+	Type			string		`json:"type,omitempty"`
+	Number			string		`json:"number,omitempty"`
+	IssuingCountry	string		`json:"issuing_country,omitempty"`
+	IssuingAuthority string		`json:"issuing_authority,omitempty"`
+	IssueDate		string		`json:"issue_date,omitempty"`
+	ExpiryDate		string		`json:"expiry_date,omitempty"`
+	CreatedAt		time.Time 	`json:"created_at,omitempty"`
+	UpdatedAt		*time.Time 	`json:"updated_at,omitempty"`
+}
+
+type Tombstone struct {
+	PersonID			string		`json:"person_id"`
+	TenantID			string		`json:"tenant_id,omitempty"`
+	Actor				string		`json:"actor,omitempty"`
+	TraceID				string		`json:"trace_id,omitempty"`
+	ErasedAt			time.Time	`json:"erased_at"`
+	ObjectsDeleted		int			`json:"objects_deleted"`
+	ObjectsDeletedAt	*time.Time	`json:"objects_deleted_at,omitempty"`
+}
+
+// Span of the name matched, in runes
+type Span struct {
+	Start	int	`json:"start"`
+	End		int	`json:"end"`
+}
+
+type PersonMatch struct {
+	Person		*Person		`json:"person"`
+	Score		float64		`json:"score"`
+	Highlight	[]Span		`json:"highlight"`
+}
+
+type PersonSearch struct {
+	Query		string			`json:"query"`
+	TenantID	string			`json:"tenant_id,omitempty"`
+	Limit		int				`json:"limit"`
+	Offset		int				`json:"offset"`
+	NextOffset	*int			`json:"next_offset,omitempty"`
+	Items		[]PersonMatch	`json:"items"`
+}
+
+type PersonDuplicate struct {
+	ID			int64		`json:"id,omitempty"`
+	PersonID	string		`json:"person_id,omitempty"`
+	TenantID	string		`json:"tenant_id,omitempty"`
+	Candidate	*Person		`json:"candidate"`
+	Score		float64		`json:"score"`
+	Reasons		[]string	`json:"reasons"`
+	Status		string		`json:"status,omitempty"`
+	CreatedAt	*time.Time	`json:"created_at,omitempty"`
+	ResolvedAt	*time.Time	`json:"resolved_at,omitempty"`
+}
+
+type PersonMerge struct {
+	SurvivorPersonID	string		`json:"survivor_person_id"`
+	MergedPersonID		string		`json:"merged_person_id"`
+	TenantID			string		`json:"tenant_id,omitempty"`
+	Actor				string		`json:"actor,omitempty"`
+	TraceID				string		`json:"trace_id,omitempty"`
+	MergedAt			time.Time	`json:"merged_at"`
+	ObjectsMoved		int			`json:"objects_moved"`
+	ObjectsMovedAt		*time.Time	`json:"objects_moved_at,omitempty"`
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"io"
+	"fmt"
+	"bytes"
+	"strings"
+	"net/http"
+	"encoding/json"
+
+	"github.com/go-onboarding/internal/core/erro"
+)
+
+// the errors of the api, a *APIError wraps one of them (errors.Is)
+var (
+	ErrNotFound			= erro.ErrNotFound
+	ErrBadRequest		= erro.ErrBadRequest
+	ErrInvalid			= erro.ErrInvalid
+	ErrUnauthorized		= erro.ErrUnauthorized
+	ErrHTTPForbiden		= erro.ErrHTTPForbiden
+	ErrTimeout			= erro.ErrTimeout
+	ErrTooManyRequests	= erro.ErrTooManyRequests
+	ErrOverloaded		= erro.ErrOverloaded
+	ErrDuplicateTaxID	= erro.ErrDuplicateTaxID
+	ErrDuplicatePerson	= erro.ErrDuplicatePerson
+	ErrEncryptionDisabled	= erro.ErrEncryptionDisabled
+	ErrWebhookDisabled	= erro.ErrWebhookDisabled
+	ErrJobDisabled		= erro.ErrJobDisabled
+	ErrJobFinished		= erro.ErrJobFinished
+	ErrServer			= erro.ErrServer
+)
+
+// the errors told by their message, the api answers with the message of the error
+var knownErrors = []error{
+	ErrNotFound,
+	ErrBadRequest,
+	ErrUnauthorized,
+	ErrHTTPForbiden,
+	ErrTimeout,
+	ErrTooManyRequests,
+	ErrOverloaded,
+	ErrDuplicateTaxID,
+	ErrDuplicatePerson,
+	ErrEncryptionDisabled,
+	ErrWebhookDisabled,
+	ErrJobDisabled,
+	ErrJobFinished,
+}
+
+// APIError is a response of the api other than 2xx
+type APIError struct {
+	StatusCode	int
+	Message		string
+	TraceID		string
+	Candidates	[]PersonDuplicate	// the persons matched, with ErrDuplicatePerson
+	err			error
+}
+
+func (e *APIError) Error() string {
+	if e.TraceID != "" {
+		return fmt.Sprintf("onboarding api %d: %s (trace_id %s)", e.StatusCode, e.Message, e.TraceID)
+	}
+	return fmt.Sprintf("onboarding api %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// About read the error of a response, the body is closed
+func decodeError(res *http.Response) *APIError {
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+
+	var payload struct {
+		Msg			string				`json:"msg"`
+		TraceID		string				`json:"request-id"`
+		Candidates	[]PersonDuplicate	`json:"candidates"`
+	}
+	apiError := &APIError{StatusCode: res.StatusCode}
+	if json.Unmarshal(body, &payload) == nil && payload.Msg != "" {
+		apiError.Message = payload.Msg
+		apiError.TraceID = payload.TraceID
+		apiError.Candidates = payload.Candidates
+	} else if message := bytes.TrimSpace(body); len(message) > 0 {
+		apiError.Message = string(message)
+	} else {
+		apiError.Message = http.StatusText(res.StatusCode)
+	}
+	if apiError.TraceID == "" {
+		apiError.TraceID = res.Header.Get("X-Trace-Id")
+	}
+	apiError.err = classify(apiError.StatusCode, apiError.Message)
+
+	return apiError
+}
+
+// About the error of the api for a status and message, by the status when the
+// message is not one of the known errors
+func classify(statusCode int, message string) error {
+	for _, err := range knownErrors {
+		if message == err.Error() {
+			return err
+		}
+	}
+	// the validation errors keep the invalid fields after the message
+	if strings.HasPrefix(message, ErrInvalid.Error()) {
+		return ErrInvalid
+	}
+
+	switch statusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrHTTPForbiden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ErrOverloaded
+	case http.StatusGatewayTimeout:
+		return ErrTimeout
+	}
+	return ErrServer
+}
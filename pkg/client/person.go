@@ -0,0 +1,269 @@
+package client
+
+import (
+	"io"
+	"iter"
+	"context"
+	"strconv"
+	"net/url"
+	"net/http"
+
+	"github.com/go-onboarding/internal/infra/tracing"
+)
+
+// About add a person, a duplicate is a *APIError with ErrDuplicatePerson and the candidates
+func (c *Client) AddPerson(ctx context.Context, person *Person) (_ *Onboarding, err error) {
+	ctx, span := tracing.Start(ctx, "client.AddPerson", tracing.PersonAttributes(person.TenantID, person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPost, "/person/add", Onboarding{Person: person})
+	if err != nil {
+		return nil, err
+	}
+	res := Onboarding{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About get a person with its addresses and documents
+func (c *Client) GetPerson(ctx context.Context, personID string) (_ *Onboarding, err error) {
+	ctx, span := tracing.Start(ctx, "client.GetPerson", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	res := Onboarding{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: "/person/" + url.PathEscape(personID)}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About update a person, found by its person_id
+func (c *Client) UpdatePerson(ctx context.Context, person *Person) (_ *Onboarding, err error) {
+	ctx, span := tracing.Start(ctx, "client.UpdatePerson", tracing.PersonAttributes(person.TenantID, person.PersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPost, "/person/update", Onboarding{Person: person})
+	if err != nil {
+		return nil, err
+	}
+	res := Onboarding{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About list the persons from a person_id, in person_id order
+func (c *Client) ListPerson(ctx context.Context, fromPersonID string) (_ []Onboarding, err error) {
+	ctx, span := tracing.Start(ctx, "client.ListPerson")
+	defer func() { tracing.End(span, err) }()
+
+	res := []Onboarding{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: "/person/list/" + url.PathEscape(fromPersonID)}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// About find the persons of a tenant by the exact tax_id or email
+func (c *Client) LookupPerson(ctx context.Context, tenantID string, taxID string, email string) (_ []Onboarding, err error) {
+	ctx, span := tracing.Start(ctx, "client.LookupPerson", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := url.Values{}
+	setQuery(query, "tenant_id", tenantID)
+	setQuery(query, "tax_id", taxID)
+	setQuery(query, "email", email)
+
+	res := []Onboarding{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: "/person/lookup", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// About search a page of the persons of a tenant by the name: the query, tenant_id,
+// limit and offset of the search. The next_offset is set when there is a next page.
+func (c *Client) SearchPerson(ctx context.Context, search *PersonSearch) (_ *PersonSearch, err error) {
+	ctx, span := tracing.Start(ctx, "client.SearchPerson", tracing.PersonAttributes(search.TenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := url.Values{}
+	setQuery(query, "q", search.Query)
+	setQuery(query, "tenant_id", search.TenantID)
+	if search.Limit > 0 {
+		query.Set("limit", strconv.Itoa(search.Limit))
+	}
+	if search.Offset > 0 {
+		query.Set("offset", strconv.Itoa(search.Offset))
+	}
+
+	res := PersonSearch{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: "/person/search", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About iterate all the matches of a search from its offset, the pages (of its limit)
+// are fetched as the iteration goes. A error ends the iteration.
+func (c *Client) SearchPersonIter(ctx context.Context, search PersonSearch) iter.Seq2[PersonMatch, error] {
+	return func(yield func(PersonMatch, error) bool) {
+		for {
+			page, err := c.SearchPerson(ctx, &search)
+			if err != nil {
+				yield(PersonMatch{}, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if page.NextOffset == nil || len(page.Items) == 0 {
+				return
+			}
+			search.Offset = *page.NextOffset
+		}
+	}
+}
+
+// About write the zip of all the data held about a person, the bytes written are returned
+func (c *Client) ExportPerson(ctx context.Context, personID string, out io.Writer) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "client.ExportPerson", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	res, err := c.send(ctx, request{method: http.MethodGet, path: "/person/" + url.PathEscape(personID) + "/export"})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	return io.Copy(out, res.Body)
+}
+
+// About erase a person, it may be called again until it succeeds
+func (c *Client) ErasePerson(ctx context.Context, personID string) (_ *Tombstone, err error) {
+	ctx, span := tracing.Start(ctx, "client.ErasePerson", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	res := Tombstone{}
+	if err = c.do(ctx, request{method: http.MethodDelete, path: "/person/" + url.PathEscape(personID)}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About list the possible duplicates of a tenant flagged by the onboarding, of a status when given
+func (c *Client) ListDuplicate(ctx context.Context, tenantID string, status string) (_ []PersonDuplicate, err error) {
+	ctx, span := tracing.Start(ctx, "client.ListDuplicate", tracing.PersonAttributes(tenantID, "")...)
+	defer func() { tracing.End(span, err) }()
+
+	query := url.Values{}
+	setQuery(query, "tenant_id", tenantID)
+	setQuery(query, "status", status)
+
+	res := []PersonDuplicate{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: "/person/duplicates", query: query}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// About dismiss a possible duplicate, the persons are not the same
+func (c *Client) DismissDuplicate(ctx context.Context, id int64) (_ *PersonDuplicate, err error) {
+	ctx, span := tracing.Start(ctx, "client.DismissDuplicate")
+	defer func() { tracing.End(span, err) }()
+
+	res := PersonDuplicate{}
+	if err = c.do(ctx, request{method: http.MethodPost, path: "/person/duplicates/" + strconv.FormatInt(id, 10) + "/dismiss"}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About merge a person into the survivor, the merged one is erased
+func (c *Client) MergePerson(ctx context.Context, merge *PersonMerge) (_ *PersonMerge, err error) {
+	ctx, span := tracing.Start(ctx, "client.MergePerson", tracing.PersonAttributes(merge.TenantID, merge.SurvivorPersonID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPost, "/person/merge", merge)
+	if err != nil {
+		return nil, err
+	}
+	res := PersonMerge{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About list the addresses of a person
+func (c *Client) ListAddress(ctx context.Context, personID string) (_ []Address, err error) {
+	ctx, span := tracing.Start(ctx, "client.ListAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	res := []Address{}
+	if err = c.do(ctx, request{method: http.MethodGet, path: addressPath(personID, 0)}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// About add a address to a person
+func (c *Client) AddAddress(ctx context.Context, personID string, address *Address) (_ *Address, err error) {
+	ctx, span := tracing.Start(ctx, "client.AddAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPost, addressPath(personID, 0), address)
+	if err != nil {
+		return nil, err
+	}
+	res := Address{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About update a address of a person, found by its id
+func (c *Client) UpdateAddress(ctx context.Context, personID string, address *Address) (_ *Address, err error) {
+	ctx, span := tracing.Start(ctx, "client.UpdateAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	r, err := jsonRequest(http.MethodPut, addressPath(personID, address.ID), address)
+	if err != nil {
+		return nil, err
+	}
+	res := Address{}
+	if err = c.do(ctx, r, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// About delete a address of a person
+func (c *Client) DeleteAddress(ctx context.Context, personID string, addressID int) (err error) {
+	ctx, span := tracing.Start(ctx, "client.DeleteAddress", tracing.PersonAttributes("", personID)...)
+	defer func() { tracing.End(span, err) }()
+
+	return c.do(ctx, request{method: http.MethodDelete, path: addressPath(personID, addressID)}, nil)
+}
+
+// About the path of the addresses of a person, of one of them when the id is given
+func addressPath(personID string, addressID int) string {
+	path := "/person/" + url.PathEscape(personID) + "/address"
+	if addressID != 0 {
+		path += "/" + strconv.Itoa(addressID)
+	}
+	return path
+}
+
+// About set a query param when it has a value
+func setQuery(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,4 @@
+package onboardingv1
+
+// The code is generated from onboarding.proto by protoc with the go and go-grpc plugins
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative onboarding/v1/onboarding.proto
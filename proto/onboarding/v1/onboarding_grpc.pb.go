@@ -0,0 +1,532 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: onboarding/v1/onboarding.proto
+
+// The person and document operations of go-onboarding, for the internal services.
+// The same operations as the http routes, the lists are streamed.
+
+package onboardingv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OnboardingService_AddPerson_FullMethodName      = "/onboarding.v1.OnboardingService/AddPerson"
+	OnboardingService_GetPerson_FullMethodName      = "/onboarding.v1.OnboardingService/GetPerson"
+	OnboardingService_UpdatePerson_FullMethodName   = "/onboarding.v1.OnboardingService/UpdatePerson"
+	OnboardingService_ListPerson_FullMethodName     = "/onboarding.v1.OnboardingService/ListPerson"
+	OnboardingService_LookupPerson_FullMethodName   = "/onboarding.v1.OnboardingService/LookupPerson"
+	OnboardingService_SearchPerson_FullMethodName   = "/onboarding.v1.OnboardingService/SearchPerson"
+	OnboardingService_ErasePerson_FullMethodName    = "/onboarding.v1.OnboardingService/ErasePerson"
+	OnboardingService_ListDocument_FullMethodName   = "/onboarding.v1.OnboardingService/ListDocument"
+	OnboardingService_AddDocument_FullMethodName    = "/onboarding.v1.OnboardingService/AddDocument"
+	OnboardingService_UpdateDocument_FullMethodName = "/onboarding.v1.OnboardingService/UpdateDocument"
+	OnboardingService_DeleteDocument_FullMethodName = "/onboarding.v1.OnboardingService/DeleteDocument"
+)
+
+// OnboardingServiceClient is the client API for OnboardingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OnboardingServiceClient interface {
+	// Creates a person, ALREADY_EXISTS when it matches a person of the tenant,
+	// the PersonDuplicate candidates are in the status details
+	AddPerson(ctx context.Context, in *AddPersonRequest, opts ...grpc.CallOption) (*AddPersonResponse, error)
+	GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// Updates a person, the addresses and documents have their own operations
+	UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// Streams the persons from a person_id on, in the person_id order
+	ListPerson(ctx context.Context, in *ListPersonRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Person], error)
+	// Streams the persons of a tenant with the exact tax id or email
+	LookupPerson(ctx context.Context, in *LookupPersonRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Person], error)
+	// Streams the persons of a tenant by the name, the most relevant first
+	SearchPerson(ctx context.Context, in *SearchPersonRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PersonMatch], error)
+	// Erases a person, it is idempotent
+	ErasePerson(ctx context.Context, in *ErasePersonRequest, opts ...grpc.CallOption) (*Tombstone, error)
+	ListDocument(ctx context.Context, in *ListDocumentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Document], error)
+	AddDocument(ctx context.Context, in *AddDocumentRequest, opts ...grpc.CallOption) (*Document, error)
+	UpdateDocument(ctx context.Context, in *UpdateDocumentRequest, opts ...grpc.CallOption) (*Document, error)
+	DeleteDocument(ctx context.Context, in *DeleteDocumentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type onboardingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOnboardingServiceClient(cc grpc.ClientConnInterface) OnboardingServiceClient {
+	return &onboardingServiceClient{cc}
+}
+
+func (c *onboardingServiceClient) AddPerson(ctx context.Context, in *AddPersonRequest, opts ...grpc.CallOption) (*AddPersonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddPersonResponse)
+	err := c.cc.Invoke(ctx, OnboardingService_AddPerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Person)
+	err := c.cc.Invoke(ctx, OnboardingService_GetPerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Person)
+	err := c.cc.Invoke(ctx, OnboardingService_UpdatePerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) ListPerson(ctx context.Context, in *ListPersonRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Person], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OnboardingService_ServiceDesc.Streams[0], OnboardingService_ListPerson_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListPersonRequest, Person]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_ListPersonClient = grpc.ServerStreamingClient[Person]
+
+func (c *onboardingServiceClient) LookupPerson(ctx context.Context, in *LookupPersonRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Person], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OnboardingService_ServiceDesc.Streams[1], OnboardingService_LookupPerson_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LookupPersonRequest, Person]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_LookupPersonClient = grpc.ServerStreamingClient[Person]
+
+func (c *onboardingServiceClient) SearchPerson(ctx context.Context, in *SearchPersonRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PersonMatch], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OnboardingService_ServiceDesc.Streams[2], OnboardingService_SearchPerson_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchPersonRequest, PersonMatch]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_SearchPersonClient = grpc.ServerStreamingClient[PersonMatch]
+
+func (c *onboardingServiceClient) ErasePerson(ctx context.Context, in *ErasePersonRequest, opts ...grpc.CallOption) (*Tombstone, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Tombstone)
+	err := c.cc.Invoke(ctx, OnboardingService_ErasePerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) ListDocument(ctx context.Context, in *ListDocumentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Document], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OnboardingService_ServiceDesc.Streams[3], OnboardingService_ListDocument_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListDocumentRequest, Document]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_ListDocumentClient = grpc.ServerStreamingClient[Document]
+
+func (c *onboardingServiceClient) AddDocument(ctx context.Context, in *AddDocumentRequest, opts ...grpc.CallOption) (*Document, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Document)
+	err := c.cc.Invoke(ctx, OnboardingService_AddDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) UpdateDocument(ctx context.Context, in *UpdateDocumentRequest, opts ...grpc.CallOption) (*Document, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Document)
+	err := c.cc.Invoke(ctx, OnboardingService_UpdateDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) DeleteDocument(ctx context.Context, in *DeleteDocumentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, OnboardingService_DeleteDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OnboardingServiceServer is the server API for OnboardingService service.
+// All implementations must embed UnimplementedOnboardingServiceServer
+// for forward compatibility.
+type OnboardingServiceServer interface {
+	// Creates a person, ALREADY_EXISTS when it matches a person of the tenant,
+	// the PersonDuplicate candidates are in the status details
+	AddPerson(context.Context, *AddPersonRequest) (*AddPersonResponse, error)
+	GetPerson(context.Context, *GetPersonRequest) (*Person, error)
+	// Updates a person, the addresses and documents have their own operations
+	UpdatePerson(context.Context, *UpdatePersonRequest) (*Person, error)
+	// Streams the persons from a person_id on, in the person_id order
+	ListPerson(*ListPersonRequest, grpc.ServerStreamingServer[Person]) error
+	// Streams the persons of a tenant with the exact tax id or email
+	LookupPerson(*LookupPersonRequest, grpc.ServerStreamingServer[Person]) error
+	// Streams the persons of a tenant by the name, the most relevant first
+	SearchPerson(*SearchPersonRequest, grpc.ServerStreamingServer[PersonMatch]) error
+	// Erases a person, it is idempotent
+	ErasePerson(context.Context, *ErasePersonRequest) (*Tombstone, error)
+	ListDocument(*ListDocumentRequest, grpc.ServerStreamingServer[Document]) error
+	AddDocument(context.Context, *AddDocumentRequest) (*Document, error)
+	UpdateDocument(context.Context, *UpdateDocumentRequest) (*Document, error)
+	DeleteDocument(context.Context, *DeleteDocumentRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedOnboardingServiceServer()
+}
+
+// UnimplementedOnboardingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOnboardingServiceServer struct{}
+
+func (UnimplementedOnboardingServiceServer) AddPerson(context.Context, *AddPersonRequest) (*AddPersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) GetPerson(context.Context, *GetPersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) UpdatePerson(context.Context, *UpdatePersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) ListPerson(*ListPersonRequest, grpc.ServerStreamingServer[Person]) error {
+	return status.Errorf(codes.Unimplemented, "method ListPerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) LookupPerson(*LookupPersonRequest, grpc.ServerStreamingServer[Person]) error {
+	return status.Errorf(codes.Unimplemented, "method LookupPerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) SearchPerson(*SearchPersonRequest, grpc.ServerStreamingServer[PersonMatch]) error {
+	return status.Errorf(codes.Unimplemented, "method SearchPerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) ErasePerson(context.Context, *ErasePersonRequest) (*Tombstone, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ErasePerson not implemented")
+}
+func (UnimplementedOnboardingServiceServer) ListDocument(*ListDocumentRequest, grpc.ServerStreamingServer[Document]) error {
+	return status.Errorf(codes.Unimplemented, "method ListDocument not implemented")
+}
+func (UnimplementedOnboardingServiceServer) AddDocument(context.Context, *AddDocumentRequest) (*Document, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddDocument not implemented")
+}
+func (UnimplementedOnboardingServiceServer) UpdateDocument(context.Context, *UpdateDocumentRequest) (*Document, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDocument not implemented")
+}
+func (UnimplementedOnboardingServiceServer) DeleteDocument(context.Context, *DeleteDocumentRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDocument not implemented")
+}
+func (UnimplementedOnboardingServiceServer) mustEmbedUnimplementedOnboardingServiceServer() {}
+func (UnimplementedOnboardingServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeOnboardingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OnboardingServiceServer will
+// result in compilation errors.
+type UnsafeOnboardingServiceServer interface {
+	mustEmbedUnimplementedOnboardingServiceServer()
+}
+
+func RegisterOnboardingServiceServer(s grpc.ServiceRegistrar, srv OnboardingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedOnboardingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OnboardingService_ServiceDesc, srv)
+}
+
+func _OnboardingService_AddPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).AddPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_AddPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).AddPerson(ctx, req.(*AddPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_GetPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).GetPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_GetPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).GetPerson(ctx, req.(*GetPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_UpdatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).UpdatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_UpdatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).UpdatePerson(ctx, req.(*UpdatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_ListPerson_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListPersonRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OnboardingServiceServer).ListPerson(m, &grpc.GenericServerStream[ListPersonRequest, Person]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_ListPersonServer = grpc.ServerStreamingServer[Person]
+
+func _OnboardingService_LookupPerson_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LookupPersonRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OnboardingServiceServer).LookupPerson(m, &grpc.GenericServerStream[LookupPersonRequest, Person]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_LookupPersonServer = grpc.ServerStreamingServer[Person]
+
+func _OnboardingService_SearchPerson_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchPersonRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OnboardingServiceServer).SearchPerson(m, &grpc.GenericServerStream[SearchPersonRequest, PersonMatch]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_SearchPersonServer = grpc.ServerStreamingServer[PersonMatch]
+
+func _OnboardingService_ErasePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ErasePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).ErasePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_ErasePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).ErasePerson(ctx, req.(*ErasePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_ListDocument_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListDocumentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OnboardingServiceServer).ListDocument(m, &grpc.GenericServerStream[ListDocumentRequest, Document]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OnboardingService_ListDocumentServer = grpc.ServerStreamingServer[Document]
+
+func _OnboardingService_AddDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).AddDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_AddDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).AddDocument(ctx, req.(*AddDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_UpdateDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).UpdateDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_UpdateDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).UpdateDocument(ctx, req.(*UpdateDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_DeleteDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).DeleteDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_DeleteDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).DeleteDocument(ctx, req.(*DeleteDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OnboardingService_ServiceDesc is the grpc.ServiceDesc for OnboardingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OnboardingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "onboarding.v1.OnboardingService",
+	HandlerType: (*OnboardingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddPerson",
+			Handler:    _OnboardingService_AddPerson_Handler,
+		},
+		{
+			MethodName: "GetPerson",
+			Handler:    _OnboardingService_GetPerson_Handler,
+		},
+		{
+			MethodName: "UpdatePerson",
+			Handler:    _OnboardingService_UpdatePerson_Handler,
+		},
+		{
+			MethodName: "ErasePerson",
+			Handler:    _OnboardingService_ErasePerson_Handler,
+		},
+		{
+			MethodName: "AddDocument",
+			Handler:    _OnboardingService_AddDocument_Handler,
+		},
+		{
+			MethodName: "UpdateDocument",
+			Handler:    _OnboardingService_UpdateDocument_Handler,
+		},
+		{
+			MethodName: "DeleteDocument",
+			Handler:    _OnboardingService_DeleteDocument_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListPerson",
+			Handler:       _OnboardingService_ListPerson_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "LookupPerson",
+			Handler:       _OnboardingService_LookupPerson_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchPerson",
+			Handler:       _OnboardingService_SearchPerson_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListDocument",
+			Handler:       _OnboardingService_ListDocument_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "onboarding/v1/onboarding.proto",
+}